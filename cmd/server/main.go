@@ -10,11 +10,13 @@ import (
 	"time"
 
 	"github.com/den/gmail-triage-assistant/frontend"
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/ask"
 	"github.com/den/gmail-triage-assistant/internal/config"
 	"github.com/den/gmail-triage-assistant/internal/database"
 	"github.com/den/gmail-triage-assistant/internal/gmail"
+	"github.com/den/gmail-triage-assistant/internal/llm"
 	"github.com/den/gmail-triage-assistant/internal/memory"
-	"github.com/den/gmail-triage-assistant/internal/openai"
 	"github.com/den/gmail-triage-assistant/internal/pipeline"
 	"github.com/den/gmail-triage-assistant/internal/pushover"
 	"github.com/den/gmail-triage-assistant/internal/webhook"
@@ -73,18 +75,92 @@ func main() {
 		Endpoint: google.Endpoint,
 	}
 
-	// Initialize OpenAI client
-	openaiClient := openai.NewClient(cfg.OpenAIAPIKey, cfg.OpenAIModel, cfg.OpenAIBaseURL)
-	log.Printf("✓ OpenAI client initialized (model: %s)", cfg.OpenAIModel)
+	// Initialize LLM provider
+	llmOptions := providerOptions(cfg, cfg.LLMProvider)
+	llmProvider, err := llm.New(llmOptions)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM provider: %v", err)
+	}
+
+	// Route each task to its configured model
+	taskModels := map[string]string{
+		llm.TaskAnalyze: cfg.ModelAnalyze,
+		llm.TaskActions: cfg.ModelActions,
+		llm.TaskTriage:  cfg.ModelTriage,
+		llm.TaskDraft:   cfg.ModelDraft,
+		llm.TaskProfile: cfg.ModelProfile,
+		llm.TaskMemory:  cfg.ModelMemory,
+		llm.TaskWrapup:  cfg.ModelWrapup,
+		llm.TaskWizard:  cfg.ModelWizard,
+		llm.TaskAsk:     cfg.ModelAsk,
+		llm.TaskGuard:   cfg.ModelGuard,
+		llm.TaskBucket:  cfg.ModelBucket,
+	}
+	for bucket, model := range cfg.ModelBuckets {
+		taskModels[llm.BucketTask(bucket)] = model
+	}
+	llmRouter := llm.NewRouter(llmProvider, taskModels)
+
+	// Record per-user token usage and estimated cost of every call
+	prices, err := llm.ParsePrices(cfg.LLMPrices)
+	if err != nil {
+		log.Fatalf("Invalid LLM_PRICES: %v", err)
+	}
+
+	// Optional fallback: a secondary model on the same provider, or a different provider
+	var fallbacks []llm.Fallback
+	if cfg.LLMFallbackProvider != "" || cfg.LLMFallbackModel != "" {
+		fallbackProvider := llmProvider
+		if cfg.LLMFallbackProvider != "" && cfg.LLMFallbackProvider != cfg.LLMProvider {
+			fallbackProvider, err = llm.New(providerOptions(cfg, cfg.LLMFallbackProvider))
+			if err != nil {
+				log.Fatalf("Failed to initialize fallback LLM provider: %v", err)
+			}
+		}
+		fallbacks = append(fallbacks, llm.Fallback{LLM: ai.NewMeter(fallbackProvider, db, prices), Model: cfg.LLMFallbackModel})
+		log.Printf("✓ LLM fallback configured (provider: %s, model: %s)", cfg.LLMFallbackProvider, cfg.LLMFallbackModel)
+	}
+
+	// Deadlines, retries, response validation and fallback for every call
+	llmClient := llm.NewResilient(ai.NewMeter(llmRouter, db, prices), llm.ResilienceOptions{
+		Timeout:    time.Duration(cfg.LLMTimeoutSeconds) * time.Second,
+		MaxRetries: cfg.LLMMaxRetries,
+		Backoff:    time.Duration(cfg.LLMRetryBackoffMs) * time.Millisecond,
+	}, fallbacks...)
+	log.Printf("✓ LLM provider initialized (provider: %s, model: %s)", cfg.LLMProvider, llmOptions.Model)
+
+	// Optional embeddings for retrieving similar past decisions as few-shot examples
+	var embedder llm.Embedder
+	if cfg.FewShotExamples > 0 {
+		embeddingProvider := cfg.EmbeddingProvider
+		if embeddingProvider == "" {
+			embeddingProvider = cfg.LLMProvider
+			if embeddingProvider == llm.ProviderAnthropic {
+				embeddingProvider = llm.ProviderLocal
+			}
+		}
+		embeddingOptions := providerOptions(cfg, embeddingProvider)
+		embeddingOptions.Model = cfg.EmbeddingModel
+		baseEmbedder, err := llm.NewEmbedder(embeddingOptions)
+		if err != nil {
+			log.Fatalf("Failed to initialize embedding provider: %v", err)
+		}
+		embedder = ai.NewEmbedMeter(baseEmbedder, db, prices)
+		log.Printf("✓ Embeddings initialized (provider: %s, examples: %d)", embeddingProvider, cfg.FewShotExamples)
+	}
 
 	// Initialize memory service
-	memoryService := memory.NewService(db, openaiClient)
+	memoryService := memory.NewService(db, llmClient)
 	log.Printf("✓ Memory service initialized")
 
 	// Initialize wrapup service
-	wrapupService := wrapup.NewService(db, openaiClient, oauthConfig)
+	wrapupService := wrapup.NewService(db, llmClient, oauthConfig)
 	log.Printf("✓ Wrapup service initialized")
 
+	// Initialize archive Q&A service
+	askService := ask.NewService(db, llmClient, embedder)
+	log.Printf("✓ Ask service initialized")
+
 	// Initialize Pushover client for push notifications
 	pushoverClient := pushover.NewClient()
 	log.Printf("✓ Pushover client initialized")
@@ -94,7 +170,7 @@ func main() {
 	log.Printf("✓ Webhook client initialized")
 
 	// Initialize email processor pipeline
-	processor := pipeline.NewProcessor(db, openaiClient, oauthConfig, pushoverClient, webhookClient)
+	processor := pipeline.NewProcessor(db, cfg, llmClient, embedder, oauthConfig, pushoverClient, webhookClient)
 	log.Printf("✓ Email processing pipeline initialized")
 
 	// Create message handler using the pipeline
@@ -106,15 +182,21 @@ func main() {
 	checkInterval := time.Duration(cfg.GmailCheckInterval) * time.Minute
 	monitor := gmail.NewMultiUserMonitor(db, oauthConfig, checkInterval, messageHandler)
 
+	// Security lane: one-time codes and account alerts are picked up between regular polls
+	if cfg.SecurityCheckInterval > 0 {
+		securityInterval := time.Duration(cfg.SecurityCheckInterval) * time.Second
+		monitor.WithSecurityLane(securityInterval, processor.ProcessSecurityEmail)
+	}
+
 	// Initialize web server with embedded frontend
 	frontendFS, err := fs.Sub(frontend.DistFS, "dist")
 	if err != nil {
 		log.Fatalf("Failed to get frontend filesystem: %v", err)
 	}
-	server := web.NewServer(db, cfg, memoryService, openaiClient, frontendFS)
+	server := web.NewServer(db, cfg, memoryService, llmClient, processor, askService, frontendFS)
 
 	// Initialize scheduler
-	sched := scheduler.NewScheduler(db, memoryService, wrapupService, oauthConfig)
+	sched := scheduler.NewScheduler(db, cfg, memoryService, wrapupService, oauthConfig)
 
 	log.Printf("✓ Multi-user Gmail monitor initialized (checking every %v)", checkInterval)
 	log.Printf("✓ Web server ready on: http://%s:%s", cfg.ServerHost, cfg.ServerPort)
@@ -157,3 +239,21 @@ func main() {
 	time.Sleep(2 * time.Second)
 	log.Println("Goodbye!")
 }
+
+// providerOptions builds the LLM options for a provider from its credentials in the config
+func providerOptions(cfg *config.Config, provider string) llm.Options {
+	if provider == llm.ProviderAnthropic {
+		return llm.Options{
+			Provider: provider,
+			APIKey:   cfg.AnthropicAPIKey,
+			Model:    cfg.AnthropicModel,
+			BaseURL:  cfg.AnthropicBaseURL,
+		}
+	}
+	return llm.Options{
+		Provider: provider,
+		APIKey:   cfg.OpenAIAPIKey,
+		Model:    cfg.OpenAIModel,
+		BaseURL:  cfg.OpenAIBaseURL,
+	}
+}
@@ -66,6 +66,10 @@ export const api = {
       method: "PUT",
       body: JSON.stringify({ feedback }),
     }),
+  releaseNotification: (id: string) =>
+    request<{ status: string }>(`/emails/${id}/release-notification`, {
+      method: "POST",
+    }),
 
   getPrompts: () => request<import("./types").PromptsResponse>("/prompts"),
   getDefaultPrompts: () =>
@@ -55,6 +55,12 @@ export interface Email {
   feedback_dirty: boolean;
   processed_at: string;
   created_at: string;
+  // Prompt-injection detection (0-1 score; notification held until confirmed).
+  injection_score?: number;
+  injection_signals?: string[];
+  held_notification?: string;
+  // Distinct personal-data values replaced with placeholders before the AI saw the email.
+  redaction_count?: number;
   // v2 pipeline fields — null/undefined for legacy v1 rows.
   bucket?: Bucket | null;
   pipeline_stage?: PipelineStage;
@@ -70,7 +76,9 @@ export interface Email {
   // Bucket-specific extractions (migration 0004).
   vendor?: string | null;
   document_type?: string | null;
-  amount?: string | null;
+  amount?: number | string | null;
+  currency?: string;
+  due_date?: string | null;
   action_type?: string | null;
   is_otp?: boolean | null;
   event_title?: string | null;
@@ -364,6 +372,8 @@ export interface SenderProfile {
   keyword_counts: Record<string, number>;
   sender_type: string;
   summary: string;
+  // Always triaged by the AI, never fast-pathed.
+  mixed?: boolean;
   first_seen_at: string;
   last_seen_at: string;
   rating: number | null;
@@ -458,6 +468,15 @@ export interface KeywordStatItem {
   count: number;
 }
 
+export interface BucketStatItem {
+  bucket: Bucket;
+  count: number;
+  today: number;
+  this_week: number;
+  archive_rate: number;
+  notification_rate: number;
+}
+
 export interface DashboardSummary {
   total_emails: number;
   emails_today: number;
@@ -470,6 +489,8 @@ export interface DashboardSummary {
   top_slugs: SlugStatItem[];
   label_distribution: LabelStatItem[];
   top_keywords: KeywordStatItem[];
+  // Emails processed in the "buckets" pipeline mode.
+  bucket_distribution: BucketStatItem[];
   new_slugs_this_week: number;
   recurring_slugs_this_week: number;
 }
@@ -223,6 +223,25 @@ function ProfileSection({
   );
 }
 
+const INJECTION_THRESHOLD = 0.5;
+
+function isSuspicious(email: Email) {
+  return (email.injection_score ?? 0) >= INJECTION_THRESHOLD;
+}
+
+function SuspiciousBadge({ email }: { email: Email }) {
+  if (!isSuspicious(email)) return null;
+  return (
+    <Badge
+      variant="destructive"
+      className="shrink-0 text-xs"
+      title={email.injection_signals?.join("; ")}
+    >
+      Suspicious
+    </Badge>
+  );
+}
+
 function EmailDetailDialog({
   email,
   open,
@@ -263,6 +282,11 @@ function EmailDetailDialog({
     onFeedbackSaved();
   };
 
+  const handleRelease = async () => {
+    await api.releaseNotification(email.id);
+    onFeedbackSaved();
+  };
+
   return (
     <Dialog open={open} onOpenChange={onOpenChange}>
       <DialogContent className="max-h-[85vh] overflow-y-auto sm:max-w-2xl">
@@ -279,6 +303,7 @@ function EmailDetailDialog({
                 Notified
               </Badge>
             )}
+            <SuspiciousBadge email={email} />
           </DialogTitle>
           <DialogDescription className="text-left">
             {email.from_address}
@@ -385,6 +410,40 @@ function EmailDetailDialog({
                 </p>
               </div>
             )}
+
+            {(email.redaction_count ?? 0) > 0 && (
+              <p className="text-xs text-muted-foreground">
+                {email.redaction_count} personal data value
+                {email.redaction_count === 1 ? " was" : "s were"} redacted before
+                AI processing
+              </p>
+            )}
+
+            {isSuspicious(email) && (
+              <div className="rounded-md border border-destructive/50 p-3">
+                <span className="text-xs font-medium text-destructive">
+                  Suspected prompt injection (score{" "}
+                  {(email.injection_score ?? 0).toFixed(2)})
+                </span>
+                {email.injection_signals && email.injection_signals.length > 0 && (
+                  <ul className="mt-1 list-disc space-y-0.5 pl-5 text-sm text-muted-foreground">
+                    {email.injection_signals.map((signal, i) => (
+                      <li key={i}>{signal}</li>
+                    ))}
+                  </ul>
+                )}
+                {email.held_notification && (
+                  <div className="mt-2 space-y-2">
+                    <p className="text-sm">
+                      Held notification: <em>{email.held_notification}</em>
+                    </p>
+                    <Button size="sm" variant="outline" onClick={handleRelease}>
+                      Send notification
+                    </Button>
+                  </div>
+                )}
+              </div>
+            )}
           </div>
 
           {/* Feedback */}
@@ -512,6 +571,7 @@ function EmailRow({
               Notified
             </Badge>
           )}
+          <SuspiciousBadge email={email} />
         </div>
         <div className="mt-1 flex flex-wrap items-center gap-1.5 text-xs text-muted-foreground">
           <span className="truncate">{email.from_address}</span>
@@ -4,7 +4,10 @@ go 1.24.0
 
 toolchain go1.24.13
 
-require github.com/lib/pq v1.11.1
+require (
+	github.com/lib/pq v1.11.1
+	golang.org/x/net v0.49.0
+)
 
 require (
 	cloud.google.com/go/auth v0.18.1 // indirect
@@ -33,7 +36,6 @@ require (
 	go.opentelemetry.io/otel/metric v1.39.0 // indirect
 	go.opentelemetry.io/otel/trace v1.39.0 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
-	golang.org/x/net v0.49.0 // indirect
 	golang.org/x/oauth2 v0.35.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// SearchPlan is the archive query derived from a natural-language question
+type SearchPlan struct {
+	Terms  string `json:"terms"`  // Keywords for full-text search
+	Sender string `json:"sender"` // Sender name, address or domain fragment, or ""
+	After  string `json:"after"`  // Inclusive start date (YYYY-MM-DD), or ""
+	Before string `json:"before"` // Exclusive end date (YYYY-MM-DD), or ""
+}
+
+// ArchiveAnswer is an answer to a question about the mail archive
+type ArchiveAnswer struct {
+	Answer    string   `json:"answer"`
+	Citations []string `json:"citations"` // IDs of the emails the answer is based on
+}
+
+// searchPlanSchema is the structured output of PlanArchiveSearch
+var searchPlanSchema = llm.Schema{
+	Name:        "archive_search_plan",
+	Description: "Search terms, sender and date range for a question about the user's email",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"terms": map[string]interface{}{
+				"type":        "string",
+				"description": "Space-separated keywords and synonyms to search email subjects and summaries for",
+			},
+			"sender": map[string]interface{}{
+				"type":        "string",
+				"description": "Sender name, address or domain fragment (e.g. \"aws\"), or empty string if none",
+			},
+			"after": map[string]interface{}{
+				"type":        "string",
+				"description": "Inclusive start date as YYYY-MM-DD, or empty string if unbounded",
+			},
+			"before": map[string]interface{}{
+				"type":        "string",
+				"description": "Exclusive end date as YYYY-MM-DD, or empty string if unbounded",
+			},
+		},
+		"required":             []string{"terms", "sender", "after", "before"},
+		"additionalProperties": false,
+	},
+}
+
+// archiveAnswerSchema is the structured output of AnswerArchiveQuestion
+var archiveAnswerSchema = llm.Schema{
+	Name:        "archive_answer",
+	Description: "Answer to a question about the user's email with citations",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"answer": map[string]interface{}{
+				"type":        "string",
+				"description": "Concise answer based only on the sources",
+			},
+			"citations": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+				"description": "IDs of the source emails the answer relies on",
+			},
+		},
+		"required":             []string{"answer", "citations"},
+		"additionalProperties": false,
+	},
+}
+
+// PlanArchiveSearch turns a question into search terms, a sender filter and a date range
+func (c *Client) PlanArchiveSearch(ctx context.Context, question string, today time.Time) (*SearchPlan, error) {
+	systemPrompt := `You translate questions about a user's email archive into a search.
+The archive holds processed emails with a sender, subject, one-line summary, keywords and category.
+Extract the search keywords (add close synonyms, e.g. "invoice bill receipt"), the sender if one is named,
+and the date range implied by the question ("last quarter", "in March", "this year").
+Leave fields empty when the question does not constrain them.`
+
+	userPrompt := fmt.Sprintf("Today is %s (%s).\n\nQuestion: %s", today.Format("2006-01-02"), today.Weekday(), question)
+
+	c.logPrompts("PlanArchiveSearch", systemPrompt, userPrompt)
+
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "PlanArchiveSearch",
+		Task:         llm.TaskAsk,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    2000,
+		Validate: func(content string) error {
+			_, err := parseSearchPlan(content)
+			return err
+		},
+	}, searchPlanSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSearchPlan(response.Content)
+}
+
+// AnswerArchiveQuestion answers a question from the formatted sources.
+// Citations must be IDs from sourceIDs.
+func (c *Client) AnswerArchiveQuestion(ctx context.Context, question, sources string, sourceIDs []string) (*ArchiveAnswer, error) {
+	systemPrompt := `You answer questions about the user's email archive using only the sources provided.
+Each email source starts with its ID in square brackets. Cite the IDs of every email your answer relies on.
+If the sources do not contain the answer, say so plainly and cite nothing. Do not guess.
+Keep the answer short; list items (e.g. invoices with dates and amounts) when the question asks for several.`
+
+	userPrompt := fmt.Sprintf("Sources:\n\n%s\nQuestion: %s", sources, question)
+
+	c.logPrompts("AnswerArchiveQuestion", systemPrompt, userPrompt)
+
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "AnswerArchiveQuestion",
+		Task:         llm.TaskAsk,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    4000,
+		Validate: func(content string) error {
+			_, err := parseArchiveAnswer(content, sourceIDs)
+			return err
+		},
+	}, archiveAnswerSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseArchiveAnswer(response.Content, sourceIDs)
+}
+
+// parseSearchPlan decodes a search plan and checks its dates
+func parseSearchPlan(content string) (*SearchPlan, error) {
+	var plan SearchPlan
+	if err := json.Unmarshal([]byte(content), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse search plan: %w", err)
+	}
+	for _, date := range []string{plan.After, plan.Before} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, &ValidationError{Reason: fmt.Sprintf("date %q is not in YYYY-MM-DD format", date)}
+		}
+	}
+	return &plan, nil
+}
+
+// parseArchiveAnswer decodes an answer and checks that every citation is a known source
+func parseArchiveAnswer(content string, sourceIDs []string) (*ArchiveAnswer, error) {
+	var answer ArchiveAnswer
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return nil, fmt.Errorf("failed to parse answer: %w", err)
+	}
+	if strings.TrimSpace(answer.Answer) == "" {
+		return nil, &ValidationError{Reason: "answer is empty"}
+	}
+
+	known := make(map[string]bool, len(sourceIDs))
+	for _, id := range sourceIDs {
+		known[id] = true
+	}
+	for _, id := range answer.Citations {
+		if !known[id] {
+			return nil, &ValidationError{Reason: fmt.Sprintf("citation %q is not one of the source email IDs", id)}
+		}
+	}
+	if answer.Citations == nil {
+		answer.Citations = []string{}
+	}
+	return &answer, nil
+}
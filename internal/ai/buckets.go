@@ -0,0 +1,373 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// BucketTriage is the bucket an email was classified into (buckets pipeline mode)
+type BucketTriage struct {
+	Bucket     string  `json:"bucket"`
+	Confidence float64 `json:"confidence"`
+	Reasoning  string  `json:"reasoning"`
+	Model      string  `json:"-"`
+	Escalated  bool    `json:"-"` // True when the classification came from the escalation model
+}
+
+// BucketResult is the output of a bucket-specific processor.
+// Fields a bucket's schema does not include are left empty.
+type BucketResult struct {
+	Slug                string       `json:"slug"`
+	Keywords            []string     `json:"keywords"`
+	Summary             string       `json:"summary"`
+	Labels              []string     `json:"labels"`
+	NotificationMessage string       `json:"notification_message"`
+	Reasoning           string       `json:"reasoning"`
+	DraftReply          bool         `json:"draft_reply"`         // human
+	Severity            string       `json:"severity"`            // notification
+	Urgency             string       `json:"urgency"`             // notification
+	InterestingScore    int          `json:"interesting_score"`   // newsletter
+	InterestingReasons  []string     `json:"interesting_reasons"` // newsletter
+	IsOTP               bool         `json:"is_otp"`              // security
+	Transaction         *Transaction `json:"transaction"`         // transactional
+	Event               *Event       `json:"event"`               // transactional, calendar
+	Tasks               []Task       `json:"tasks"`               // human, notification
+	Shipment            *Shipment    `json:"shipment"`            // transactional
+	Model               string       `json:"-"`
+	Escalated           bool         `json:"-"` // True when the result came from the escalation model
+}
+
+// defaultBucketTriagePrompt is the bucket classification prompt used when the user has not customized it
+const defaultBucketTriagePrompt = `You classify emails into one of six buckets so the right automation can process them. Be decisive; pick the single best bucket. If an email could fit two buckets, pick the one that determines how the user should act on it (security > calendar > human > transactional > notification > newsletter).`
+
+const bucketDefinitions = `Buckets:
+- newsletter: recurring marketing/content emails - Substacks, product announcements, promotional campaigns
+- notification: automated alerts triggered by external activity - monitoring alerts, PR comments, social mentions, system status
+- human: a message written by a real person to the user, or a mailing list thread with actual human participation
+- transactional: triggered by a user action - order confirmations, receipts, invoices, shipping updates, booking confirmations
+- security: MFA codes, password resets, login alerts, account recovery. Safety-critical even if automated
+- calendar: meeting invites, calendar updates, cancellations`
+
+var bucketTriageSchema = llm.Schema{
+	Name:        "bucket_triage",
+	Description: "Bucket classification of an email",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"bucket": map[string]interface{}{
+				"type": "string",
+				"enum": database.Buckets,
+			},
+			"confidence": map[string]interface{}{
+				"type":        "number",
+				"description": "How confident you are in the bucket, from 0.0 to 1.0",
+			},
+			"reasoning": map[string]interface{}{
+				"type":        "string",
+				"description": "One sentence explaining the bucket",
+			},
+		},
+		"required":             []string{"bucket", "confidence", "reasoning"},
+		"additionalProperties": false,
+	},
+}
+
+// ClassifyBucket runs the buckets-mode triage stage: which of the six buckets an email belongs to.
+// The classification is retried on the escalation model when confidence is low or the response is invalid.
+func (c *Client) ClassifyBucket(ctx context.Context, from, subject, body string, senderContext string, customSystemPrompt string) (*BucketTriage, error) {
+	systemPrompt := customSystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultBucketTriagePrompt
+	}
+	systemPrompt += "\n\n" + bucketDefinitions + untrustedContentRule
+
+	userPrompt := fmt.Sprintf(`%s
+
+%sWhich bucket does this email belong to?`, wrapUntrusted(from, subject, body), senderContext)
+
+	c.logPrompts("ClassifyBucket", systemPrompt, userPrompt)
+
+	req := llm.Request{
+		Name:         "ClassifyBucket",
+		Task:         llm.TaskBucket,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    2000,
+		Validate: func(content string) error {
+			_, err := parseBucketTriage(content)
+			return err
+		},
+	}
+	triage, err := c.classifyBucket(ctx, req)
+
+	confidence := noConfidence
+	if err == nil {
+		confidence = triage.Confidence
+	}
+	if reason := c.escalationReason(err, confidence); reason != "" {
+		log.Printf("Escalating ClassifyBucket to %s: %s", c.escalationModel, reason)
+		req.Model = c.escalationModel
+		escalated, escErr := c.classifyBucket(ctx, req)
+		if escErr == nil {
+			escalated.Escalated = true
+			return escalated, nil
+		}
+		log.Printf("Escalated ClassifyBucket failed, keeping original classification: %v", escErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return triage, nil
+}
+
+// classifyBucket runs one bucket classification call and validates the result
+func (c *Client) classifyBucket(ctx context.Context, req llm.Request) (*BucketTriage, error) {
+	response, err := c.completeJSON(ctx, req, bucketTriageSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	triage, err := parseBucketTriage(response.Content)
+	if err != nil {
+		return nil, err
+	}
+	triage.Model = response.Model
+	return triage, nil
+}
+
+// parseBucketTriage decodes and validates a bucket classification
+func parseBucketTriage(content string) (*BucketTriage, error) {
+	var triage BucketTriage
+	if err := json.Unmarshal([]byte(content), &triage); err != nil {
+		return nil, &ValidationError{Reason: fmt.Sprintf("failed to parse AI response (content: %q): %v", content, err)}
+	}
+	if !database.IsValidBucket(triage.Bucket) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("unknown bucket %q", triage.Bucket)}
+	}
+	if triage.Confidence < 0 || triage.Confidence > 1 {
+		return nil, &ValidationError{Reason: fmt.Sprintf("confidence %v out of range", triage.Confidence)}
+	}
+	return &triage, nil
+}
+
+// bucketSpec is one bucket processor: its default prompt, what it must produce and its extra output fields
+type bucketSpec struct {
+	prompt     string                 // Default system prompt, replaced by the user's bucket prompt
+	produce    string                 // Output instructions, always appended
+	properties map[string]interface{} // Fields beyond the common slug/keywords/summary/labels/notification/reasoning
+}
+
+var bucketSpecs = map[string]bucketSpec{
+	database.BucketNewsletter: {
+		prompt: "You process newsletter emails. Score each for how likely it is to be worth the user's time.",
+		produce: `- interesting_score: 0-10. 0 = pure marketing/generic filler. 10 = novel insight directly aligned with the user's interests. Default to 3 unless you have a real reason to score higher.
+- interesting_reasons: short reasons backing the score (max 3)
+- notification_message: leave blank; newsletters are never pushed`,
+		properties: map[string]interface{}{
+			"interesting_score":   map[string]interface{}{"type": "integer"},
+			"interesting_reasons": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	},
+	database.BucketNotification: {
+		prompt: "You assess automated notifications for severity and urgency.",
+		produce: `- severity: low | medium | high | critical - what's at stake?
+- urgency: low | medium | high - how soon does the user need to react?
+- notification_message: leave blank unless severity is high or critical, or urgency is high; when set, a short friendly message for a push notification` + taskRule,
+		properties: map[string]interface{}{
+			"severity": map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high", "critical"}},
+			"urgency":  map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high"}},
+			"tasks":    taskProperty,
+		},
+	},
+	database.BucketHuman: {
+		prompt: "You process emails written by people to the user.",
+		produce: `- notification_message: leave blank unless this is time-sensitive and from someone who matters to the user; when set, a short friendly message
+- draft_reply: true only when there is a clear question or request aimed at the user (not at another recipient) and the body gives enough to write a useful reply` + taskRule,
+		properties: map[string]interface{}{
+			"draft_reply": map[string]interface{}{"type": "boolean"},
+			"tasks":       taskProperty,
+		},
+	},
+	database.BucketTransactional: {
+		prompt: "You process transactional emails - receipts, invoices, order/shipping confirmations and bookings.",
+		produce: `- labels: also add one timed label: "🗑️/1m" for receipts and shipping updates, "📥/1y" for invoices or anything tax-relevant
+- notification_message: leave blank unless something failed or needs action (e.g. a payment was declined)` + transactionRule + eventRule + shipmentRule,
+		properties: map[string]interface{}{
+			"transaction": transactionProperty,
+			"event":       eventProperty,
+			"shipment":    shipmentProperty,
+		},
+	},
+	database.BucketSecurity: {
+		prompt: "You process security emails - MFA codes, password resets, login alerts and account recovery.",
+		produce: `- summary: include the one-time code inline if there is one
+- is_otp: true if the email contains a one-time code
+- notification_message: always set; a concise push-ready message (e.g. "Login code 123456 for GitHub", "New sign-in to your Google account from Linux")`,
+		properties: map[string]interface{}{
+			"is_otp": map[string]interface{}{"type": "boolean"},
+		},
+	},
+	database.BucketCalendar: {
+		prompt: "You process calendar emails - meeting invites, updates and cancellations.",
+		produce: `- summary: include the event title and when it starts
+- notification_message: leave blank unless the event starts within the next hour or an imminent event was cancelled` + eventRule,
+		properties: map[string]interface{}{
+			"event": eventProperty,
+		},
+	},
+}
+
+// bucketSchema is the structured output of a bucket processor
+func bucketSchema(bucket string, spec bucketSpec) llm.Schema {
+	properties := map[string]interface{}{
+		"slug":                 map[string]interface{}{"type": "string", "description": "A snake_case_slug categorizing the email type"},
+		"keywords":             map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "3-5 keywords describing the email content"},
+		"summary":              map[string]interface{}{"type": "string", "description": "Single line summary (max 100 chars)"},
+		"labels":               map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Array of label names to apply"},
+		"notification_message": map[string]interface{}{"type": "string"},
+		"reasoning":            map[string]interface{}{"type": "string", "description": "Brief explanation of the decision"},
+	}
+	required := []string{"slug", "keywords", "summary", "labels", "notification_message", "reasoning"}
+	for key, prop := range spec.properties {
+		properties[key] = prop
+		required = append(required, key)
+	}
+	return llm.Schema{
+		Name:        "bucket_" + bucket,
+		Description: fmt.Sprintf("Processing result for a %s email", bucket),
+		Definition: map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+	}
+}
+
+// ProcessBucket runs the bucket-specific stage on an email already classified into bucket.
+// The bucket's default actions (archive, timed labels, notification rules) are applied by the caller.
+// Processors report no confidence, so the call is retried on the escalation model only when the
+// response is invalid.
+func (c *Client) ProcessBucket(ctx context.Context, bucket, from, subject, body string, labelNames []string, formattedLabels string, senderContext string, memoryContext string, customSystemPrompt string) (*BucketResult, error) {
+	spec, ok := bucketSpecs[bucket]
+	if !ok {
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
+	}
+
+	systemPrompt := customSystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = spec.prompt
+	}
+	systemPrompt += fmt.Sprintf(`
+
+Available labels:
+%s
+
+Produce:
+- slug: snake_case email type (e.g. "order_confirmation", "pr_review_comment")
+- keywords: 3-5 descriptive keywords
+- summary: one line (max 100 chars)
+- labels: exact label names from the list above that clearly apply; do not invent labels
+%s
+- reasoning: 1-2 sentences backing the above`, formattedLabels, spec.produce) + untrustedContentRule
+
+	userPrompt := fmt.Sprintf(`%s
+
+%s%s%sProcess this %s email.`, wrapUntrusted(from, subject, body), todayContext(), senderContext, memoryContext, bucket)
+
+	name := "ProcessBucket:" + bucket
+	c.logPrompts(name, systemPrompt, userPrompt)
+
+	req := llm.Request{
+		Name:         name,
+		Task:         llm.BucketTask(bucket),
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    4000,
+		Validate: func(content string) error {
+			_, err := parseBucketResult(content, labelNames)
+			return err
+		},
+	}
+	schema := bucketSchema(bucket, spec)
+	result, err := c.processBucket(ctx, req, schema, labelNames)
+
+	if reason := c.escalationReason(err, noConfidence); reason != "" {
+		log.Printf("Escalating %s to %s: %s", name, c.escalationModel, reason)
+		req.Model = c.escalationModel
+		escalated, escErr := c.processBucket(ctx, req, schema, labelNames)
+		if escErr == nil {
+			escalated.Escalated = true
+			return escalated, nil
+		}
+		log.Printf("Escalated %s failed: %v", name, escErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// processBucket runs one bucket processor call and validates the result
+func (c *Client) processBucket(ctx context.Context, req llm.Request, schema llm.Schema, labelNames []string) (*BucketResult, error) {
+	response, err := c.completeJSON(ctx, req, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseBucketResult(response.Content, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	result.Model = response.Model
+	return result, nil
+}
+
+// parseBucketResult decodes and validates a bucket processor response
+func parseBucketResult(content string, labelNames []string) (*BucketResult, error) {
+	var result BucketResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, &ValidationError{Reason: fmt.Sprintf("failed to parse AI response (content: %q): %v", content, err)}
+	}
+	if !snakeCase.MatchString(result.Slug) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("slug %q is not snake_case", result.Slug)}
+	}
+	if err := validateActions(&EmailActions{Labels: result.Labels}, labelNames); err != nil {
+		return nil, err
+	}
+	if result.InterestingScore < 0 || result.InterestingScore > 10 {
+		return nil, &ValidationError{Reason: fmt.Sprintf("interesting_score %d out of range", result.InterestingScore)}
+	}
+	transaction, err := normalizeTransaction(result.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	result.Transaction = transaction
+	event, err := normalizeEvent(result.Event)
+	if err != nil {
+		return nil, err
+	}
+	result.Event = event
+	tasks, err := normalizeTasks(result.Tasks)
+	if err != nil {
+		return nil, err
+	}
+	result.Tasks = tasks
+	shipment, err := normalizeShipment(result.Shipment)
+	if err != nil {
+		return nil, err
+	}
+	result.Shipment = shipment
+	if result.Keywords == nil {
+		result.Keywords = []string{}
+	}
+	if result.Labels == nil {
+		result.Labels = []string{}
+	}
+	return &result, nil
+}
@@ -0,0 +1,535 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// Client builds the triage prompts and schemas and runs them on any LLM provider
+type Client struct {
+	llm      llm.LLM
+	model    string // Overrides the provider's default model when set
+	debugLog bool
+
+	// Escalation: retry decisions (Stage 2, economy triage, bucket stages) on this model when
+	// confidence is low or the response is invalid
+	escalationModel     string
+	escalationThreshold float64
+}
+
+// NewClient creates a new AI client on top of an LLM provider
+func NewClient(provider llm.LLM) *Client {
+	return &Client{
+		llm:      provider,
+		debugLog: strings.Contains(os.Getenv("DEBUG"), "OPENAI") || strings.Contains(os.Getenv("DEBUG"), "LLM"),
+	}
+}
+
+// WithModel returns a copy of the client that uses a different model.
+// An empty model returns the client unchanged.
+func (c *Client) WithModel(model string) *Client {
+	if model == "" || model == c.model {
+		return c
+	}
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+// Model returns the client's model override, or "" when the provider default is used
+func (c *Client) Model() string {
+	return c.model
+}
+
+// WithEscalation returns a copy of the client that retries decisions (Stage 2, economy triage and the
+// bucket stages) on a stronger model when confidence is below the threshold or the response fails validation.
+// An empty model disables escalation.
+func (c *Client) WithEscalation(model string, threshold float64) *Client {
+	clone := *c
+	clone.escalationModel = model
+	clone.escalationThreshold = threshold
+	return &clone
+}
+
+func (c *Client) logPrompts(label, systemPrompt, userPrompt string) {
+	if !c.debugLog {
+		return
+	}
+	log.Printf("[LLM DEBUG] === %s ===\nSYSTEM:\n%s\n\nUSER:\n%s\n=== END %s ===", label, systemPrompt, userPrompt, label)
+}
+
+// complete runs a plain text completion and records its usage
+func (c *Client) complete(ctx context.Context, req llm.Request) (string, error) {
+	if req.Model == "" {
+		req.Model = c.model
+	}
+	response, err := c.llm.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	recordCall(ctx, req, response)
+	return response.Content, nil
+}
+
+// completeJSON runs a structured completion and records its usage.
+// The client's model override applies unless the request names a model.
+func (c *Client) completeJSON(ctx context.Context, req llm.Request, schema llm.Schema) (*llm.Response, error) {
+	if req.Model == "" {
+		req.Model = c.model
+	}
+	response, err := c.llm.CompleteJSON(ctx, req, schema)
+	if err != nil {
+		return nil, err
+	}
+	recordCall(ctx, req, response)
+	return response, nil
+}
+
+// EmailAnalysis represents the Stage 1 AI output
+type EmailAnalysis struct {
+	Slug        string       `json:"slug"`
+	Keywords    []string     `json:"keywords"`
+	Summary     string       `json:"summary"`
+	Transaction *Transaction `json:"transaction"` // nil unless the email is transactional
+	Event       *Event       `json:"event"`       // nil unless the email confirms or invites to an event
+	Tasks       []Task       `json:"tasks"`       // Action items asked of the user
+	Shipment    *Shipment    `json:"shipment"`    // nil unless the email is a shipping notification
+}
+
+// EmailActions represents the Stage 2 AI output
+type EmailActions struct {
+	Labels              []string `json:"labels"`
+	BypassInbox         bool     `json:"bypass_inbox"`
+	NotificationMessage string   `json:"notification_message"`
+	DraftReply          bool     `json:"draft_reply"`
+	Reasoning           string   `json:"reasoning"`
+	Confidence          float64  `json:"confidence"`
+	Model               string   `json:"-"` // Model that made the final decision
+	Escalated           bool     `json:"-"` // True when the decision came from the escalation model
+}
+
+// defaultAnalyzePrompt is the Stage 1 system prompt used when the user has not customized it
+const defaultAnalyzePrompt = `You are an email classification assistant. Analyze the email and provide a JSON response with:
+1. A snake_case_slug that categorizes this type of email (e.g., "marketing_newsletter", "invoice_due", "meeting_request")
+2. An array of 3-5 keywords that describe the email content
+3. A single line summary (max 100 chars)
+
+Respond ONLY with valid JSON in this format:
+{"slug": "example_slug", "keywords": ["word1", "word2", "word3"], "summary": "Brief summary here"}`
+
+// defaultActionsPrompt is the Stage 2 system prompt used when the user has not customized it.
+// The %s placeholder receives the formatted label list.
+const defaultActionsPrompt = `You are an email automation assistant. Based on the email analysis and past learnings, determine what actions to take and respond with JSON.
+
+Available labels:
+%s
+
+Decide:
+1. Which labels to apply (use exact label names from the list above, only when they clearly match)
+2. Whether to bypass the inbox (archive immediately)
+3. notification_message: leave blank unless this is an important email the user should be alerted about immediately. When needed, write a short friendly message summarizing why it matters (e.g. "Hi, the school nurse said your daughter was taken to the sick bay" or "Heads up — you have a late invoice from PowerCo"). Keep it conversational and to the point.
+4. Brief reasoning for your decisions
+5. draft_reply: set to true if this email is from a human and would benefit from a response. Never draft replies to newsletters, notifications, automated emails, or marketing. Consider the sender type and email content.
+
+Use the learnings from past email processing (provided below) to make better decisions about labeling and archiving.`
+
+// analysisSchema is the structured output of Stage 1
+var analysisSchema = llm.Schema{
+	Name:        "email_analysis",
+	Description: "Email content analysis with slug, keywords, and summary",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"slug": map[string]interface{}{
+				"type":        "string",
+				"description": "A snake_case_slug categorizing the email type",
+			},
+			"keywords": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+				"description": "3-5 keywords describing the email content",
+			},
+			"summary": map[string]interface{}{
+				"type":        "string",
+				"description": "Single line summary (max 100 chars)",
+			},
+			"transaction": transactionProperty,
+			"event":       eventProperty,
+			"tasks":       taskProperty,
+			"shipment":    shipmentProperty,
+		},
+		"required":             []string{"slug", "keywords", "summary", "transaction", "event", "tasks", "shipment"},
+		"additionalProperties": false,
+	},
+}
+
+// actionsSchema is the structured output of Stage 2
+var actionsSchema = llm.Schema{
+	Name:        "email_actions",
+	Description: "Email automation actions including labels and inbox bypass",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+				"description": "Array of label names to apply",
+			},
+			"bypass_inbox": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Whether to archive the email immediately",
+			},
+			"notification_message": map[string]interface{}{
+				"type":        "string",
+				"description": "Short friendly notification message explaining why this email matters. Leave as empty string unless the email is important enough to alert the user immediately.",
+			},
+			"draft_reply": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Whether to create a draft reply for this email. Set to true only for emails from humans that expect or would benefit from a response. Never draft replies to newsletters, notifications, automated messages, or marketing emails.",
+			},
+			"reasoning": map[string]interface{}{
+				"type":        "string",
+				"description": "Brief explanation of the decision",
+			},
+			"confidence": map[string]interface{}{
+				"type":        "number",
+				"description": "How confident you are in these decisions, from 0.0 (guessing) to 1.0 (certain)",
+			},
+		},
+		"required":             []string{"labels", "bypass_inbox", "notification_message", "draft_reply", "reasoning", "confidence"},
+		"additionalProperties": false,
+	},
+}
+
+// AnalyzeEmail runs Stage 1: Content analysis
+func (c *Client) AnalyzeEmail(ctx context.Context, from, subject, body string, senderContext string, customSystemPrompt string) (*EmailAnalysis, error) {
+	systemPrompt := customSystemPrompt
+	if systemPrompt == "" {
+		// Default prompt if none provided
+		systemPrompt = defaultAnalyzePrompt
+	}
+
+	systemPrompt += transactionRule + eventRule + taskRule + shipmentRule + untrustedContentRule
+
+	userPrompt := fmt.Sprintf(`%s
+
+%s%sAnalyze this email and provide the slug, keywords, summary, transaction, event, tasks and shipment.`, wrapUntrusted(from, subject, body), todayContext(), senderContext)
+
+	c.logPrompts("AnalyzeEmail", systemPrompt, userPrompt)
+
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "AnalyzeEmail",
+		Task:         llm.TaskAnalyze,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    10000,
+		Validate: func(content string) error {
+			_, err := parseAnalysis(content)
+			return err
+		},
+	}, analysisSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAnalysis(response.Content)
+}
+
+// snakeCase matches slugs like "marketing_newsletter"
+var snakeCase = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// parseAnalysis decodes and validates a Stage 1 response
+func parseAnalysis(content string) (*EmailAnalysis, error) {
+	var analysis EmailAnalysis
+	if err := json.Unmarshal([]byte(content), &analysis); err != nil {
+		return nil, &ValidationError{Reason: fmt.Sprintf("failed to parse AI response (content: %q): %v", content, err)}
+	}
+	if !snakeCase.MatchString(analysis.Slug) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("slug %q is not snake_case", analysis.Slug)}
+	}
+	if analysis.Keywords == nil {
+		analysis.Keywords = []string{}
+	}
+	transaction, err := normalizeTransaction(analysis.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Transaction = transaction
+	event, err := normalizeEvent(analysis.Event)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Event = event
+	tasks, err := normalizeTasks(analysis.Tasks)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Tasks = tasks
+	shipment, err := normalizeShipment(analysis.Shipment)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Shipment = shipment
+	return &analysis, nil
+}
+
+// DetermineActions runs Stage 2: Action generation
+// labelNames is the list of valid label names (for response validation)
+// formattedLabels is a human-readable bullet list with descriptions (for the prompt)
+// memoryContext is the formatted memory string from past learnings
+func (c *Client) DetermineActions(ctx context.Context, from, subject, slug string, keywords []string, summary string, labelNames []string, formattedLabels string, senderContext string, memoryContext string, customSystemPrompt string) (*EmailActions, error) {
+	systemPrompt := customSystemPrompt
+	if systemPrompt == "" {
+		// Default prompt if none provided
+		systemPrompt = defaultActionsPrompt
+	}
+
+	if customSystemPrompt == "" {
+		// Default prompt has %s placeholder for labels
+		systemPrompt = fmt.Sprintf(systemPrompt, formattedLabels)
+	} else {
+		// Always append labels to custom prompts so they're never lost
+		systemPrompt += "\n\nAvailable labels:\n" + formattedLabels
+	}
+
+	systemPrompt += untrustedContentRule
+
+	userPrompt := fmt.Sprintf(`%s
+Slug: %s
+Keywords: %v
+Summary: %s
+
+%s%sWhat actions should be taken for this email?`, wrapUntrusted(from, subject, ""), slug, keywords, summary, senderContext, memoryContext)
+
+	c.logPrompts("DetermineActions", systemPrompt, userPrompt)
+
+	req := llm.Request{
+		Name:         "DetermineActions",
+		Task:         llm.TaskActions,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    10000,
+		Validate: func(content string) error {
+			_, err := parseActions(content, labelNames)
+			return err
+		},
+	}
+
+	schema := actionsSchema
+	actions, err := c.decideActions(ctx, req, schema, labelNames)
+
+	// Escalate to the stronger model on low confidence or an invalid response
+	confidence := noConfidence
+	if err == nil {
+		confidence = actions.Confidence
+	}
+	if reason := c.escalationReason(err, confidence); reason != "" {
+		log.Printf("Escalating DetermineActions to %s: %s", c.escalationModel, reason)
+		req.Model = c.escalationModel
+		escalated, escErr := c.decideActions(ctx, req, schema, labelNames)
+		if escErr == nil {
+			escalated.Escalated = true
+			return escalated, nil
+		}
+		log.Printf("Escalated DetermineActions failed, keeping original decision: %v", escErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// noConfidence is passed to escalationReason for calls that don't report a confidence
+const noConfidence = -1.0
+
+// escalationReason returns why a decision should be retried on the escalation model: the response
+// failed validation, or its confidence is below the threshold. Returns "" when it should stand or
+// escalation is off.
+func (c *Client) escalationReason(err error, confidence float64) string {
+	if c.escalationModel == "" || c.model == c.escalationModel {
+		return ""
+	}
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Error()
+	}
+	if err == nil && confidence != noConfidence && confidence < c.escalationThreshold {
+		return fmt.Sprintf("confidence %.2f below threshold %.2f", confidence, c.escalationThreshold)
+	}
+	return ""
+}
+
+// decideActions runs one Stage 2 call and validates the result
+func (c *Client) decideActions(ctx context.Context, req llm.Request, schema llm.Schema, labelNames []string) (*EmailActions, error) {
+	response, err := c.completeJSON(ctx, req, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := parseActions(response.Content, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	actions.Model = response.Model
+
+	return actions, nil
+}
+
+// parseActions decodes and validates a Stage 2 response
+func parseActions(content string, labelNames []string) (*EmailActions, error) {
+	var actions EmailActions
+	if err := json.Unmarshal([]byte(content), &actions); err != nil {
+		return nil, &ValidationError{Reason: fmt.Sprintf("failed to parse AI response (content: %q): %v", content, err)}
+	}
+	if err := validateActions(&actions, labelNames); err != nil {
+		return nil, err
+	}
+	return &actions, nil
+}
+
+// ValidationError reports an AI response that parsed but did not satisfy the expected constraints
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid AI response: " + e.Reason
+}
+
+// validateActions checks that labels exist and confidence is in range
+func validateActions(actions *EmailActions, labelNames []string) error {
+	known := make(map[string]bool, len(labelNames))
+	for _, name := range labelNames {
+		known[name] = true
+	}
+	for _, label := range actions.Labels {
+		if !known[label] && !strings.HasPrefix(label, "📥/") && !strings.HasPrefix(label, "🗑️/") {
+			return &ValidationError{Reason: fmt.Sprintf("unknown label %q", label)}
+		}
+	}
+	if actions.Confidence < 0 || actions.Confidence > 1 {
+		return &ValidationError{Reason: fmt.Sprintf("confidence %v out of range", actions.Confidence)}
+	}
+	return nil
+}
+
+// MemoryResult represents structured memory output with reasoning
+type MemoryResult struct {
+	Content   string `json:"content"`
+	Reasoning string `json:"reasoning"`
+}
+
+// GenerateMemoryWithReasoning creates a memory with structured JSON output including reasoning
+func (c *Client) GenerateMemoryWithReasoning(ctx context.Context, systemPrompt, userPrompt string) (*MemoryResult, error) {
+	// Append JSON instruction to system prompt
+	structuredSystemPrompt := systemPrompt + `
+
+IMPORTANT: You must respond with a JSON object containing two fields:
+- "content": Your memory content (the actual memory text)
+- "reasoning": Your editorial reasoning explaining what you considered important, what you dropped, and why you made the decisions you did`
+
+	c.logPrompts("GenerateMemoryWithReasoning", structuredSystemPrompt, userPrompt)
+
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "GenerateMemoryWithReasoning",
+		Task:         llm.TaskMemory,
+		SystemPrompt: structuredSystemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    20000,
+	}, llm.Schema{
+		Name:        "memory_result",
+		Description: "Memory content with editorial reasoning",
+		Definition: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The actual memory content text",
+				},
+				"reasoning": map[string]interface{}{
+					"type":        "string",
+					"description": "Editorial reasoning explaining what was considered important, what was dropped, and why",
+				},
+			},
+			"required":             []string{"content", "reasoning"},
+			"additionalProperties": false,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := response.Content
+
+	var result MemoryResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse memory result (content: %q): %w", content, err)
+	}
+
+	return &result, nil
+}
+
+// GenerateMemory creates a memory summary from email analysis
+func (c *Client) GenerateMemory(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.complete(ctx, llm.Request{
+		Name:         "GenerateMemory",
+		Task:         llm.TaskMemory,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    20000,
+	})
+}
+
+// GenerateReport creates a short free-form summary for wrapup reports
+func (c *Client) GenerateReport(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.complete(ctx, llm.Request{
+		Name:         "GenerateReport",
+		Task:         llm.TaskWrapup,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    20000,
+	})
+}
+
+// GenerateDraftReply generates a draft reply body for an email using AI.
+func (c *Client) GenerateDraftReply(ctx context.Context, from, subject, body, senderContext, customPrompt string) (string, error) {
+	systemPrompt := `You are drafting a reply to an email on behalf of the user. Write a natural, professional response that:
+- Addresses the key points in the original email
+- Is concise and to the point
+- Matches a professional but friendly tone
+- Does NOT include a subject line — only the body text
+- Does NOT include greetings like "Dear..." unless the original email used them
+- Ends with a simple sign-off if appropriate
+
+The user will review and edit this draft before sending, so aim for a good starting point rather than a perfect response.`
+
+	if customPrompt != "" {
+		systemPrompt += "\n\nAdditional context about the user's preferences:\n" + customPrompt
+	}
+
+	systemPrompt += untrustedContentRule
+
+	userPrompt := fmt.Sprintf("%s\n\n%sDraft a reply to this email.", wrapUntrusted(from, subject, body), senderContext)
+
+	c.logPrompts("GenerateDraftReply", systemPrompt, userPrompt)
+
+	return c.complete(ctx, llm.Request{
+		Name:         "GenerateDraftReply",
+		Task:         llm.TaskDraft,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    10000,
+	})
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is a calendar event extracted from the body of an email without an invite attached
+// (booking confirmations, appointment reminders, tickets). Stage 1 returns nil for every other email.
+type Event struct {
+	Title    string `json:"title"`
+	Start    string `json:"start"`     // YYYY-MM-DDTHH:MM local time, or YYYY-MM-DD for all-day events
+	End      string `json:"end"`       // Same format as start, or "" if not stated
+	AllDay   bool   `json:"all_day"`   // True when only dates are given
+	Location string `json:"location"`  // Address, venue or meeting link
+	TimeZone string `json:"time_zone"` // IANA zone of start/end, e.g. "Europe/London", or "" if unknown
+}
+
+// eventRule is appended to the analysis prompt (default or custom) so extraction is never lost
+const eventRule = `
+
+Also extract event: for emails confirming or inviting the user to something at a specific date (bookings, reservations, appointments, flights, tickets, meetings), an object with
+- title: a short calendar title (e.g. "Dinner at Nopa", "Flight BA283 LHR → SFO")
+- start: YYYY-MM-DDTHH:MM in the event's local time, or YYYY-MM-DD if no time is given
+- end: same format as start, or "" if no end is stated
+- all_day: true if only dates are given
+- location: the address, venue or meeting link, or ""
+- time_zone: IANA time zone of start and end (e.g. "America/New_York") if stated or clear from the location, otherwise ""
+For every other email, event is null.`
+
+// eventProperty is the schema of the event field (nullable)
+var eventProperty = map[string]interface{}{
+	"type":        []string{"object", "null"},
+	"description": "Calendar event the email confirms or invites to; null for other emails",
+	"properties": map[string]interface{}{
+		"title": map[string]interface{}{
+			"type": "string",
+		},
+		"start": map[string]interface{}{
+			"type":        "string",
+			"description": "YYYY-MM-DDTHH:MM or YYYY-MM-DD",
+		},
+		"end": map[string]interface{}{
+			"type":        "string",
+			"description": "YYYY-MM-DDTHH:MM, YYYY-MM-DD or empty",
+		},
+		"all_day": map[string]interface{}{
+			"type": "boolean",
+		},
+		"location": map[string]interface{}{
+			"type": "string",
+		},
+		"time_zone": map[string]interface{}{
+			"type":        "string",
+			"description": "IANA time zone or empty",
+		},
+	},
+	"required":             []string{"title", "start", "end", "all_day", "location", "time_zone"},
+	"additionalProperties": false,
+}
+
+// Layouts accepted for event start and end
+const (
+	eventDateTimeLayout = "2006-01-02T15:04"
+	eventDateLayout     = "2006-01-02"
+)
+
+// normalizeEvent validates an extracted event and tidies its fields.
+// An event without a title or start is dropped rather than rejected.
+func normalizeEvent(e *Event) (*Event, error) {
+	if e == nil {
+		return nil, nil
+	}
+	e.Title = strings.TrimSpace(e.Title)
+	e.Start = strings.TrimSpace(e.Start)
+	e.End = strings.TrimSpace(e.End)
+	e.Location = strings.TrimSpace(e.Location)
+	if e.Title == "" || e.Start == "" {
+		return nil, nil
+	}
+	if _, err := parseEventTime(e.Start, time.UTC); err != nil {
+		return nil, &ValidationError{Reason: fmt.Sprintf("event start %q is not YYYY-MM-DDTHH:MM or YYYY-MM-DD", e.Start)}
+	}
+	if e.End != "" {
+		if _, err := parseEventTime(e.End, time.UTC); err != nil {
+			return nil, &ValidationError{Reason: fmt.Sprintf("event end %q is not YYYY-MM-DDTHH:MM or YYYY-MM-DD", e.End)}
+		}
+	}
+	e.AllDay = len(e.Start) == len(eventDateLayout)
+	// An unknown zone falls back to the server's local time rather than failing the analysis
+	if _, err := time.LoadLocation(e.TimeZone); e.TimeZone == "" || err != nil {
+		e.TimeZone = ""
+	}
+	return e, nil
+}
+
+// Times returns the event's start and end (nil if it has none) in its time zone.
+// Times without a zone are read in the server's local time; all-day events are dates at UTC midnight.
+func (e *Event) Times() (time.Time, *time.Time, error) {
+	loc := time.Local
+	if e.AllDay {
+		loc = time.UTC
+	} else if e.TimeZone != "" {
+		if zone, err := time.LoadLocation(e.TimeZone); err == nil {
+			loc = zone
+		}
+	}
+	start, err := parseEventTime(e.Start, loc)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to parse event start: %w", err)
+	}
+	if e.End == "" {
+		return start, nil, nil
+	}
+	end, err := parseEventTime(e.End, loc)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to parse event end: %w", err)
+	}
+	if !end.After(start) {
+		return start, nil, nil
+	}
+	return start, &end, nil
+}
+
+// parseEventTime parses a date-time or date in loc
+func parseEventTime(value string, loc *time.Location) (time.Time, error) {
+	if len(value) == len(eventDateLayout) {
+		return time.ParseInLocation(eventDateLayout, value, loc)
+	}
+	return time.ParseInLocation(eventDateTimeLayout, value, loc)
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+type userKey struct{}
+
+// WithUser returns a context whose AI calls are billed to the given user
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+func userFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userKey{}).(int64)
+	return userID, ok
+}
+
+// Meter records the token usage and estimated cost of every AI call made for a user
+type Meter struct {
+	llm    llm.LLM
+	db     *database.DB
+	prices llm.Prices
+}
+
+// NewMeter wraps an LLM so that calls made with a WithUser context are written to the usage ledger
+func NewMeter(provider llm.LLM, db *database.DB, prices llm.Prices) *Meter {
+	return &Meter{llm: provider, db: db, prices: prices}
+}
+
+// Complete runs and records a plain text completion
+func (m *Meter) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	response, err := m.llm.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m.record(ctx, req, response)
+	return response, nil
+}
+
+// CompleteJSON runs and records a structured completion
+func (m *Meter) CompleteJSON(ctx context.Context, req llm.Request, schema llm.Schema) (*llm.Response, error) {
+	response, err := m.llm.CompleteJSON(ctx, req, schema)
+	if err != nil {
+		return nil, err
+	}
+	m.record(ctx, req, response)
+	return response, nil
+}
+
+// record writes a usage row (non-critical: failures are logged)
+func (m *Meter) record(ctx context.Context, req llm.Request, response *llm.Response) {
+	recordLedger(ctx, m.db, m.prices, req.Task, response.Model, response.Usage)
+}
+
+// EmbedMeter records the token usage and estimated cost of embedding calls made for a user
+type EmbedMeter struct {
+	embedder llm.Embedder
+	db       *database.DB
+	prices   llm.Prices
+}
+
+// NewEmbedMeter wraps an Embedder so that calls made with a WithUser context are written to the usage ledger
+func NewEmbedMeter(embedder llm.Embedder, db *database.DB, prices llm.Prices) *EmbedMeter {
+	return &EmbedMeter{embedder: embedder, db: db, prices: prices}
+}
+
+// Embed runs and records an embedding request
+func (m *EmbedMeter) Embed(ctx context.Context, req llm.EmbedRequest) (*llm.EmbedResponse, error) {
+	response, err := m.embedder.Embed(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	recordLedger(ctx, m.db, m.prices, llm.TaskEmbed, response.Model, response.Usage)
+	return response, nil
+}
+
+// recordLedger adds the call's cost to the context's usage and writes a usage row for the user in the
+// context, if any (non-critical: failures are logged)
+func recordLedger(ctx context.Context, db *database.DB, prices llm.Prices, task, model string, tokens llm.Usage) {
+	cost := prices.Cost(model, tokens)
+	recordCost(ctx, cost)
+
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	if _, known := prices.Lookup(model); !known {
+		log.Printf("Warning: no price configured for model %q; recording cost as 0", model)
+	}
+	usage := &database.AIUsage{
+		UserID:           userID,
+		Task:             task,
+		Model:            model,
+		PromptTokens:     tokens.PromptTokens,
+		CompletionTokens: tokens.CompletionTokens,
+		CostUSD:          cost,
+		CreatedAt:        time.Now(),
+	}
+	if err := db.RecordAIUsage(ctx, usage); err != nil {
+		log.Printf("Failed to record AI usage for user %d: %v", userID, err)
+	}
+}
@@ -1,4 +1,4 @@
-package openai
+package ai
 
 import (
 	"context"
@@ -7,9 +7,7 @@ import (
 	"strings"
 
 	"github.com/den/gmail-triage-assistant/internal/database"
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/packages/param"
-	"github.com/openai/openai-go/shared"
+	"github.com/den/gmail-triage-assistant/internal/llm"
 )
 
 // ProfileBootstrapResult contains the AI-generated fields for a new profile
@@ -60,57 +58,35 @@ Classify sender_type as:
 
 	c.logPrompts("BootstrapSenderProfile", systemPrompt, userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: shared.ChatModel(c.model),
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
-				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
-					Name:        "profile_bootstrap",
-					Description: param.NewOpt("Sender profile classification and summary"),
-					Strict:      param.NewOpt(true),
-					Schema: map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"sender_type": map[string]interface{}{
-								"type":        "string",
-								"description": "Classification: human, newsletter, automated, marketing, notification, or mixed",
-							},
-							"summary": map[string]interface{}{
-								"type":        "string",
-								"description": "2-3 sentence description of the sender",
-							},
-						},
-						"required":             []string{"sender_type", "summary"},
-						"additionalProperties": false,
-					},
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "BootstrapSenderProfile",
+		Task:         llm.TaskProfile,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    5000,
+	}, llm.Schema{
+		Name:        "profile_bootstrap",
+		Description: "Sender profile classification and summary",
+		Definition: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"sender_type": map[string]interface{}{
+					"type":        "string",
+					"description": "Classification: human, newsletter, automated, marketing, notification, or mixed",
+				},
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "2-3 sentence description of the sender",
 				},
 			},
+			"required":             []string{"sender_type", "summary"},
+			"additionalProperties": false,
 		},
-		MaxCompletionTokens: openai.Int(5000),
 	})
-
 	if err != nil {
-		return nil, fmt.Errorf("openai api error: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no response from openai")
-	}
-
-	choice := response.Choices[0]
-
-	if choice.Message.Refusal != "" {
-		return nil, fmt.Errorf("openai refused request: %s", choice.Message.Refusal)
-	}
-
-	content := choice.Message.Content
-	if content == "" {
-		return nil, fmt.Errorf("empty content from openai (finish_reason: %s)", choice.FinishReason)
+		return nil, err
 	}
+	content := response.Content
 
 	var result ProfileBootstrapResult
 	if err := json.Unmarshal([]byte(content), &result); err != nil {
@@ -156,57 +132,35 @@ New email processed:
 
 	c.logPrompts("EvolveProfileSummary", systemPrompt, userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: shared.ChatModel(c.model),
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
-				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
-					Name:        "profile_update",
-					Description: param.NewOpt("Updated sender profile classification and summary"),
-					Strict:      param.NewOpt(true),
-					Schema: map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"sender_type": map[string]interface{}{
-								"type":        "string",
-								"description": "Classification: human, newsletter, automated, marketing, notification, or mixed",
-							},
-							"summary": map[string]interface{}{
-								"type":        "string",
-								"description": "Updated 2-3 sentence summary",
-							},
-						},
-						"required":             []string{"sender_type", "summary"},
-						"additionalProperties": false,
-					},
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "EvolveProfileSummary",
+		Task:         llm.TaskProfile,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    5000,
+	}, llm.Schema{
+		Name:        "profile_update",
+		Description: "Updated sender profile classification and summary",
+		Definition: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"sender_type": map[string]interface{}{
+					"type":        "string",
+					"description": "Classification: human, newsletter, automated, marketing, notification, or mixed",
+				},
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "Updated 2-3 sentence summary",
 				},
 			},
+			"required":             []string{"sender_type", "summary"},
+			"additionalProperties": false,
 		},
-		MaxCompletionTokens: openai.Int(5000),
 	})
-
 	if err != nil {
-		return nil, fmt.Errorf("openai api error: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no response from openai")
-	}
-
-	choice := response.Choices[0]
-
-	if choice.Message.Refusal != "" {
-		return nil, fmt.Errorf("openai refused request: %s", choice.Message.Refusal)
-	}
-
-	content := choice.Message.Content
-	if content == "" {
-		return nil, fmt.Errorf("empty content from openai (finish_reason: %s)", choice.FinishReason)
+		return nil, err
 	}
+	content := response.Content
 
 	var result ProfileBootstrapResult
 	if err := json.Unmarshal([]byte(content), &result); err != nil {
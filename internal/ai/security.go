@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// Who recognised a security email
+const (
+	SecurityDetectedByRules = "rules"
+	SecurityDetectedByAI    = "ai"
+)
+
+// securityBodyWindow is how much of the body the heuristics read. Security notices lead with
+// their purpose; newsletters mention passwords and sign-ins in their footers.
+const securityBodyWindow = 1500
+
+// SecurityReport is the result of checking an email for one-time codes, password resets,
+// new-login alerts and account recovery
+type SecurityReport struct {
+	Kind       string // database.SecurityKind*
+	Code       string // One-time code, "" if none was found
+	Confident  bool   // False when only the body hinted at the kind; the AI decides those
+	DetectedBy string // SecurityDetectedByRules or SecurityDetectedByAI
+}
+
+// securityPatterns recognise each kind in the subject (confident) or the start of the body
+// (ambiguous). Earlier kinds win.
+var securityPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{database.SecurityKindAccountRecovery, regexp.MustCompile(`(?i)\b(account|password) recovery\b|\brecover (your|my) account\b|\brecovery (email|phone|phone number|address) (was )?(added|changed|updated|removed)\b`)},
+	{database.SecurityKindPasswordReset, regexp.MustCompile(`(?i)\b(reset|change|update|forgot)\b.{0,20}\bpassword\b|\bpassword\b.{0,20}\b(reset|changed|updated)\b`)},
+	{database.SecurityKindNewLogin, regexp.MustCompile(`(?i)\bnew (sign[- ]?in|log[- ]?in|device|browser)\b|\b(sign[- ]?in|log[- ]?in) (attempt|alert|from|detected)\b|\b(signed|logged) in (from|on|to)\b|\bunusual (sign[- ]?in|log[- ]?in|activity)\b`)},
+	{database.SecurityKindOTP, regexp.MustCompile(`(?i)\b(verification|security|one[- ]time|login|log[- ]in|sign[- ]?in|authentication|confirmation|access|2fa|mfa)\s+(code|pin|passcode)\b|\botp\b|\bpasscode\b|\b(is|as) your (\w+ )?code\b|\b(use|enter) (this|the following|the) code\b`)},
+}
+
+// otpPatterns find the code in a one-time code email, most specific first.
+// The first capture group is the code.
+var otpPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bG-([0-9]{6})\b`), // Google
+	regexp.MustCompile(`(?i)\b([0-9]{4,8}|[0-9]{3}[- ][0-9]{3})\s+is your\b`),
+	regexp.MustCompile(`(?i)\b(?:code|otp|passcode|pin)\b(?:\s+is)?[\s:]{0,4}\b([0-9]{4,8}|[0-9]{3}[- ][0-9]{3})\b`),
+	regexp.MustCompile(`(?i)\b(?:code|otp|passcode|pin)\b[^0-9\n]{0,40}\n\s*([0-9]{4,8}|[0-9]{3}[- ][0-9]{3})\s*(?:\n|$)`),
+	regexp.MustCompile(`(?i)\b(?:code|otp|passcode)\b(?:\s+is)?[\s:]{0,4}\b([A-Z0-9]{6,8})\b`),
+}
+
+// codePattern is what a one-time code looks like after normalisation
+var codePattern = regexp.MustCompile(`^[0-9A-Z]{4,10}$`)
+
+// DetectSecurity checks an email for security content with heuristics. Returns nil when neither
+// the subject nor the start of the body looks like a security email.
+func DetectSecurity(subject, body string) *SecurityReport {
+	if len(body) > securityBodyWindow {
+		body = body[:securityBodyWindow]
+	}
+
+	var report *SecurityReport
+	for _, p := range securityPatterns {
+		if p.pattern.MatchString(subject) {
+			report = &SecurityReport{Kind: p.kind, Confident: true}
+			break
+		}
+		if report == nil && p.pattern.MatchString(body) {
+			report = &SecurityReport{Kind: p.kind}
+		}
+	}
+	if report == nil {
+		return nil
+	}
+	report.DetectedBy = SecurityDetectedByRules
+
+	if report.Kind == database.SecurityKindOTP {
+		report.Code = ExtractOTP(subject + "\n" + body)
+		// A code next to the wording is as good as a subject match
+		if report.Code != "" {
+			report.Confident = true
+		}
+	}
+	return report
+}
+
+// ExtractOTP returns the one-time code in the content, or "" if none is found
+func ExtractOTP(content string) string {
+	for _, p := range otpPatterns {
+		for _, m := range p.FindAllStringSubmatch(content, -1) {
+			code := normalizeCode(m[1])
+			// Letters-only words ("number", "expires") are not codes
+			if codePattern.MatchString(code) && strings.ContainsAny(code, "0123456789") {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeCode drops the separators people type codes without
+func normalizeCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// securityVerdictSchema is the structured output of ClassifySecurity
+var securityVerdictSchema = llm.Schema{
+	Name:        "security_verdict",
+	Description: "Whether an email is a one-time code, password reset, new-login alert or account recovery email",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"enum":        append([]string{"none"}, database.SecurityKinds...),
+				"description": "The kind of security email, or none",
+			},
+			"code": map[string]interface{}{
+				"type":        "string",
+				"description": "The one-time code exactly as written, or \"\"",
+			},
+		},
+		"required":             []string{"kind", "code"},
+		"additionalProperties": false,
+	},
+}
+
+// ClassifySecurity asks the AI what kind of security email an email is.
+// Used for emails the heuristics find ambiguous; returns nil for ordinary emails.
+func (c *Client) ClassifySecurity(ctx context.Context, from, subject, body string) (*SecurityReport, error) {
+	systemPrompt := `You classify emails about the security of the recipient's own accounts.
+- otp: a one-time, verification, login or MFA code for the recipient to enter
+- password_reset: a password reset link, or notice that the password was changed
+- new_login: an alert about a new sign-in, device or unusual account activity
+- account_recovery: an account recovery request, or a change to recovery email/phone details
+- none: anything else, including newsletters, marketing and articles that merely mention passwords or security
+For otp, also return the code exactly as written; otherwise code is "".` + untrustedContentRule
+
+	userPrompt := wrapUntrusted(from, subject, body) + "\n\nWhat kind of security email is this?"
+
+	c.logPrompts("ClassifySecurity", systemPrompt, userPrompt)
+
+	response, err := c.completeJSON(ctx, llm.Request{
+		Name:         "ClassifySecurity",
+		Task:         llm.TaskGuard,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    500,
+		Validate: func(content string) error {
+			_, err := parseSecurityVerdict(content)
+			return err
+		},
+	}, securityVerdictSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSecurityVerdict(response.Content)
+}
+
+// parseSecurityVerdict decodes a classifier response into a report (nil for "none")
+func parseSecurityVerdict(content string) (*SecurityReport, error) {
+	var verdict struct {
+		Kind string `json:"kind"`
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal([]byte(content), &verdict); err != nil {
+		return nil, fmt.Errorf("failed to parse security verdict: %w", err)
+	}
+	if verdict.Kind == "none" {
+		return nil, nil
+	}
+	if !database.IsValidSecurityKind(verdict.Kind) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("unknown security kind %q", verdict.Kind)}
+	}
+
+	report := &SecurityReport{Kind: verdict.Kind, Confident: true, DetectedBy: SecurityDetectedByAI}
+	if verdict.Kind == database.SecurityKindOTP {
+		if code := normalizeCode(verdict.Code); codePattern.MatchString(code) {
+			report.Code = code
+		}
+	}
+	return report, nil
+}
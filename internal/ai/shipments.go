@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// Shipment is the package tracking data extracted from a shipping notification.
+// Stage 1 returns nil for every other email.
+type Shipment struct {
+	Carrier        string `json:"carrier"`         // e.g. "UPS", "DHL", "Royal Mail"
+	TrackingNumber string `json:"tracking_number"` // "" if not stated
+	OrderRef       string `json:"order_ref"`       // Merchant order number, "" if not stated
+	Status         string `json:"status"`          // database.ShipmentStatus*
+}
+
+// shipmentRule is appended to the analysis prompt (default or custom) so extraction is never lost
+const shipmentRule = `
+
+Also extract shipment: for shipping notifications about a package sent to the user, an object with
+- carrier: the delivery company (e.g. "UPS", "USPS", "DHL", "Royal Mail"), or "" if not stated
+- tracking_number: the package tracking number exactly as written, or ""
+- order_ref: the merchant's order number exactly as written, or ""
+- status: shipped | out_for_delivery | delivered | exception (exception = delayed, failed delivery attempt, returned or lost)
+For every other email, shipment is null.`
+
+// shipmentProperty is the schema of the shipment field (nullable)
+var shipmentProperty = map[string]interface{}{
+	"type":        []string{"object", "null"},
+	"description": "Package tracking data for shipping notifications; null for other emails",
+	"properties": map[string]interface{}{
+		"carrier": map[string]interface{}{
+			"type": "string",
+		},
+		"tracking_number": map[string]interface{}{
+			"type": "string",
+		},
+		"order_ref": map[string]interface{}{
+			"type": "string",
+		},
+		"status": map[string]interface{}{
+			"type": "string",
+			"enum": database.ShipmentStatuses,
+		},
+	},
+	"required":             []string{"carrier", "tracking_number", "order_ref", "status"},
+	"additionalProperties": false,
+}
+
+// Tracking number formats that identify their carrier
+var trackingCarriers = []struct {
+	carrier string
+	pattern *regexp.Regexp
+}{
+	{"UPS", regexp.MustCompile(`^1Z[0-9A-Z]{16}$`)},
+	{"USPS", regexp.MustCompile(`^(94|93|92|95)[0-9]{20}$`)},
+	{"Royal Mail", regexp.MustCompile(`^[A-Z]{2}[0-9]{9}GB$`)},
+}
+
+// NormalizeTrackingNumber removes the spacing from a tracking number and upper-cases it
+func NormalizeTrackingNumber(number string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(number), ""))
+}
+
+// NormalizeOrderRef trims an order reference and its leading "#"
+func NormalizeOrderRef(ref string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(ref), "#"))
+}
+
+// normalizeShipment validates an extracted shipment and tidies its fields. A shipment with
+// neither a tracking number nor an order reference cannot be grouped and is dropped.
+func normalizeShipment(s *Shipment) (*Shipment, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.Carrier = strings.TrimSpace(s.Carrier)
+	s.TrackingNumber = NormalizeTrackingNumber(s.TrackingNumber)
+	s.OrderRef = NormalizeOrderRef(s.OrderRef)
+	if s.TrackingNumber == "" && s.OrderRef == "" {
+		return nil, nil
+	}
+	if !database.IsValidShipmentStatus(s.Status) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("unknown shipment status %q", s.Status)}
+	}
+	if s.Carrier == "" {
+		for _, tc := range trackingCarriers {
+			if tc.pattern.MatchString(s.TrackingNumber) {
+				s.Carrier = tc.carrier
+				break
+			}
+		}
+	}
+	return s, nil
+}
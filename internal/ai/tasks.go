@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTasks caps the action items kept per email
+const maxTasks = 5
+
+// Task is an action item an email asks of the user (e.g. "Sign the lease by Friday")
+type Task struct {
+	Description string `json:"description"`
+	DueDate     string `json:"due_date"` // YYYY-MM-DD if the email states a deadline, otherwise ""
+}
+
+// taskRule is appended to the analysis prompt (default or custom) so extraction is never lost
+const taskRule = `
+
+Also extract tasks: the action items this email asks of the user personally (e.g. "Sign the lease", "Review PR #412", "Pay the invoice"), each with
+- description: a short imperative sentence naming what to do and for whom or what
+- due_date: YYYY-MM-DD if a deadline is stated or implied by a weekday or relative date (resolve weekdays and relative dates against today's date, given with the email), otherwise ""
+Only include requests aimed at the user; skip marketing calls to action, requests to other recipients and things already done. Return [] when there are none.`
+
+// taskProperty is the schema of the tasks field
+var taskProperty = map[string]interface{}{
+	"type":        "array",
+	"description": "Action items the email asks of the user; empty for most emails",
+	"items": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"description": map[string]interface{}{
+				"type": "string",
+			},
+			"due_date": map[string]interface{}{
+				"type":        "string",
+				"description": "YYYY-MM-DD or empty",
+			},
+		},
+		"required":             []string{"description", "due_date"},
+		"additionalProperties": false,
+	},
+}
+
+// normalizeTasks validates extracted tasks, drops blank ones and caps the list at maxTasks
+func normalizeTasks(tasks []Task) ([]Task, error) {
+	normalized := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		t.Description = strings.TrimSpace(t.Description)
+		t.DueDate = strings.TrimSpace(t.DueDate)
+		if t.Description == "" {
+			continue
+		}
+		if t.DueDate != "" {
+			if _, err := time.Parse("2006-01-02", t.DueDate); err != nil {
+				return nil, &ValidationError{Reason: fmt.Sprintf("task due_date %q is not YYYY-MM-DD", t.DueDate)}
+			}
+		}
+		normalized = append(normalized, t)
+		if len(normalized) == maxTasks {
+			break
+		}
+	}
+	return normalized, nil
+}
+
+// todayContext states the current date so relative deadlines ("by Friday") resolve to dates
+func todayContext() string {
+	return fmt.Sprintf("Today is %s.\n\n", time.Now().Format("Monday, 2 January 2006"))
+}
+
+// ParseDueDate returns the task's due date, or nil if it has none
+func (t Task) ParseDueDate() *time.Time {
+	if t.DueDate == "" {
+		return nil
+	}
+	due, err := time.Parse("2006-01-02", t.DueDate)
+	if err != nil {
+		return nil
+	}
+	return &due
+}
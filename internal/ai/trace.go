@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// TraceCall is a single recorded AI call: the exact prompts sent and the raw output received
+type TraceCall struct {
+	Name             string `json:"name"`
+	Model            string `json:"model"`
+	SystemPrompt     string `json:"system_prompt"`
+	UserPrompt       string `json:"user_prompt"`
+	Output           string `json:"output"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// Trace records every AI call made with a context, in order
+type Trace struct {
+	mu    sync.Mutex
+	calls []TraceCall
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that records the prompts and outputs of every AI call made with it
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	t := &Trace{}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// Calls returns a copy of the recorded calls
+func (t *Trace) Calls() []TraceCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	calls := make([]TraceCall, len(t.calls))
+	copy(calls, t.calls)
+	return calls
+}
+
+// Find returns the most recent call with the given name, or nil
+func (t *Trace) Find(name string) *TraceCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.calls) - 1; i >= 0; i-- {
+		if t.calls[i].Name == name {
+			call := t.calls[i]
+			return &call
+		}
+	}
+	return nil
+}
+
+// FindPrefix returns the most recent call whose name starts with prefix, or nil
+func (t *Trace) FindPrefix(prefix string) *TraceCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.calls) - 1; i >= 0; i-- {
+		if strings.HasPrefix(t.calls[i].Name, prefix) {
+			call := t.calls[i]
+			return &call
+		}
+	}
+	return nil
+}
+
+// recordCall records token usage and, when tracing, the call's prompts and output
+func recordCall(ctx context.Context, req llm.Request, response *llm.Response) {
+	recordUsage(ctx, response.Usage)
+
+	t, ok := ctx.Value(traceKey{}).(*Trace)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, TraceCall{
+		Name:             req.Name,
+		Model:            response.Model,
+		SystemPrompt:     req.SystemPrompt,
+		UserPrompt:       req.UserPrompt,
+		Output:           response.Content,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+	})
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// Transaction is the structured data extracted from a receipt, invoice, shipping confirmation,
+// refund or booking. Stage 1 returns nil for every other email.
+type Transaction struct {
+	Vendor       string   `json:"vendor"`
+	DocumentType string   `json:"document_type"` // database.DocumentType*
+	Amount       *float64 `json:"amount"`        // Total charged, refunded or due (nil if not stated)
+	Currency     string   `json:"currency"`      // ISO 4217 code, e.g. "USD"
+	DueDate      string   `json:"due_date"`      // YYYY-MM-DD for invoices with a due date, otherwise ""
+}
+
+// transactionRule is appended to the analysis prompt (default or custom) so extraction is never lost
+const transactionRule = `
+
+Also extract transaction: for receipts, invoices, shipping confirmations, refunds and bookings, an object with
+- vendor: the business that charged, refunded or invoiced (e.g. "Amazon", not "Amazon.com Services LLC")
+- document_type: receipt | invoice | shipping | refund | booking
+- amount: the total charged, refunded or due as a number (e.g. 42.5), or null if no total is stated
+- currency: ISO 4217 code of the amount (e.g. "USD"), or "" if there is no amount
+- due_date: YYYY-MM-DD for invoices that state a due date, otherwise ""
+For every other email, transaction is null.`
+
+// transactionProperty is the schema of the transaction field (nullable)
+var transactionProperty = map[string]interface{}{
+	"type":        []string{"object", "null"},
+	"description": "Structured data for receipts, invoices, shipping confirmations, refunds and bookings; null for other emails",
+	"properties": map[string]interface{}{
+		"vendor": map[string]interface{}{
+			"type": "string",
+		},
+		"document_type": map[string]interface{}{
+			"type": "string",
+			"enum": database.DocumentTypes,
+		},
+		"amount": map[string]interface{}{
+			"type": []string{"number", "null"},
+		},
+		"currency": map[string]interface{}{
+			"type":        "string",
+			"description": "ISO 4217 currency code",
+		},
+		"due_date": map[string]interface{}{
+			"type":        "string",
+			"description": "YYYY-MM-DD or empty",
+		},
+	},
+	"required":             []string{"vendor", "document_type", "amount", "currency", "due_date"},
+	"additionalProperties": false,
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeTransaction validates an extracted transaction and tidies its fields.
+// A transaction without a vendor is dropped rather than rejected.
+func normalizeTransaction(t *Transaction) (*Transaction, error) {
+	if t == nil {
+		return nil, nil
+	}
+	t.Vendor = strings.TrimSpace(t.Vendor)
+	if t.Vendor == "" {
+		return nil, nil
+	}
+	if !database.IsValidDocumentType(t.DocumentType) {
+		return nil, &ValidationError{Reason: fmt.Sprintf("unknown document_type %q", t.DocumentType)}
+	}
+	t.Currency = strings.ToUpper(strings.TrimSpace(t.Currency))
+	if t.Amount != nil {
+		if *t.Amount < 0 {
+			amount := -*t.Amount
+			t.Amount = &amount
+		}
+		if t.Currency != "" && !currencyCode.MatchString(t.Currency) {
+			return nil, &ValidationError{Reason: fmt.Sprintf("currency %q is not an ISO 4217 code", t.Currency)}
+		}
+	} else {
+		t.Currency = ""
+	}
+	if t.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", t.DueDate); err != nil {
+			return nil, &ValidationError{Reason: fmt.Sprintf("due_date %q is not YYYY-MM-DD", t.DueDate)}
+		}
+	}
+	return t, nil
+}
+
+// ParseDueDate returns the transaction's due date, or nil if it has none
+func (t *Transaction) ParseDueDate() *time.Time {
+	if t == nil || t.DueDate == "" {
+		return nil
+	}
+	due, err := time.Parse("2006-01-02", t.DueDate)
+	if err != nil {
+		return nil
+	}
+	return &due
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// triageSchema combines the Stage 1 and Stage 2 outputs into a single object
+var triageSchema = mergeSchemas("email_triage", "Email classification and automation actions in one step", analysisSchema, actionsSchema)
+
+// TriageEmail runs Stage 1 and Stage 2 as one merged call ("economy" pipeline mode).
+// The user's analyze and actions prompts are combined. Like Stage 2, the call is retried on the
+// escalation model when confidence is low or the response is invalid.
+func (c *Client) TriageEmail(ctx context.Context, from, subject, body string, labelNames []string, formattedLabels string, senderContext string, memoryContext string, analyzePrompt string, actionsPrompt string) (*EmailAnalysis, *EmailActions, error) {
+	if analyzePrompt == "" {
+		analyzePrompt = defaultAnalyzePrompt
+	}
+	analyzePrompt += transactionRule + eventRule + taskRule + shipmentRule
+	if actionsPrompt == "" {
+		actionsPrompt = fmt.Sprintf(defaultActionsPrompt, formattedLabels)
+	} else {
+		actionsPrompt += "\n\nAvailable labels:\n" + formattedLabels
+	}
+
+	systemPrompt := fmt.Sprintf(`You triage an email in a single step: first classify it, then decide what to do with it.
+Respond with one JSON object containing the fields from both parts.
+
+## Part 1: Classification
+
+%s
+
+## Part 2: Actions
+
+%s%s`, analyzePrompt, actionsPrompt, untrustedContentRule)
+
+	userPrompt := fmt.Sprintf(`%s
+
+%s%s%sClassify this email (slug, keywords, summary, transaction, event, tasks, shipment) and decide what actions should be taken.`, wrapUntrusted(from, subject, body), todayContext(), senderContext, memoryContext)
+
+	c.logPrompts("TriageEmail", systemPrompt, userPrompt)
+
+	req := llm.Request{
+		Name:         "TriageEmail",
+		Task:         llm.TaskTriage,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		MaxTokens:    10000,
+		Validate: func(content string) error {
+			_, _, err := parseTriage(content, labelNames)
+			return err
+		},
+	}
+	analysis, actions, err := c.triage(ctx, req, labelNames)
+
+	confidence := noConfidence
+	if err == nil {
+		confidence = actions.Confidence
+	}
+	if reason := c.escalationReason(err, confidence); reason != "" {
+		log.Printf("Escalating TriageEmail to %s: %s", c.escalationModel, reason)
+		req.Model = c.escalationModel
+		escalatedAnalysis, escalated, escErr := c.triage(ctx, req, labelNames)
+		if escErr == nil {
+			escalated.Escalated = true
+			return escalatedAnalysis, escalated, nil
+		}
+		log.Printf("Escalated TriageEmail failed, keeping original decision: %v", escErr)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return analysis, actions, nil
+}
+
+// triage runs one merged call and validates the result
+func (c *Client) triage(ctx context.Context, req llm.Request, labelNames []string) (*EmailAnalysis, *EmailActions, error) {
+	response, err := c.completeJSON(ctx, req, triageSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	analysis, actions, err := parseTriage(response.Content, labelNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions.Model = response.Model
+	return analysis, actions, nil
+}
+
+// parseTriage decodes and validates both halves of a merged response
+func parseTriage(content string, labelNames []string) (*EmailAnalysis, *EmailActions, error) {
+	analysis, err := parseAnalysis(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, err := parseActions(content, labelNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	return analysis, actions, nil
+}
+
+// mergeSchemas combines object schemas into one object with the union of their properties
+func mergeSchemas(name, description string, schemas ...llm.Schema) llm.Schema {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, schema := range schemas {
+		if props, ok := schema.Definition["properties"].(map[string]interface{}); ok {
+			for key, prop := range props {
+				properties[key] = prop
+			}
+		}
+		if req, ok := schema.Definition["required"].([]string); ok {
+			required = append(required, req...)
+		}
+	}
+	return llm.Schema{
+		Name:        name,
+		Description: description,
+		Definition: map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+	}
+}
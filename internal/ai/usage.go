@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"sync"
+
+	"github.com/den/gmail-triage-assistant/internal/llm"
+)
+
+// Usage accumulates token counts and estimated cost across the AI calls made with a context
+type Usage struct {
+	mu               sync.Mutex
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64 // Priced by the Meter, including retries, fallbacks and embeddings
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context that records token usage of every AI call made with it
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// Totals returns the accumulated prompt and completion tokens
+func (u *Usage) Totals() (promptTokens, completionTokens int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.PromptTokens, u.CompletionTokens
+}
+
+// Cost returns the accumulated estimated cost in USD
+func (u *Usage) Cost() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.CostUSD
+}
+
+// recordCost adds a metered call's estimated cost to the context's accumulator, if any
+func recordCost(ctx context.Context, cost float64) {
+	u, ok := ctx.Value(usageKey{}).(*Usage)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.CostUSD += cost
+}
+
+// recordUsage adds a completion's token usage to the context's accumulator, if any
+func recordUsage(ctx context.Context, usage llm.Usage) {
+	u, ok := ctx.Value(usageKey{}).(*Usage)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.PromptTokens += usage.PromptTokens
+	u.CompletionTokens += usage.CompletionTokens
+}
@@ -0,0 +1,242 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// Limits on the sources gathered for one question
+const (
+	maxEmailSources   = 20
+	maxSemanticHits   = 10
+	minSemanticScore  = 0.3 // Questions are phrased differently from subjects, so the bar is lower than for few-shot examples
+	maxProfileSources = 3
+	maxMemorySources  = 3
+)
+
+type Service struct {
+	db       *database.DB
+	ai       *ai.Client
+	embedder llm.Embedder // nil = full-text search only
+}
+
+func NewService(db *database.DB, provider llm.LLM, embedder llm.Embedder) *Service {
+	return &Service{
+		db:       db,
+		ai:       ai.NewClient(provider),
+		embedder: embedder,
+	}
+}
+
+// Citation identifies an email an answer is based on
+type Citation struct {
+	EmailID string    `json:"email_id"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"`
+}
+
+// Answer is the response to a question about the mail archive
+type Answer struct {
+	Question  string         `json:"question"`
+	Answer    string         `json:"answer"`
+	Citations []Citation     `json:"citations"`
+	Plan      *ai.SearchPlan `json:"plan"`    // How the question was searched
+	Sources   int            `json:"sources"` // Emails considered
+}
+
+// Ask answers a question from the user's processed emails, sender profiles and memories.
+// Emails are found by full-text search (plus embedding search when available) within the
+// sender and date range the AI reads from the question.
+func (s *Service) Ask(ctx context.Context, userID int64, question string) (*Answer, error) {
+	ctx = ai.WithUser(ctx, userID)
+
+	plan, err := s.ai.PlanArchiveSearch(ctx, question, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan search: %w", err)
+	}
+
+	search := database.EmailSearch{Text: plan.Terms, MatchAny: true, Sender: plan.Sender, Limit: maxEmailSources}
+	if plan.After != "" {
+		after, _ := time.ParseInLocation("2006-01-02", plan.After, time.Local)
+		search.After = &after
+	}
+	if plan.Before != "" {
+		before, _ := time.ParseInLocation("2006-01-02", plan.Before, time.Local)
+		search.Before = &before
+	}
+
+	emails, err := s.db.SearchEmails(ctx, userID, search)
+	if err != nil {
+		return nil, err
+	}
+	emails = s.addSemanticMatches(ctx, userID, question, search, emails)
+
+	// No keyword matches: fall back to everything from the sender and period, if the question named one
+	if len(emails) == 0 && search.Text != "" && (search.Sender != "" || search.After != nil || search.Before != nil) {
+		search.Text = ""
+		emails, err = s.db.SearchEmails(ctx, userID, search)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var profiles []*database.SenderProfile
+	if plan.Sender != "" {
+		profiles, _, err = s.db.GetAllSenderProfiles(ctx, userID, "", plan.Sender, maxProfileSources, 0)
+		if err != nil {
+			log.Printf("Ask: failed to load sender profiles: %v", err)
+		}
+	}
+	memories, err := s.db.SearchMemories(ctx, userID, strings.TrimSpace(plan.Terms+" "+plan.Sender), maxMemorySources)
+	if err != nil {
+		log.Printf("Ask: failed to search memories: %v", err)
+	}
+
+	answer := &Answer{Question: question, Plan: plan, Sources: len(emails), Citations: []Citation{}}
+	if len(emails) == 0 && len(profiles) == 0 && len(memories) == 0 {
+		answer.Answer = "I couldn't find any processed emails related to that question."
+		return answer, nil
+	}
+
+	sourceIDs := make([]string, len(emails))
+	byID := make(map[string]*database.Email, len(emails))
+	for i, email := range emails {
+		sourceIDs[i] = email.ID
+		byID[email.ID] = email
+	}
+
+	sources, redactions := s.redactSources(ctx, userID, emails)
+	result, err := s.ai.AnswerArchiveQuestion(ctx, question, formatSources(sources, profiles, memories), sourceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer question: %w", err)
+	}
+
+	answer.Answer = redactions.Restore(result.Answer)
+	for _, id := range result.Citations {
+		email := byID[id]
+		answer.Citations = append(answer.Citations, Citation{
+			EmailID: email.ID,
+			From:    email.FromAddress,
+			Subject: email.Subject,
+			Date:    email.ProcessedAt,
+		})
+	}
+	return answer, nil
+}
+
+// addSemanticMatches appends emails similar in meaning to the question that full-text search missed,
+// applying the same sender and date filters (non-critical: failures keep the full-text results)
+func (s *Service) addSemanticMatches(ctx context.Context, userID int64, question string, search database.EmailSearch, emails []*database.Email) []*database.Email {
+	if s.embedder == nil {
+		return emails
+	}
+
+	response, err := s.embedder.Embed(ctx, llm.EmbedRequest{Name: "EmbedQuestion", Inputs: []string{question}})
+	if err != nil {
+		log.Printf("Ask: failed to embed question: %v", err)
+		return emails
+	}
+	similar, err := s.db.FindSimilarEmails(ctx, userID, response.Model, response.Vectors[0], "", maxSemanticHits)
+	if err != nil {
+		log.Printf("Ask: semantic search failed: %v", err)
+		return emails
+	}
+
+	seen := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		seen[email.ID] = true
+	}
+	var ids []string
+	for _, hit := range similar {
+		if hit.Similarity >= minSemanticScore && !seen[hit.EmailID] {
+			ids = append(ids, hit.EmailID)
+		}
+	}
+
+	extra, err := s.db.GetEmailsByIDs(ctx, userID, ids)
+	if err != nil {
+		log.Printf("Ask: failed to load semantic matches: %v", err)
+		return emails
+	}
+	for _, email := range extra {
+		if search.Sender != "" && !strings.Contains(strings.ToLower(email.FromAddress), strings.ToLower(search.Sender)) {
+			continue
+		}
+		if (search.After != nil && email.ProcessedAt.Before(*search.After)) || (search.Before != nil && !email.ProcessedAt.Before(*search.Before)) {
+			continue
+		}
+		emails = append(emails, email)
+	}
+	return emails
+}
+
+// redactSources returns copies of the emails with their senders and subjects redacted as the
+// pipeline redacts emails, and the placeholders used so they can be restored in the answer
+func (s *Service) redactSources(ctx context.Context, userID int64, emails []*database.Email) ([]*database.Email, *redact.Result) {
+	result := redact.NewResult()
+	settings, err := s.db.GetRedactionSettings(ctx, userID)
+	if err != nil {
+		// Fail closed: redact with the built-in detectors
+		log.Printf("Ask: failed to load redaction settings, using built-in detectors: %v", err)
+		settings = &database.RedactionSettings{Enabled: true}
+	}
+	if !settings.Enabled {
+		return emails, result
+	}
+	redactor, err := settings.Redactor()
+	if err != nil {
+		log.Printf("Ask: invalid custom redaction pattern, using built-in detectors: %v", err)
+	}
+
+	redacted := make([]*database.Email, len(emails))
+	for i, email := range emails {
+		source := *email
+		if !settings.Allows(email.FromAddress) {
+			source.Subject = redactor.Redact(email.Subject, result)
+			source.FromAddress = redactor.Redact(email.FromAddress, result)
+		}
+		redacted[i] = &source
+	}
+	return redacted, result
+}
+
+// formatSources formats the retrieved emails, profiles and memories for the answer prompt
+func formatSources(emails []*database.Email, profiles []*database.SenderProfile, memories []*database.Memory) string {
+	var b strings.Builder
+	if len(emails) > 0 {
+		b.WriteString("## Emails\n\n")
+		for _, email := range emails {
+			fmt.Fprintf(&b, "[%s] %s | From: %s | Subject: %s\n", email.ID, email.ProcessedAt.Format("2006-01-02"), email.FromAddress, email.Subject)
+			fmt.Fprintf(&b, "Summary: %s\n", email.Summary)
+			if len(email.Keywords) > 0 {
+				fmt.Fprintf(&b, "Keywords: %s\n", strings.Join(email.Keywords, ", "))
+			}
+			if len(email.LabelsApplied) > 0 {
+				fmt.Fprintf(&b, "Labels: %s\n", strings.Join(email.LabelsApplied, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(profiles) > 0 {
+		b.WriteString("## Sender Profiles\n\n")
+		for _, profile := range profiles {
+			fmt.Fprintf(&b, "%s (%s):\n%s\n", profile.Identifier, profile.ProfileType, profile.FormatForPrompt())
+		}
+	}
+	if len(memories) > 0 {
+		b.WriteString("## Memories\n\n")
+		for _, mem := range memories {
+			fmt.Fprintf(&b, "%s memory from %s:\n%s\n\n", mem.Type, mem.StartDate.Format("2006-01-02"), mem.Content)
+		}
+	}
+	return b.String()
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // DefaultSessionSecret is the insecure default used in development.
@@ -24,16 +25,91 @@ type Config struct {
 	GoogleClientSecret string
 	GoogleRedirectURL  string
 
+	// LLM provider: "openai" (also any OpenAI-compatible server via OPENAI_BASE_URL), "anthropic" or "fake"
+	LLMProvider string
+
 	// OpenAI settings
 	OpenAIAPIKey   string
 	OpenAIModel    string // Default: "gpt-4o-nano" or latest v5 nano model
 	OpenAIBaseURL  string
 
+	// Anthropic settings
+	AnthropicAPIKey  string
+	AnthropicModel   string
+	AnthropicBaseURL string
+
+	// Per-task model overrides (empty = provider default model)
+	ModelAnalyze string
+	ModelActions string
+	ModelTriage  string
+	ModelDraft   string
+	ModelProfile string
+	ModelMemory  string
+	ModelWrapup  string
+	ModelWizard  string
+	ModelAsk     string
+	ModelGuard   string
+	ModelBucket  string            // Bucket classifier (buckets mode)
+	ModelBuckets map[string]string // Bucket processors by bucket name (LLM_MODEL_BUCKET_<NAME>)
+
+	// Stage 2 escalation: retry on a stronger model below this confidence or on invalid output
+	EscalationModel     string
+	EscalationThreshold float64
+
+	// Call resilience: per-attempt deadline, retries of transient errors, and a fallback provider/model
+	LLMTimeoutSeconds   int
+	LLMMaxRetries       int
+	LLMRetryBackoffMs   int
+	LLMFallbackProvider string // Empty = same provider as LLM_PROVIDER
+	LLMFallbackModel    string // Empty = fallback provider's default model
+
+	// Cost accounting: price table overrides ("model=input:output,..." in USD per million tokens)
+	LLMPrices string
+
+	// Default per-user AI budgets in USD (0 = unlimited); users can override them in settings
+	BudgetDailyUSD    float64
+	BudgetMonthlyUSD  float64
+	BudgetSoftPercent int // Percent of a budget after which optional AI stages are skipped
+
+	// Token budget for the variable prompt context: body, profiles, labels and memories (0 = unlimited)
+	ContextTokenBudget int
+
+	// Similar-email retrieval: embeddings of past emails provide few-shot examples for Stage 2
+	EmbeddingProvider string // Empty = LLM_PROVIDER (local hashing embedder for anthropic)
+	EmbeddingModel    string
+	FewShotExamples   int // Similar past decisions per email (0, the default, disables embeddings)
+
+	// Prompt-injection defense: optional AI classifier for emails the heuristics flag
+	InjectionClassifier bool
+
+	// Decision cache: hours to reuse a triage decision for duplicate/templated emails (0 disables)
+	DecisionCacheTTLHours int
+
+	// Fast paths: skip the AI for replies in an already-triaged thread and for senders whose
+	// history is consistent enough (share of FastPathMinEmails+ emails agreeing on the decision)
+	FastPathThreads     bool
+	FastPathSenders     bool
+	FastPathMinEmails   int
+	FastPathConsistency float64
+
+	// Label applied to a shipment's emails once it is delivered, e.g. a timed delete label ("" disables)
+	ShipmentDeliveredLabel string
+
+	// Security lane: one-time codes, password resets, new-login alerts and account recovery
+	SecurityLabel         string // Applied to security emails that are not flagged ("" disables)
+	SecurityCheckInterval int    // Seconds between security-lane checks (0 disables the lane)
+
+	// Warning label for likely phishing or spoofed emails ("" disables)
+	PhishingLabel string
+
 	// Gmail settings
 	GmailCheckInterval int // Minutes between email checks
 
 	// Session settings
 	SessionSecret string
+
+	// Explainability settings
+	ExplanationRetentionDays int // Days to keep per-email prompt snapshots (0 disables capture)
 }
 
 // Load reads configuration from environment variables
@@ -45,11 +121,71 @@ func Load() (*Config, error) {
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/callback"),
+		LLMProvider:        getEnv("LLM_PROVIDER", "openai"),
 		OpenAIAPIKey:       getEnv("OPENAI_API_KEY", ""),
 		OpenAIModel:        getEnv("OPENAI_MODEL", "gpt-4o-nano"),
 		OpenAIBaseURL:      getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		AnthropicAPIKey:    getEnv("ANTHROPIC_API_KEY", ""),
+		AnthropicModel:     getEnv("ANTHROPIC_MODEL", "claude-haiku-4-5"),
+		AnthropicBaseURL:   getEnv("ANTHROPIC_BASE_URL", ""),
+
+		ModelAnalyze: getEnv("LLM_MODEL_ANALYZE", ""),
+		ModelActions: getEnv("LLM_MODEL_ACTIONS", ""),
+		ModelTriage:  getEnv("LLM_MODEL_TRIAGE", ""),
+		ModelDraft:   getEnv("LLM_MODEL_DRAFT", ""),
+		ModelProfile: getEnv("LLM_MODEL_PROFILE", ""),
+		ModelMemory:  getEnv("LLM_MODEL_MEMORY", ""),
+		ModelWrapup:  getEnv("LLM_MODEL_WRAPUP", ""),
+		ModelWizard:  getEnv("LLM_MODEL_WIZARD", ""),
+		ModelAsk:     getEnv("LLM_MODEL_ASK", ""),
+		ModelGuard:   getEnv("LLM_MODEL_GUARD", ""),
+		ModelBucket:  getEnv("LLM_MODEL_BUCKET", ""),
+		ModelBuckets: map[string]string{},
+
+		EscalationModel:     getEnv("LLM_ESCALATION_MODEL", ""),
+		EscalationThreshold: getEnvFloat("LLM_ESCALATION_THRESHOLD", 0.6),
+
+		LLMTimeoutSeconds:   getEnvInt("LLM_TIMEOUT_SECONDS", 60),
+		LLMMaxRetries:       getEnvInt("LLM_MAX_RETRIES", 2),
+		LLMRetryBackoffMs:   getEnvInt("LLM_RETRY_BACKOFF_MS", 500),
+		LLMFallbackProvider: getEnv("LLM_FALLBACK_PROVIDER", ""),
+		LLMFallbackModel:    getEnv("LLM_FALLBACK_MODEL", ""),
+
+		LLMPrices:         getEnv("LLM_PRICES", ""),
+		BudgetDailyUSD:    getEnvFloat("BUDGET_DAILY_USD", 0),
+		BudgetMonthlyUSD:  getEnvFloat("BUDGET_MONTHLY_USD", 0),
+		BudgetSoftPercent: getEnvInt("BUDGET_SOFT_PERCENT", 80),
+
+		ContextTokenBudget: getEnvInt("LLM_CONTEXT_TOKENS", 4000),
+
+		EmbeddingProvider: getEnv("EMBEDDING_PROVIDER", ""),
+		EmbeddingModel:    getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		FewShotExamples:   getEnvInt("FEW_SHOT_EXAMPLES", 0),
+
+		InjectionClassifier: getEnvBool("INJECTION_CLASSIFIER", false),
+
+		DecisionCacheTTLHours: getEnvInt("DECISION_CACHE_TTL_HOURS", 0),
+
+		FastPathThreads:     getEnvBool("FAST_PATH_THREADS", false),
+		FastPathSenders:     getEnvBool("FAST_PATH_SENDERS", false),
+		FastPathMinEmails:   getEnvInt("FAST_PATH_MIN_EMAILS", 10),
+		FastPathConsistency: getEnvFloat("FAST_PATH_CONSISTENCY", 0.95),
+
+		ShipmentDeliveredLabel: getEnv("SHIPMENT_DELIVERED_LABEL", "🗑️/1w"),
+
+		SecurityLabel:         getEnv("SECURITY_LABEL", "🗑️/1d"),
+		SecurityCheckInterval: getEnvInt("SECURITY_CHECK_INTERVAL", 60),
+
+		PhishingLabel: getEnv("PHISHING_LABEL", "⚠️ Possible phishing"),
+
 		GmailCheckInterval: getEnvInt("GMAIL_CHECK_INTERVAL", 5),
 		SessionSecret:      getEnv("SESSION_SECRET", DefaultSessionSecret),
+
+		ExplanationRetentionDays: getEnvInt("EXPLANATION_RETENTION_DAYS", 30),
+	}
+
+	for _, bucket := range []string{"newsletter", "notification", "human", "transactional", "security", "calendar"} {
+		cfg.ModelBuckets[bucket] = getEnv("LLM_MODEL_BUCKET_"+strings.ToUpper(bucket), "")
 	}
 
 	if cfg.SessionSecret == DefaultSessionSecret {
@@ -81,6 +217,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -89,3 +234,12 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -37,6 +37,35 @@ func (db *DB) GetLatestAIPrompt(ctx context.Context, userID int64, promptType AI
 	return &prompt, nil
 }
 
+// GetAIPromptByID retrieves a specific AI prompt version owned by the user.
+// Returns nil, nil if it does not exist.
+func (db *DB) GetAIPromptByID(ctx context.Context, userID, promptID int64) (*AIPrompt, error) {
+	query := `
+		SELECT id, user_id, type, content, version, created_at
+		FROM ai_prompts
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var prompt AIPrompt
+	err := db.conn.QueryRowContext(ctx, query, promptID, userID).Scan(
+		&prompt.ID,
+		&prompt.UserID,
+		&prompt.Type,
+		&prompt.Content,
+		&prompt.Version,
+		&prompt.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get AI prompt: %w", err)
+	}
+
+	return &prompt, nil
+}
+
 // CreateAIPrompt inserts a new AI prompt version. It auto-increments the version
 // based on the current max version for this user+type.
 func (db *DB) CreateAIPrompt(ctx context.Context, prompt *AIPrompt) error {
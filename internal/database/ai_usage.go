@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AIUsage is one metered AI call
+type AIUsage struct {
+	ID               int64     `db:"id" json:"id"`
+	UserID           int64     `db:"user_id" json:"user_id"`
+	Task             string    `db:"task" json:"task"`   // analyze, actions, draft, profile, memory, wrapup, wizard
+	Model            string    `db:"model" json:"model"` // Model that served the call
+	PromptTokens     int       `db:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int       `db:"completion_tokens" json:"completion_tokens"`
+	CostUSD          float64   `db:"cost_usd" json:"cost_usd"` // Estimated from the configured price table
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+}
+
+// UsageBreakdown aggregates usage for one task/model combination
+type UsageBreakdown struct {
+	Task             string  `json:"task"`
+	Model            string  `json:"model"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// DayUsage aggregates usage for one day
+type DayUsage struct {
+	Date             string  `json:"date"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// RecordAIUsage appends a row to the usage ledger
+func (db *DB) RecordAIUsage(ctx context.Context, usage *AIUsage) error {
+	query := `
+		INSERT INTO ai_usage (user_id, task, model, prompt_tokens, completion_tokens, cost_usd, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		usage.UserID, usage.Task, usage.Model, usage.PromptTokens, usage.CompletionTokens, usage.CostUSD, usage.CreatedAt,
+	).Scan(&usage.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record ai usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetAISpendSince returns the user's estimated AI spend in USD since the given time
+func (db *DB) GetAISpendSince(ctx context.Context, userID int64, since time.Time) (float64, error) {
+	var spend float64
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(cost_usd), 0)
+		FROM ai_usage
+		WHERE user_id = $1 AND created_at >= $2
+	`, userID, since).Scan(&spend)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ai spend: %w", err)
+	}
+	return spend, nil
+}
+
+// GetUsageBreakdown aggregates the user's AI usage since the given time by task and model
+func (db *DB) GetUsageBreakdown(ctx context.Context, userID int64, since time.Time) ([]UsageBreakdown, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT task, model, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM ai_usage
+		WHERE user_id = $1 AND created_at >= $2
+		GROUP BY task, model
+		ORDER BY SUM(cost_usd) DESC, task, model
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("usage breakdown query failed: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := []UsageBreakdown{}
+	for rows.Next() {
+		var b UsageBreakdown
+		if err := rows.Scan(&b.Task, &b.Model, &b.Calls, &b.PromptTokens, &b.CompletionTokens, &b.CostUSD); err != nil {
+			return nil, fmt.Errorf("usage breakdown scan failed: %w", err)
+		}
+		breakdown = append(breakdown, b)
+	}
+
+	return breakdown, rows.Err()
+}
+
+// GetDailyUsage aggregates the user's AI usage per day since the given time
+func (db *DB) GetDailyUsage(ctx context.Context, userID int64, since time.Time) ([]DayUsage, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT DATE(created_at) as day, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM ai_usage
+		WHERE user_id = $1 AND created_at >= $2
+		GROUP BY day ORDER BY day
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("daily usage query failed: %w", err)
+	}
+	defer rows.Close()
+
+	days := []DayUsage{}
+	for rows.Next() {
+		var d DayUsage
+		var t time.Time
+		if err := rows.Scan(&t, &d.Calls, &d.PromptTokens, &d.CompletionTokens, &d.CostUSD); err != nil {
+			return nil, fmt.Errorf("daily usage scan failed: %w", err)
+		}
+		d.Date = t.Format("2006-01-02")
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// UpdateUserBudgets sets a user's daily and monthly AI budgets in USD (0 = server default)
+func (db *DB) UpdateUserBudgets(ctx context.Context, userID int64, daily, monthly float64) error {
+	query := `
+		UPDATE users
+		SET daily_budget_usd = $1, monthly_budget_usd = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, daily, monthly, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update budgets: %w", err)
+	}
+
+	return nil
+}
@@ -3,12 +3,17 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"sync"
 
 	_ "github.com/lib/pq"
 )
 
 type DB struct {
 	conn *sql.DB
+
+	vectorMu      sync.Mutex
+	vectorChecked bool // pgvector detection succeeded; failed checks are retried
+	hasVector     bool // pgvector column available for similarity search
 }
 
 // New creates a new database connection
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CachedDecision is a stored AI triage decision reused for duplicate emails
+type CachedDecision struct {
+	UserID    int64           `db:"user_id" json:"user_id"`
+	CacheKey  string          `db:"cache_key" json:"cache_key"`
+	Analysis  json.RawMessage `db:"analysis" json:"analysis"` // Stage 1 output
+	Actions   json.RawMessage `db:"actions" json:"actions"`   // Stage 2 output
+	Model     string          `db:"model" json:"model"`
+	Hits      int             `db:"hits" json:"hits"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	ExpiresAt time.Time       `db:"expires_at" json:"expires_at"`
+}
+
+// CacheStats reports how many emails were served from the decision cache
+type CacheStats struct {
+	Emails  int     `json:"emails"`
+	Cached  int     `json:"cached"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// GetCachedDecision returns an unexpired cached decision and counts the hit, or nil if none
+func (db *DB) GetCachedDecision(ctx context.Context, userID int64, cacheKey string) (*CachedDecision, error) {
+	d := &CachedDecision{}
+	err := db.conn.QueryRowContext(ctx, `
+		UPDATE decision_cache
+		SET hits = hits + 1
+		WHERE user_id = $1 AND cache_key = $2 AND expires_at > NOW()
+		RETURNING user_id, cache_key, analysis, actions, model, hits, created_at, expires_at
+	`, userID, cacheKey).Scan(&d.UserID, &d.CacheKey, &d.Analysis, &d.Actions, &d.Model, &d.Hits, &d.CreatedAt, &d.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached decision: %w", err)
+	}
+	return d, nil
+}
+
+// PutCachedDecision stores a decision, replacing any previous entry for the key
+func (db *DB) PutCachedDecision(ctx context.Context, d *CachedDecision) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO decision_cache (user_id, cache_key, analysis, actions, model, hits, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, $7)
+		ON CONFLICT (user_id, cache_key) DO UPDATE
+		SET analysis = EXCLUDED.analysis, actions = EXCLUDED.actions, model = EXCLUDED.model,
+		    hits = 0, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+	`, d.UserID, d.CacheKey, []byte(d.Analysis), []byte(d.Actions), d.Model, d.CreatedAt, d.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to store cached decision: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredCachedDecisions removes cache entries past their TTL
+func (db *DB) DeleteExpiredCachedDecisions(ctx context.Context) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM decision_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired cached decisions: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// GetCacheStats returns the decision cache hit rate for emails processed since the given time
+func (db *DB) GetCacheStats(ctx context.Context, userID int64, since time.Time) (*CacheStats, error) {
+	stats := &CacheStats{}
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE cached)
+		FROM emails
+		WHERE user_id = $1 AND processed_at >= $2
+	`, userID, since).Scan(&stats.Emails, &stats.Cached)
+	if err != nil {
+		return nil, fmt.Errorf("cache stats query failed: %w", err)
+	}
+	if stats.Emails > 0 {
+		stats.HitRate = float64(stats.Cached) / float64(stats.Emails)
+	}
+	return stats, nil
+}
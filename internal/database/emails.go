@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 )
@@ -29,9 +30,31 @@ func (db *DB) CreateEmail(ctx context.Context, email *Email) error {
 		return fmt.Errorf("failed to marshal labels: %w", err)
 	}
 
+	if email.InjectionSignals == nil {
+		email.InjectionSignals = []string{}
+	}
+	signalsJSON, err := json.Marshal(email.InjectionSignals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal injection signals: %w", err)
+	}
+
+	if email.PhishingSignals == nil {
+		email.PhishingSignals = []string{}
+	}
+	phishingJSON, err := json.Marshal(email.PhishingSignals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal phishing signals: %w", err)
+	}
+
 	query := `
-		INSERT INTO emails (id, user_id, from_address, from_domain, subject, slug, keywords, summary, labels_applied, bypassed_inbox, reasoning, notification_sent, draft_created, processed_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		INSERT INTO emails (id, user_id, from_address, from_domain, subject, slug, keywords, summary, labels_applied, bypassed_inbox, reasoning, notification_sent, draft_created, processed_at, created_at,
+		                    experiment_id, experiment_variant, prompt_tokens, completion_tokens, decision_model, confidence, escalated, pipeline_mode, cached,
+		                    injection_score, injection_signals, held_notification, redaction_count,
+		                    bucket, triage_reasoning, severity, urgency, interesting_score, thread_id, triage_via,
+		                    vendor, document_type, amount, currency, due_date,
+		                    phishing_score, phishing_signals, subscription_id, cost_usd)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28,
+		        $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42, $43, $44)
 		ON CONFLICT (id) DO NOTHING
 	`
 
@@ -53,6 +76,35 @@ func (db *DB) CreateEmail(ctx context.Context, email *Email) error {
 		email.DraftCreated,
 		email.ProcessedAt,
 		email.CreatedAt,
+		email.ExperimentID,
+		email.ExperimentVariant,
+		email.PromptTokens,
+		email.CompletionTokens,
+		email.DecisionModel,
+		email.Confidence,
+		email.Escalated,
+		email.PipelineMode,
+		email.Cached,
+		email.InjectionScore,
+		signalsJSON,
+		email.HeldNotification,
+		email.RedactionCount,
+		email.Bucket,
+		email.TriageReasoning,
+		email.Severity,
+		email.Urgency,
+		email.InterestingScore,
+		email.ThreadID,
+		email.TriageVia,
+		email.Vendor,
+		email.DocumentType,
+		email.Amount,
+		email.Currency,
+		email.DueDate,
+		email.PhishingScore,
+		phishingJSON,
+		email.SubscriptionID,
+		email.CostUSD,
 	)
 
 	if err != nil {
@@ -124,18 +176,22 @@ func (db *DB) GetUserLabelsWithDetails(ctx context.Context, userID int64) ([]*La
 	return labels, rows.Err()
 }
 
-// GetRecentEmails retrieves recent processed emails for a user
-func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offset int) ([]*Email, error) {
+// GetRecentEmails retrieves recent processed emails for a user, optionally only those in one bucket
+// or decided one way (triage_via)
+func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offset int, bucket string, triageVia string) ([]*Email, error) {
 	query := `
 		SELECT id, user_id, from_address, from_domain, subject, slug, keywords, summary,
-		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), COALESCE(feedback_dirty, FALSE), notification_sent, COALESCE(draft_created, FALSE), processed_at, created_at
+		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), COALESCE(feedback_dirty, FALSE), notification_sent, COALESCE(draft_created, FALSE), processed_at, created_at,
+		       injection_score, injection_signals, held_notification, redaction_count,
+		       bucket, triage_reasoning, severity, urgency, interesting_score, thread_id, triage_via,
+		       vendor, document_type, amount, currency, due_date, phishing_score, phishing_signals
 		FROM emails
-		WHERE user_id = $1
+		WHERE user_id = $1 AND ($4 = '' OR bucket = $4) AND ($5 = '' OR triage_via = $5)
 		ORDER BY processed_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := db.conn.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := db.conn.QueryContext(ctx, query, userID, limit, offset, bucket, triageVia)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query recent emails: %w", err)
 	}
@@ -144,7 +200,7 @@ func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offs
 	emails := make([]*Email, 0)
 	for rows.Next() {
 		var email Email
-		var keywordsJSON, labelsJSON []byte
+		var keywordsJSON, labelsJSON, signalsJSON, phishingJSON []byte
 
 		err := rows.Scan(
 			&email.ID,
@@ -164,6 +220,24 @@ func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offs
 			&email.DraftCreated,
 			&email.ProcessedAt,
 			&email.CreatedAt,
+			&email.InjectionScore,
+			&signalsJSON,
+			&email.HeldNotification,
+			&email.RedactionCount,
+			&email.Bucket,
+			&email.TriageReasoning,
+			&email.Severity,
+			&email.Urgency,
+			&email.InterestingScore,
+			&email.ThreadID,
+			&email.TriageVia,
+			&email.Vendor,
+			&email.DocumentType,
+			&email.Amount,
+			&email.Currency,
+			&email.DueDate,
+			&email.PhishingScore,
+			&phishingJSON,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan email: %w", err)
@@ -176,6 +250,12 @@ func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offs
 		if err := json.Unmarshal(labelsJSON, &email.LabelsApplied); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
 		}
+		if err := json.Unmarshal(signalsJSON, &email.InjectionSignals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal injection signals: %w", err)
+		}
+		if err := json.Unmarshal(phishingJSON, &email.PhishingSignals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal phishing signals: %w", err)
+		}
 
 		emails = append(emails, &email)
 	}
@@ -187,6 +267,142 @@ func (db *DB) GetRecentEmails(ctx context.Context, userID int64, limit int, offs
 	return emails, nil
 }
 
+// GetEmailByID retrieves a single processed email, or nil if not found
+func (db *DB) GetEmailByID(ctx context.Context, userID int64, emailID string) (*Email, error) {
+	query := `
+		SELECT id, user_id, from_address, from_domain, subject, slug, keywords, summary,
+		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), COALESCE(feedback_dirty, FALSE), notification_sent, COALESCE(draft_created, FALSE), processed_at, created_at,
+		       experiment_id, experiment_variant, prompt_tokens, completion_tokens, user_unarchived,
+		       decision_model, confidence, escalated, pipeline_mode, cached,
+		       injection_score, injection_signals, held_notification, redaction_count,
+		       bucket, triage_reasoning, severity, urgency, interesting_score, thread_id, triage_via,
+		       vendor, document_type, amount, currency, due_date, phishing_score, phishing_signals, subscription_id, cost_usd
+		FROM emails
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var email Email
+	var keywordsJSON, labelsJSON, signalsJSON, phishingJSON []byte
+	err := db.conn.QueryRowContext(ctx, query, emailID, userID).Scan(
+		&email.ID,
+		&email.UserID,
+		&email.FromAddress,
+		&email.FromDomain,
+		&email.Subject,
+		&email.Slug,
+		&keywordsJSON,
+		&email.Summary,
+		&labelsJSON,
+		&email.BypassedInbox,
+		&email.Reasoning,
+		&email.HumanFeedback,
+		&email.FeedbackDirty,
+		&email.NotificationSent,
+		&email.DraftCreated,
+		&email.ProcessedAt,
+		&email.CreatedAt,
+		&email.ExperimentID,
+		&email.ExperimentVariant,
+		&email.PromptTokens,
+		&email.CompletionTokens,
+		&email.UserUnarchived,
+		&email.DecisionModel,
+		&email.Confidence,
+		&email.Escalated,
+		&email.PipelineMode,
+		&email.Cached,
+		&email.InjectionScore,
+		&signalsJSON,
+		&email.HeldNotification,
+		&email.RedactionCount,
+		&email.Bucket,
+		&email.TriageReasoning,
+		&email.Severity,
+		&email.Urgency,
+		&email.InterestingScore,
+		&email.ThreadID,
+		&email.TriageVia,
+		&email.Vendor,
+		&email.DocumentType,
+		&email.Amount,
+		&email.Currency,
+		&email.DueDate,
+		&email.PhishingScore,
+		&phishingJSON,
+		&email.SubscriptionID,
+		&email.CostUSD,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email: %w", err)
+	}
+
+	if err := json.Unmarshal(keywordsJSON, &email.Keywords); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keywords: %w", err)
+	}
+	if err := json.Unmarshal(labelsJSON, &email.LabelsApplied); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+	}
+	if err := json.Unmarshal(signalsJSON, &email.InjectionSignals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal injection signals: %w", err)
+	}
+	if err := json.Unmarshal(phishingJSON, &email.PhishingSignals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal phishing signals: %w", err)
+	}
+
+	return &email, nil
+}
+
+// GetLatestEmailInThread returns the most recently processed email in a Gmail thread other than
+// excludeID, or nil if there is none. Only the decision fields are loaded.
+func (db *DB) GetLatestEmailInThread(ctx context.Context, userID int64, threadID string, excludeID string) (*Email, error) {
+	query := `
+		SELECT id, from_address, slug, keywords, labels_applied, bypassed_inbox, COALESCE(human_feedback, ''),
+		       notification_sent, COALESCE(draft_created, FALSE), user_unarchived, decision_model, bucket, processed_at
+		FROM emails
+		WHERE user_id = $1 AND thread_id = $2 AND id != $3
+		ORDER BY processed_at DESC
+		LIMIT 1
+	`
+
+	var email Email
+	var keywordsJSON, labelsJSON []byte
+	err := db.conn.QueryRowContext(ctx, query, userID, threadID, excludeID).Scan(
+		&email.ID,
+		&email.FromAddress,
+		&email.Slug,
+		&keywordsJSON,
+		&labelsJSON,
+		&email.BypassedInbox,
+		&email.HumanFeedback,
+		&email.NotificationSent,
+		&email.DraftCreated,
+		&email.UserUnarchived,
+		&email.DecisionModel,
+		&email.Bucket,
+		&email.ProcessedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest email in thread: %w", err)
+	}
+	email.UserID = userID
+	email.ThreadID = threadID
+
+	if err := json.Unmarshal(keywordsJSON, &email.Keywords); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keywords: %w", err)
+	}
+	if err := json.Unmarshal(labelsJSON, &email.LabelsApplied); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+	}
+
+	return &email, nil
+}
+
 // UpdateEmailFeedback updates the human feedback for an email
 func (db *DB) UpdateEmailFeedback(ctx context.Context, userID int64, emailID string, feedback string) error {
 	query := `
@@ -211,3 +427,22 @@ func (db *DB) UpdateEmailFeedback(ctx context.Context, userID int64, emailID str
 
 	return nil
 }
+
+// ReleaseHeldNotification clears an email's held notification and marks it sent.
+// Returns false if the email has no held notification.
+func (db *DB) ReleaseHeldNotification(ctx context.Context, userID int64, emailID string) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE emails
+		SET held_notification = '', notification_sent = TRUE
+		WHERE id = $1 AND user_id = $2 AND held_notification != ''
+	`, emailID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to release held notification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
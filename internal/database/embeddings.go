@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// embeddingScanLimit caps how many recent embeddings are compared in Go when pgvector is unavailable
+const embeddingScanLimit = 5000
+
+// SimilarEmail is a past email and its decision, ranked by similarity to a new email
+type SimilarEmail struct {
+	EmailID        string   `json:"email_id"`
+	FromAddress    string   `json:"from_address"`
+	Subject        string   `json:"subject"`
+	Summary        string   `json:"summary"`
+	LabelsApplied  []string `json:"labels_applied"`
+	BypassedInbox  bool     `json:"bypassed_inbox"`
+	HumanFeedback  string   `json:"human_feedback"`
+	UserUnarchived bool     `json:"user_unarchived"`
+	Similarity     float64  `json:"similarity"` // Cosine similarity, 1 = identical
+}
+
+// Corrected reports whether the user corrected the decision (feedback or un-archiving)
+func (s *SimilarEmail) Corrected() bool {
+	return s.HumanFeedback != "" || s.UserUnarchived
+}
+
+// vectorEnabled reports whether the pgvector column exists. The answer is cached once the check
+// succeeds; if it fails, this call falls back to in-process search and the next call checks again.
+func (db *DB) vectorEnabled(ctx context.Context) bool {
+	db.vectorMu.Lock()
+	defer db.vectorMu.Unlock()
+	if db.vectorChecked {
+		return db.hasVector
+	}
+
+	var hasVector bool
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'email_embeddings' AND column_name = 'vec'
+		)
+	`).Scan(&hasVector)
+	if err != nil {
+		log.Printf("Failed to detect pgvector, using in-process similarity search: %v", err)
+		return false
+	}
+	db.hasVector, db.vectorChecked = hasVector, true
+	return hasVector
+}
+
+// SaveEmailEmbedding stores (or replaces) the embedding of a processed email
+func (db *DB) SaveEmailEmbedding(ctx context.Context, emailID string, userID int64, model string, vector []float32) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO email_embeddings (email_id, user_id, model, embedding, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (email_id) DO UPDATE
+		SET model = EXCLUDED.model, embedding = EXCLUDED.embedding, created_at = EXCLUDED.created_at
+	`, emailID, userID, model, pq.Array(vector))
+	if err != nil {
+		return fmt.Errorf("failed to save email embedding: %w", err)
+	}
+
+	if db.vectorEnabled(ctx) {
+		_, err = db.conn.ExecContext(ctx, `UPDATE email_embeddings SET vec = embedding::vector WHERE email_id = $1`, emailID)
+		if err != nil {
+			return fmt.Errorf("failed to set email embedding vector: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindSimilarEmails returns the user's past emails most similar to the vector, most similar first.
+// Only embeddings from the same model are compared.
+func (db *DB) FindSimilarEmails(ctx context.Context, userID int64, model string, vector []float32, excludeEmailID string, limit int) ([]*SimilarEmail, error) {
+	if db.vectorEnabled(ctx) {
+		return db.findSimilarEmailsVector(ctx, userID, model, vector, excludeEmailID, limit)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT e.id, e.from_address, e.subject, e.summary, e.labels_applied, e.bypassed_inbox,
+		       COALESCE(e.human_feedback, ''), e.user_unarchived, v.embedding
+		FROM email_embeddings v
+		JOIN emails e ON e.id = v.email_id
+		WHERE v.user_id = $1 AND v.model = $2 AND v.email_id != $3
+		ORDER BY v.created_at DESC
+		LIMIT $4
+	`, userID, model, excludeEmailID, embeddingScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("similar emails query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*SimilarEmail
+	for rows.Next() {
+		var candidate []float32
+		s, err := scanSimilarEmail(rows, pq.Array(&candidate))
+		if err != nil {
+			return nil, err
+		}
+		s.Similarity = cosineSimilarity(vector, candidate)
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar emails: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (db *DB) findSimilarEmailsVector(ctx context.Context, userID int64, model string, vector []float32, excludeEmailID string, limit int) ([]*SimilarEmail, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT e.id, e.from_address, e.subject, e.summary, e.labels_applied, e.bypassed_inbox,
+		       COALESCE(e.human_feedback, ''), e.user_unarchived, 1 - (v.vec <=> $4::real[]::vector)
+		FROM email_embeddings v
+		JOIN emails e ON e.id = v.email_id
+		WHERE v.user_id = $1 AND v.model = $2 AND v.email_id != $3 AND v.vec IS NOT NULL
+		ORDER BY v.vec <=> $4::real[]::vector
+		LIMIT $5
+	`, userID, model, excludeEmailID, pq.Array(vector), limit)
+	if err != nil {
+		return nil, fmt.Errorf("similar emails query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*SimilarEmail
+	for rows.Next() {
+		var similarity float64
+		s, err := scanSimilarEmail(rows, &similarity)
+		if err != nil {
+			return nil, err
+		}
+		s.Similarity = similarity
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar emails: %w", err)
+	}
+	return results, nil
+}
+
+// scanSimilarEmail scans the shared columns of a similar email row followed by one extra column
+func scanSimilarEmail(rows interface{ Scan(...any) error }, extra any) (*SimilarEmail, error) {
+	s := &SimilarEmail{}
+	var labelsJSON []byte
+	if err := rows.Scan(&s.EmailID, &s.FromAddress, &s.Subject, &s.Summary, &labelsJSON, &s.BypassedInbox,
+		&s.HumanFeedback, &s.UserUnarchived, extra); err != nil {
+		return nil, fmt.Errorf("failed to scan similar email: %w", err)
+	}
+	if err := json.Unmarshal(labelsJSON, &s.LabelsApplied); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+	}
+	return s, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors (0 if their sizes differ)
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event is a calendar event from an invite or extracted from an email body
+type Event struct {
+	ID           int64      `db:"id" json:"id"`
+	UserID       int64      `db:"user_id" json:"user_id"`
+	EmailID      string     `db:"email_id" json:"email_id"`
+	UID          string     `db:"uid" json:"uid"`
+	Source       string     `db:"source" json:"source"` // EventSourceICS or EventSourceAI
+	Method       string     `db:"method" json:"method"`
+	Status       string     `db:"status" json:"status"` // CONFIRMED, TENTATIVE or CANCELLED
+	Sequence     int        `db:"sequence" json:"sequence"`
+	Summary      string     `db:"summary" json:"summary"`
+	Description  string     `db:"description" json:"description"`
+	Location     string     `db:"location" json:"location"`
+	Organizer    string     `db:"organizer" json:"organizer"`
+	Attendees    []string   `db:"attendees" json:"attendees"`
+	StartsAt     time.Time  `db:"starts_at" json:"starts_at"`
+	EndsAt       *time.Time `db:"ends_at" json:"ends_at"`
+	AllDay       bool       `db:"all_day" json:"all_day"`
+	TimeZone     string     `db:"time_zone" json:"time_zone"`
+	RRule        string     `db:"rrule" json:"rrule"`
+	RecurrenceID string     `db:"recurrence_id" json:"recurrence_id"` // Overridden instance of a recurring event, "" for the series
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Event sources for events.source
+const (
+	EventSourceICS = "ics" // Parsed from a text/calendar part or .ics attachment
+	EventSourceAI  = "ai"  // Extracted from the email body
+)
+
+// Event statuses
+const (
+	EventStatusConfirmed = "CONFIRMED"
+	EventStatusCancelled = "CANCELLED"
+)
+
+// UpsertEvent stores an event, replacing the stored version unless it has a higher sequence.
+// Invite updates and cancellations share the UID (and RECURRENCE-ID, for a single instance) of the
+// original invite. Once an event has an organizer, only invites from the same organizer may update
+// or cancel it, so another sender cannot take over an event by reusing its UID.
+func (db *DB) UpsertEvent(ctx context.Context, event *Event) error {
+	if event.Attendees == nil {
+		event.Attendees = []string{}
+	}
+	attendeesJSON, err := json.Marshal(event.Attendees)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attendees: %w", err)
+	}
+
+	_, err = db.conn.ExecContext(ctx, `
+		INSERT INTO events (user_id, email_id, uid, source, method, status, sequence, summary, description, location,
+		                    organizer, attendees, starts_at, ends_at, all_day, time_zone, rrule, recurrence_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), NOW())
+		ON CONFLICT (user_id, uid, recurrence_id) DO UPDATE SET
+			email_id = EXCLUDED.email_id,
+			source = EXCLUDED.source,
+			method = EXCLUDED.method,
+			status = EXCLUDED.status,
+			sequence = EXCLUDED.sequence,
+			summary = CASE WHEN EXCLUDED.summary != '' THEN EXCLUDED.summary ELSE events.summary END,
+			description = CASE WHEN EXCLUDED.description != '' THEN EXCLUDED.description ELSE events.description END,
+			location = CASE WHEN EXCLUDED.location != '' THEN EXCLUDED.location ELSE events.location END,
+			organizer = CASE WHEN EXCLUDED.organizer != '' THEN EXCLUDED.organizer ELSE events.organizer END,
+			attendees = CASE WHEN EXCLUDED.attendees != '[]' THEN EXCLUDED.attendees ELSE events.attendees END,
+			starts_at = EXCLUDED.starts_at,
+			ends_at = EXCLUDED.ends_at,
+			all_day = EXCLUDED.all_day,
+			time_zone = EXCLUDED.time_zone,
+			rrule = EXCLUDED.rrule,
+			updated_at = NOW()
+		WHERE EXCLUDED.sequence >= events.sequence
+		  AND (events.organizer = '' OR LOWER(EXCLUDED.organizer) = LOWER(events.organizer))
+	`, event.UserID, event.EmailID, event.UID, event.Source, event.Method, event.Status, event.Sequence,
+		event.Summary, event.Description, event.Location, event.Organizer, attendeesJSON,
+		event.StartsAt, event.EndsAt, event.AllDay, event.TimeZone, event.RRule, event.RecurrenceID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert event: %w", err)
+	}
+	return nil
+}
+
+// GetEvents returns a user's events starting at or after since, soonest first (limit 0 = no limit)
+func (db *DB) GetEvents(ctx context.Context, userID int64, since time.Time, limit int) ([]*Event, error) {
+	query := `
+		SELECT id, user_id, email_id, uid, source, method, status, sequence, summary, description, location,
+		       organizer, attendees, starts_at, ends_at, all_day, time_zone, rrule, recurrence_id, created_at, updated_at
+		FROM events
+		WHERE user_id = $1 AND (starts_at >= $2 OR rrule != '')
+		ORDER BY starts_at
+	`
+	args := []interface{}{userID, since}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*Event, 0)
+	for rows.Next() {
+		var e Event
+		var attendeesJSON []byte
+		err := rows.Scan(&e.ID, &e.UserID, &e.EmailID, &e.UID, &e.Source, &e.Method, &e.Status, &e.Sequence,
+			&e.Summary, &e.Description, &e.Location, &e.Organizer, &attendeesJSON,
+			&e.StartsAt, &e.EndsAt, &e.AllDay, &e.TimeZone, &e.RRule, &e.RecurrenceID, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		if err := json.Unmarshal(attendeesJSON, &e.Attendees); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attendees: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating events: %w", err)
+	}
+	return events, nil
+}
+
+// GetCalendarFeedToken returns the user's calendar feed token, creating one if they have none
+func (db *DB) GetCalendarFeedToken(ctx context.Context, userID int64) (string, error) {
+	var token string
+	err := db.conn.QueryRowContext(ctx, `SELECT token FROM calendar_feeds WHERE user_id = $1`, userID).Scan(&token)
+	if err == nil {
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get calendar feed token: %w", err)
+	}
+	return db.RotateCalendarFeedToken(ctx, userID)
+}
+
+// RotateCalendarFeedToken replaces the user's calendar feed token, invalidating the old feed URL
+func (db *DB) RotateCalendarFeedToken(ctx context.Context, userID int64) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate calendar feed token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO calendar_feeds (user_id, token, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()
+	`, userID, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to save calendar feed token: %w", err)
+	}
+	return token, nil
+}
+
+// GetUserIDByCalendarFeedToken returns the user a feed token belongs to, or 0 if it is unknown
+func (db *DB) GetUserIDByCalendarFeedToken(ctx context.Context, token string) (int64, error) {
+	var userID int64
+	err := db.conn.QueryRowContext(ctx, `SELECT user_id FROM calendar_feeds WHERE token = $1`, token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up calendar feed token: %w", err)
+	}
+	return userID, nil
+}
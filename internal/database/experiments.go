@@ -0,0 +1,382 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Experiment variant identifiers recorded on emails.experiment_variant
+const (
+	ExperimentVariantA = "A"
+	ExperimentVariantB = "B"
+)
+
+// ExperimentVariant describes one arm of a prompt experiment.
+// Empty fields fall back to the user's normal configuration.
+type ExperimentVariant struct {
+	AnalyzePrompt     string `json:"analyze_prompt"`       // Overrides the email_analyze system prompt
+	ActionsPrompt     string `json:"actions_prompt"`       // Overrides the email_actions system prompt
+	AIAnalyzePromptID *int64 `json:"ai_analyze_prompt_id"` // Pins an ai_prompts version for email_analyze (nil = latest)
+	AIActionsPromptID *int64 `json:"ai_actions_prompt_id"` // Pins an ai_prompts version for email_actions (nil = latest)
+	DisableAIPrompts  bool   `json:"disable_ai_prompts"`   // Skip AI-generated supplements entirely
+	Model             string `json:"model"`                // Overrides the AI model
+	PipelineMode      string `json:"pipeline_mode"`        // Overrides the user's pipeline mode ("two_stage", "economy" or "buckets")
+}
+
+// PromptExperiment compares two prompt variants on live traffic
+type PromptExperiment struct {
+	ID           int64             `db:"id" json:"id"`
+	UserID       int64             `db:"user_id" json:"user_id"`
+	Name         string            `db:"name" json:"name"`
+	Description  string            `db:"description" json:"description"`
+	VariantA     ExperimentVariant `db:"variant_a" json:"variant_a"`
+	VariantB     ExperimentVariant `db:"variant_b" json:"variant_b"`
+	TrafficSplit int               `db:"traffic_split" json:"traffic_split"` // Percentage of emails routed to variant B
+	IsActive     bool              `db:"is_active" json:"is_active"`
+	StartedAt    time.Time         `db:"started_at" json:"started_at"`
+	EndedAt      *time.Time        `db:"ended_at" json:"ended_at"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time         `db:"updated_at" json:"updated_at"`
+}
+
+// Variant returns the variant definition for the given identifier
+func (e *PromptExperiment) Variant(name string) *ExperimentVariant {
+	if name == ExperimentVariantB {
+		return &e.VariantB
+	}
+	return &e.VariantA
+}
+
+// ExperimentVariantResult holds outcome metrics for one experiment variant
+type ExperimentVariantResult struct {
+	Variant                string  `json:"variant"`
+	Emails                 int     `json:"emails"`
+	Corrected              int     `json:"corrected"`
+	CorrectionRate         float64 `json:"correction_rate"`
+	Archived               int     `json:"archived"`
+	Unarchived             int     `json:"unarchived"`
+	UnarchiveRate          float64 `json:"unarchive_rate"`
+	Notified               int     `json:"notified"`
+	NotificationsAccepted  int     `json:"notifications_accepted"`
+	NotificationAcceptance float64 `json:"notification_acceptance"`
+	PromptTokens           int64   `json:"prompt_tokens"`
+	CompletionTokens       int64   `json:"completion_tokens"`
+	AvgTokensPerEmail      float64 `json:"avg_tokens_per_email"`
+	CostUSD                float64 `json:"cost_usd"` // Estimated from the price table when each email was processed
+	AvgCostPerEmail        float64 `json:"avg_cost_per_email"`
+}
+
+// ExperimentResults is the per-variant report for an experiment
+type ExperimentResults struct {
+	Experiment *PromptExperiment          `json:"experiment"`
+	Variants   []*ExperimentVariantResult `json:"variants"`
+}
+
+const experimentColumns = `id, user_id, name, description, variant_a, variant_b, traffic_split, is_active, started_at, ended_at, created_at, updated_at`
+
+// scanExperiment scans a single prompt_experiments row
+func scanExperiment(scanner interface{ Scan(...interface{}) error }) (*PromptExperiment, error) {
+	var e PromptExperiment
+	var variantAJSON, variantBJSON []byte
+	err := scanner.Scan(
+		&e.ID, &e.UserID, &e.Name, &e.Description,
+		&variantAJSON, &variantBJSON,
+		&e.TrafficSplit, &e.IsActive,
+		&e.StartedAt, &e.EndedAt, &e.CreatedAt, &e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variantAJSON, &e.VariantA); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal variant_a: %w", err)
+	}
+	if err := json.Unmarshal(variantBJSON, &e.VariantB); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal variant_b: %w", err)
+	}
+	return &e, nil
+}
+
+// CreateExperiment saves a new prompt experiment. Any running experiment for the user is stopped first.
+func (db *DB) CreateExperiment(ctx context.Context, experiment *PromptExperiment) error {
+	variantAJSON, err := json.Marshal(experiment.VariantA)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variant_a: %w", err)
+	}
+	variantBJSON, err := json.Marshal(experiment.VariantB)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variant_b: %w", err)
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if experiment.IsActive {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE prompt_experiments SET is_active = FALSE, ended_at = NOW(), updated_at = NOW()
+			WHERE user_id = $1 AND is_active = TRUE
+		`, experiment.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to stop running experiment: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO prompt_experiments (user_id, name, description, variant_a, variant_b, traffic_split, is_active, started_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), NOW())
+		RETURNING id, started_at, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query,
+		experiment.UserID,
+		experiment.Name,
+		experiment.Description,
+		variantAJSON,
+		variantBJSON,
+		experiment.TrafficSplit,
+		experiment.IsActive,
+	).Scan(&experiment.ID, &experiment.StartedAt, &experiment.CreatedAt, &experiment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create experiment: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetExperiment returns an experiment by ID, or nil if not found
+func (db *DB) GetExperiment(ctx context.Context, userID, experimentID int64) (*PromptExperiment, error) {
+	query := `SELECT ` + experimentColumns + ` FROM prompt_experiments WHERE id = $1 AND user_id = $2`
+
+	e, err := scanExperiment(db.conn.QueryRowContext(ctx, query, experimentID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+	return e, nil
+}
+
+// GetActiveExperiment returns the user's running experiment, or nil if none
+func (db *DB) GetActiveExperiment(ctx context.Context, userID int64) (*PromptExperiment, error) {
+	query := `SELECT ` + experimentColumns + ` FROM prompt_experiments WHERE user_id = $1 AND is_active = TRUE`
+
+	e, err := scanExperiment(db.conn.QueryRowContext(ctx, query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active experiment: %w", err)
+	}
+	return e, nil
+}
+
+// GetExperiments lists all experiments for a user, newest first
+func (db *DB) GetExperiments(ctx context.Context, userID int64) ([]*PromptExperiment, error) {
+	query := `SELECT ` + experimentColumns + ` FROM prompt_experiments WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := db.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiments: %w", err)
+	}
+	defer rows.Close()
+
+	experiments := make([]*PromptExperiment, 0)
+	for rows.Next() {
+		e, err := scanExperiment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan experiment: %w", err)
+		}
+		experiments = append(experiments, e)
+	}
+
+	return experiments, rows.Err()
+}
+
+// UpdateExperimentSplit changes the traffic split of an experiment
+func (db *DB) UpdateExperimentSplit(ctx context.Context, userID, experimentID int64, trafficSplit int) error {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE prompt_experiments SET traffic_split = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3
+	`, trafficSplit, experimentID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update experiment split: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("experiment not found or unauthorized")
+	}
+	return nil
+}
+
+// StopExperiment ends a running experiment
+func (db *DB) StopExperiment(ctx context.Context, userID, experimentID int64) error {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE prompt_experiments SET is_active = FALSE, ended_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND is_active = TRUE
+	`, experimentID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to stop experiment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("experiment not found or not running")
+	}
+	return nil
+}
+
+// GetExperimentResults reports outcome metrics per variant.
+// Correction rate counts emails with human feedback; un-archive rate counts archived
+// emails the user moved back to the inbox; a notification is accepted when the
+// notified email received no corrective feedback. Cost is the estimated AI spend recorded
+// on each email (retries, fallbacks and embeddings included).
+func (db *DB) GetExperimentResults(ctx context.Context, userID, experimentID int64) (*ExperimentResults, error) {
+	experiment, err := db.GetExperiment(ctx, userID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if experiment == nil {
+		return nil, nil
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT experiment_variant,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE COALESCE(human_feedback, '') != ''),
+			COUNT(*) FILTER (WHERE bypassed_inbox),
+			COUNT(*) FILTER (WHERE bypassed_inbox AND user_unarchived),
+			COUNT(*) FILTER (WHERE notification_sent),
+			COUNT(*) FILTER (WHERE notification_sent AND COALESCE(human_feedback, '') = ''),
+			COALESCE(SUM(prompt_tokens), 0),
+			COALESCE(SUM(completion_tokens), 0),
+			COALESCE(SUM(cost_usd), 0)
+		FROM emails
+		WHERE user_id = $1 AND experiment_id = $2
+		GROUP BY experiment_variant
+		ORDER BY experiment_variant
+	`, userID, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("experiment results query failed: %w", err)
+	}
+	defer rows.Close()
+
+	byVariant := make(map[string]*ExperimentVariantResult)
+	for rows.Next() {
+		var r ExperimentVariantResult
+		if err := rows.Scan(&r.Variant, &r.Emails, &r.Corrected, &r.Archived, &r.Unarchived,
+			&r.Notified, &r.NotificationsAccepted, &r.PromptTokens, &r.CompletionTokens, &r.CostUSD); err != nil {
+			return nil, fmt.Errorf("experiment results scan failed: %w", err)
+		}
+		byVariant[r.Variant] = &r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating experiment results: %w", err)
+	}
+
+	results := &ExperimentResults{Experiment: experiment}
+	for _, v := range []string{ExperimentVariantA, ExperimentVariantB} {
+		r, ok := byVariant[v]
+		if !ok {
+			r = &ExperimentVariantResult{Variant: v}
+		}
+		if r.Emails > 0 {
+			r.CorrectionRate = float64(r.Corrected) / float64(r.Emails)
+			r.AvgTokensPerEmail = float64(r.PromptTokens+r.CompletionTokens) / float64(r.Emails)
+			r.AvgCostPerEmail = r.CostUSD / float64(r.Emails)
+		}
+		if r.Archived > 0 {
+			r.UnarchiveRate = float64(r.Unarchived) / float64(r.Archived)
+		}
+		if r.Notified > 0 {
+			r.NotificationAcceptance = float64(r.NotificationsAccepted) / float64(r.Notified)
+		}
+		results.Variants = append(results.Variants, r)
+	}
+
+	return results, nil
+}
+
+// GetArchivedExperimentEmailIDs returns IDs of AI-archived experiment emails processed since the given time
+// that have not yet been seen back in the inbox. Emails whose archive failed in Gmail never left the
+// inbox, so they are excluded.
+func (db *DB) GetArchivedExperimentEmailIDs(ctx context.Context, userID int64, since time.Time) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id FROM emails
+		WHERE user_id = $1 AND experiment_id IS NOT NULL AND bypassed_inbox = TRUE AND archived_at IS NOT NULL
+		  AND user_unarchived = FALSE AND processed_at >= $2
+		ORDER BY processed_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived experiment emails: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan email id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// LabelledEmail is a past decision the user accepted, used as the expected outcome when
+// replaying emails through a different pipeline mode
+type LabelledEmail struct {
+	ID          string   `json:"id"`
+	Labels      []string `json:"labels"`
+	BypassInbox bool     `json:"bypass_inbox"` // False if the user moved an AI-archived email back to the inbox
+}
+
+// GetLabelledEmails returns up to limit emails processed before the given time whose decision the
+// user did not correct with written feedback, newest first. Un-archived emails count as "keep in inbox";
+// emails with written feedback are skipped because the feedback doesn't say what the right decision was.
+func (db *DB) GetLabelledEmails(ctx context.Context, userID int64, before time.Time, limit int) ([]*LabelledEmail, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, labels_applied, bypassed_inbox AND NOT user_unarchived
+		FROM emails
+		WHERE user_id = $1 AND processed_at < $2 AND COALESCE(human_feedback, '') = ''
+		ORDER BY processed_at DESC
+		LIMIT $3
+	`, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query labelled emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := make([]*LabelledEmail, 0)
+	for rows.Next() {
+		var e LabelledEmail
+		var labelsJSON []byte
+		if err := rows.Scan(&e.ID, &labelsJSON, &e.BypassInbox); err != nil {
+			return nil, fmt.Errorf("failed to scan labelled email: %w", err)
+		}
+		if err := json.Unmarshal(labelsJSON, &e.Labels); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+		}
+		emails = append(emails, &e)
+	}
+	return emails, rows.Err()
+}
+
+// MarkEmailArchived records that the pipeline's archive decision was applied in Gmail
+func (db *DB) MarkEmailArchived(ctx context.Context, userID int64, emailID string) error {
+	_, err := db.conn.ExecContext(ctx, `UPDATE emails SET archived_at = NOW() WHERE id = $1 AND user_id = $2`, emailID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark email archived: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailUnarchived records that the user moved an AI-archived email back to the inbox
+func (db *DB) MarkEmailUnarchived(ctx context.Context, userID int64, emailID string) error {
+	_, err := db.conn.ExecContext(ctx, `UPDATE emails SET user_unarchived = TRUE WHERE id = $1 AND user_id = $2`, emailID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark email unarchived: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExplanationStage is the snapshot of a single AI call made while processing an email
+type ExplanationStage struct {
+	SystemPromptHash string `json:"system_prompt_hash"`
+	SystemPrompt     string `json:"system_prompt,omitempty"` // Resolved from prompt_snapshots on read
+	UserPrompt       string `json:"user_prompt"`
+	UserPromptHash   string `json:"user_prompt_hash"`
+	Output           string `json:"output"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// ContextTrim records a prompt section that was reduced to fit the context token budget
+type ContextTrim struct {
+	Section     string `json:"section"` // "memories", "labels", "sender_profile", "domain_profile" or "body"
+	Action      string `json:"action"`  // "dropped", "compacted" or "truncated"
+	Detail      string `json:"detail,omitempty"`
+	TokensSaved int    `json:"tokens_saved"`
+}
+
+// EmailExplanation records the exact inputs and outputs behind an email's triage decision
+type EmailExplanation struct {
+	EmailID           string           `db:"email_id" json:"email_id"`
+	UserID            int64            `db:"user_id" json:"user_id"`
+	TriageVia         string           `db:"triage_via" json:"triage_via"` // How the decision was reached (see Email.TriageVia)
+	Reasoning         string           `db:"reasoning" json:"reasoning"`   // The decision's reasoning, including rule-based paths
+	Model             string           `db:"model" json:"model"`
+	AIAnalyzePromptID *int64           `db:"ai_analyze_prompt_id" json:"ai_analyze_prompt_id"`
+	AIActionsPromptID *int64           `db:"ai_actions_prompt_id" json:"ai_actions_prompt_id"`
+	MemoryIDs         []int64          `db:"memory_ids" json:"memory_ids"`
+	SenderProfileID   *int64           `db:"sender_profile_id" json:"sender_profile_id"`
+	DomainProfileID   *int64           `db:"domain_profile_id" json:"domain_profile_id"`
+	LabelNames        []string         `db:"label_names" json:"label_names"`
+	Stage1            ExplanationStage `db:"stage1" json:"stage1"`
+	Stage2            ExplanationStage `db:"stage2" json:"stage2"`
+	ContextTrims      []ContextTrim    `db:"context_trims" json:"context_trims"`
+	ExampleEmailIDs   []string         `db:"example_email_ids" json:"example_email_ids"` // Similar past emails shown as few-shot examples
+	CreatedAt         time.Time        `db:"created_at" json:"created_at"`
+}
+
+// HashContent returns the hex SHA-256 of a prompt or output
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateEmailExplanation stores an explanation snapshot. System prompts are stored once per distinct content.
+// Stages without an AI call (empty prompts) are stored without hashes.
+func (db *DB) CreateEmailExplanation(ctx context.Context, explanation *EmailExplanation, stage1SystemPrompt, stage2SystemPrompt string) error {
+	if stage1SystemPrompt != "" {
+		explanation.Stage1.SystemPromptHash = HashContent(stage1SystemPrompt)
+		explanation.Stage1.UserPromptHash = HashContent(explanation.Stage1.UserPrompt)
+	}
+	if stage2SystemPrompt != "" {
+		explanation.Stage2.SystemPromptHash = HashContent(stage2SystemPrompt)
+		explanation.Stage2.UserPromptHash = HashContent(explanation.Stage2.UserPrompt)
+	}
+
+	memoryIDsJSON, err := json.Marshal(explanation.MemoryIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory ids: %w", err)
+	}
+	labelNamesJSON, err := json.Marshal(explanation.LabelNames)
+	if err != nil {
+		return fmt.Errorf("failed to marshal label names: %w", err)
+	}
+	stage1JSON, err := json.Marshal(explanation.Stage1)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stage1: %w", err)
+	}
+	stage2JSON, err := json.Marshal(explanation.Stage2)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stage2: %w", err)
+	}
+	if explanation.ContextTrims == nil {
+		explanation.ContextTrims = []ContextTrim{}
+	}
+	trimsJSON, err := json.Marshal(explanation.ContextTrims)
+	if err != nil {
+		return fmt.Errorf("failed to marshal context trims: %w", err)
+	}
+	if explanation.ExampleEmailIDs == nil {
+		explanation.ExampleEmailIDs = []string{}
+	}
+	examplesJSON, err := json.Marshal(explanation.ExampleEmailIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal example email ids: %w", err)
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, content := range []string{stage1SystemPrompt, stage2SystemPrompt} {
+		if content == "" {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO prompt_snapshots (hash, content, last_used_at) VALUES ($1, $2, NOW())
+			ON CONFLICT (hash) DO UPDATE SET last_used_at = NOW()
+		`, HashContent(content), content)
+		if err != nil {
+			return fmt.Errorf("failed to store prompt snapshot: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO email_explanations (email_id, user_id, model, ai_analyze_prompt_id, ai_actions_prompt_id, memory_ids,
+		                                sender_profile_id, domain_profile_id, label_names, stage1, stage2, context_trims, example_email_ids,
+		                                triage_via, reasoning, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+		ON CONFLICT (email_id) DO NOTHING
+	`,
+		explanation.EmailID,
+		explanation.UserID,
+		explanation.Model,
+		explanation.AIAnalyzePromptID,
+		explanation.AIActionsPromptID,
+		memoryIDsJSON,
+		explanation.SenderProfileID,
+		explanation.DomainProfileID,
+		labelNamesJSON,
+		stage1JSON,
+		stage2JSON,
+		trimsJSON,
+		examplesJSON,
+		explanation.TriageVia,
+		explanation.Reasoning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create email explanation: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetEmailExplanation returns the stored explanation for an email with system prompts resolved,
+// or nil if none was captured (or it has expired)
+func (db *DB) GetEmailExplanation(ctx context.Context, userID int64, emailID string) (*EmailExplanation, error) {
+	query := `
+		SELECT e.email_id, e.user_id, e.model, e.ai_analyze_prompt_id, e.ai_actions_prompt_id, e.memory_ids,
+		       e.sender_profile_id, e.domain_profile_id, e.label_names, e.stage1, e.stage2, e.context_trims, e.example_email_ids, e.created_at,
+		       e.triage_via, e.reasoning, COALESCE(s1.content, ''), COALESCE(s2.content, '')
+		FROM email_explanations e
+		LEFT JOIN prompt_snapshots s1 ON s1.hash = e.stage1->>'system_prompt_hash'
+		LEFT JOIN prompt_snapshots s2 ON s2.hash = e.stage2->>'system_prompt_hash'
+		WHERE e.email_id = $1 AND e.user_id = $2
+	`
+
+	var ex EmailExplanation
+	var memoryIDsJSON, labelNamesJSON, stage1JSON, stage2JSON, trimsJSON, examplesJSON []byte
+	var stage1System, stage2System string
+	err := db.conn.QueryRowContext(ctx, query, emailID, userID).Scan(
+		&ex.EmailID, &ex.UserID, &ex.Model, &ex.AIAnalyzePromptID, &ex.AIActionsPromptID, &memoryIDsJSON,
+		&ex.SenderProfileID, &ex.DomainProfileID, &labelNamesJSON, &stage1JSON, &stage2JSON, &trimsJSON, &examplesJSON, &ex.CreatedAt,
+		&ex.TriageVia, &ex.Reasoning, &stage1System, &stage2System,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email explanation: %w", err)
+	}
+
+	if err := json.Unmarshal(memoryIDsJSON, &ex.MemoryIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal memory ids: %w", err)
+	}
+	if err := json.Unmarshal(labelNamesJSON, &ex.LabelNames); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal label names: %w", err)
+	}
+	if err := json.Unmarshal(stage1JSON, &ex.Stage1); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stage1: %w", err)
+	}
+	if err := json.Unmarshal(stage2JSON, &ex.Stage2); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stage2: %w", err)
+	}
+	if err := json.Unmarshal(trimsJSON, &ex.ContextTrims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal context trims: %w", err)
+	}
+	if err := json.Unmarshal(examplesJSON, &ex.ExampleEmailIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal example email ids: %w", err)
+	}
+	ex.Stage1.SystemPrompt = stage1System
+	ex.Stage2.SystemPrompt = stage2System
+
+	return &ex, nil
+}
+
+// DeleteExpiredExplanations removes explanations older than the retention period and any prompt
+// snapshots no longer referenced. Snapshots used within the retention period are kept even when
+// unreferenced, so a snapshot reused by an explanation being written concurrently is never removed.
+func (db *DB) DeleteExpiredExplanations(ctx context.Context, retentionDays int) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		DELETE FROM email_explanations WHERE created_at < NOW() - make_interval(days => $1)
+	`, retentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired explanations: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+
+	_, err = db.conn.ExecContext(ctx, `
+		DELETE FROM prompt_snapshots ps
+		WHERE ps.last_used_at < NOW() - make_interval(days => $1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM email_explanations e
+			WHERE e.stage1->>'system_prompt_hash' = ps.hash OR e.stage2->>'system_prompt_hash' = ps.hash
+		)
+	`, retentionDays)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete orphaned prompt snapshots: %w", err)
+	}
+
+	return deleted, nil
+}
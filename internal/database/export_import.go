@@ -43,6 +43,7 @@ type ExportSenderProfile struct {
 	EmailCount     int            `json:"email_count"`
 	EmailsArchived int            `json:"emails_archived"`
 	EmailsNotified int            `json:"emails_notified"`
+	EmailsFastPath int            `json:"emails_fast_path"`
 	SlugCounts     map[string]int `json:"slug_counts"`
 	LabelCounts    map[string]int `json:"label_counts"`
 	KeywordCounts  map[string]int `json:"keyword_counts"`
@@ -223,7 +224,7 @@ func (db *DB) ExportMemories(ctx context.Context, userID int64) ([]ExportMemory,
 func (db *DB) ExportSenderProfiles(ctx context.Context, userID int64) ([]ExportSenderProfile, error) {
 	query := `
 		SELECT profile_type, identifier,
-		       email_count, emails_archived, emails_notified,
+		       email_count, emails_archived, emails_notified, emails_fast_path,
 		       slug_counts, label_counts, keyword_counts,
 		       sender_type, summary, first_seen_at, last_seen_at
 		FROM sender_profiles
@@ -242,7 +243,7 @@ func (db *DB) ExportSenderProfiles(ctx context.Context, userID int64) ([]ExportS
 		var slugJSON, labelJSON, kwJSON []byte
 		if err := rows.Scan(
 			&p.ProfileType, &p.Identifier,
-			&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified,
+			&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified, &p.EmailsFastPath,
 			&slugJSON, &labelJSON, &kwJSON,
 			&p.SenderType, &p.Summary, &p.FirstSeenAt, &p.LastSeenAt,
 		); err != nil {
@@ -453,16 +454,17 @@ func importSenderProfiles(ctx context.Context, tx *sql.Tx, userID int64, profile
 	query := `
 		INSERT INTO sender_profiles (
 			user_id, profile_type, identifier,
-			email_count, emails_archived, emails_notified,
+			email_count, emails_archived, emails_notified, emails_fast_path,
 			slug_counts, label_counts, keyword_counts,
 			sender_type, summary,
 			first_seen_at, last_seen_at, modified_at, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
 		ON CONFLICT (user_id, profile_type, identifier)
 		DO UPDATE SET
 			email_count = EXCLUDED.email_count,
 			emails_archived = EXCLUDED.emails_archived,
 			emails_notified = EXCLUDED.emails_notified,
+			emails_fast_path = EXCLUDED.emails_fast_path,
 			slug_counts = EXCLUDED.slug_counts,
 			label_counts = EXCLUDED.label_counts,
 			keyword_counts = EXCLUDED.keyword_counts,
@@ -478,7 +480,7 @@ func importSenderProfiles(ctx context.Context, tx *sql.Tx, userID int64, profile
 		kwJSON, _ := json.Marshal(p.KeywordCounts)
 		if _, err := tx.ExecContext(ctx, query,
 			userID, p.ProfileType, p.Identifier,
-			p.EmailCount, p.EmailsArchived, p.EmailsNotified,
+			p.EmailCount, p.EmailsArchived, p.EmailsNotified, p.EmailsFastPath,
 			slugJSON, labelJSON, kwJSON,
 			p.SenderType, p.Summary,
 			p.FirstSeenAt, p.LastSeenAt,
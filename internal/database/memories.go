@@ -326,3 +326,37 @@ func (db *DB) scanMemoryEmails(ctx context.Context, query string, args ...interf
 
 	return emails, nil
 }
+
+// GetMemoriesByIDs returns the user's memories with the given IDs
+func (db *DB) GetMemoriesByIDs(ctx context.Context, userID int64, ids []int64) ([]*Memory, error) {
+	memories := make([]*Memory, 0)
+	if len(ids) == 0 {
+		return memories, nil
+	}
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal memory ids: %w", err)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, user_id, type, content, reasoning, start_date, end_date, created_at
+		FROM memories
+		WHERE user_id = $1 AND id IN (SELECT jsonb_array_elements_text($2::jsonb)::bigint)
+		ORDER BY start_date DESC
+	`, userID, idsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Memory
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Type, &m.Content, &m.Reasoning, &m.StartDate, &m.EndDate, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memories = append(memories, &m)
+	}
+
+	return memories, rows.Err()
+}
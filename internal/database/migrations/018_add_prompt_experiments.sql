@@ -0,0 +1,34 @@
+-- Prompt A/B experiments: compare two prompt variants on live traffic
+CREATE TABLE IF NOT EXISTS prompt_experiments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+
+    -- Variant definitions (system prompt overrides, ai_prompts version references, model)
+    variant_a JSONB NOT NULL DEFAULT '{}',
+    variant_b JSONB NOT NULL DEFAULT '{}',
+
+    -- Percentage of traffic (0-100) routed to variant B
+    traffic_split INT NOT NULL DEFAULT 50 CHECK (traffic_split BETWEEN 0 AND 100),
+
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Only one running experiment per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_experiments_active ON prompt_experiments(user_id) WHERE is_active = TRUE;
+
+-- Record the assigned variant and token usage on each processed email
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS experiment_id BIGINT REFERENCES prompt_experiments(id) ON DELETE SET NULL;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS experiment_variant TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS prompt_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS completion_tokens INT NOT NULL DEFAULT 0;
+
+-- Set when an archived email is found back in the inbox (user disagreed with the archive)
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS user_unarchived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_emails_experiment ON emails(experiment_id, experiment_variant) WHERE experiment_id IS NOT NULL;
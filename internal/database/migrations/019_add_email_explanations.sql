@@ -0,0 +1,30 @@
+-- Decision explainability: snapshot of the exact AI inputs and outputs for each processed email
+
+-- Content-addressed prompt text (system prompts repeat across many emails)
+CREATE TABLE IF NOT EXISTS prompt_snapshots (
+    hash TEXT PRIMARY KEY,                  -- SHA-256 of content
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS email_explanations (
+    email_id TEXT PRIMARY KEY REFERENCES emails(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    model TEXT NOT NULL DEFAULT '',
+
+    -- References to the context that went into the prompts
+    ai_analyze_prompt_id BIGINT REFERENCES ai_prompts(id) ON DELETE SET NULL,
+    ai_actions_prompt_id BIGINT REFERENCES ai_prompts(id) ON DELETE SET NULL,
+    memory_ids JSONB NOT NULL DEFAULT '[]',
+    sender_profile_id BIGINT REFERENCES sender_profiles(id) ON DELETE SET NULL,
+    domain_profile_id BIGINT REFERENCES sender_profiles(id) ON DELETE SET NULL,
+    label_names JSONB NOT NULL DEFAULT '[]',
+
+    -- Per-stage snapshot: system prompt hash, user prompt, raw output, token usage
+    stage1 JSONB NOT NULL DEFAULT '{}',
+    stage2 JSONB NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_explanations_created_at ON email_explanations(created_at);
@@ -0,0 +1,4 @@
+-- Record which model made the final Stage 2 decision and how confident it was
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS decision_model TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS confidence REAL NOT NULL DEFAULT 0;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS escalated BOOLEAN NOT NULL DEFAULT FALSE;
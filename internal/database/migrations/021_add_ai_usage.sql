@@ -0,0 +1,17 @@
+-- Per-call AI usage ledger for cost accounting and budgets
+CREATE TABLE IF NOT EXISTS ai_usage (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task TEXT NOT NULL DEFAULT '',          -- analyze, actions, draft, profile, memory, wrapup, wizard
+    model TEXT NOT NULL DEFAULT '',
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_user_created ON ai_usage(user_id, created_at);
+
+-- Per-user spending limits in USD (0 = use the server default)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_budget_usd DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS monthly_budget_usd DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Per-user pipeline mode: two_stage (analyze + actions) or economy (one merged call)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pipeline_mode TEXT NOT NULL DEFAULT 'two_stage';
+
+-- Mode each email was processed with, for comparing outcomes between modes
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS pipeline_mode TEXT NOT NULL DEFAULT 'two_stage';
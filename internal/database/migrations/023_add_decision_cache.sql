@@ -0,0 +1,20 @@
+-- Cache of AI triage decisions for duplicate and near-duplicate (templated) emails
+CREATE TABLE IF NOT EXISTS decision_cache (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cache_key TEXT NOT NULL,                -- SHA-256 of the normalized email skeleton, prompt version and model
+    analysis JSONB NOT NULL,
+    actions JSONB NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, cache_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_decision_cache_expires_at ON decision_cache(expires_at);
+
+-- Per-user opt-out of the decision cache
+ALTER TABLE users ADD COLUMN IF NOT EXISTS decision_cache_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Whether an email's decision was served from the cache
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT FALSE;
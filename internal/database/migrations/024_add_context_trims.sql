@@ -0,0 +1,2 @@
+-- Prompt sections trimmed or dropped to fit the context token budget
+ALTER TABLE email_explanations ADD COLUMN IF NOT EXISTS context_trims JSONB NOT NULL DEFAULT '[]';
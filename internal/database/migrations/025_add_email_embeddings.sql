@@ -0,0 +1,26 @@
+-- Embeddings of processed emails (subject + summary) for retrieving similar past decisions
+CREATE TABLE IF NOT EXISTS email_embeddings (
+    email_id TEXT PRIMARY KEY REFERENCES emails(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,                    -- Vectors are only compared within the same model
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_embeddings_user_model ON email_embeddings(user_id, model, created_at DESC);
+
+-- Use pgvector for the similarity search when the extension is available;
+-- otherwise the search falls back to cosine similarity computed in Go
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        CREATE EXTENSION IF NOT EXISTS vector;
+        ALTER TABLE email_embeddings ADD COLUMN IF NOT EXISTS vec vector;
+    END IF;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE NOTICE 'pgvector not enabled: %', SQLERRM;
+END
+$$;
+
+-- Past emails shown to the AI as few-shot examples
+ALTER TABLE email_explanations ADD COLUMN IF NOT EXISTS example_email_ids JSONB NOT NULL DEFAULT '[]';
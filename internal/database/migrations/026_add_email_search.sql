@@ -0,0 +1,27 @@
+-- Full-text search over processed emails: subject ranks highest, then summary and keywords, then the rest
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(subject, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(summary, '') || ' ' || COALESCE(keywords::text, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(slug, '') || ' ' || COALESCE(reasoning, '') || ' ' || COALESCE(from_address, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_emails_search_vector ON emails USING GIN(search_vector);
+
+-- Keyset pagination and facet filters
+CREATE INDEX IF NOT EXISTS idx_emails_user_processed ON emails(user_id, processed_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_emails_user_domain ON emails(user_id, from_domain);
+CREATE INDEX IF NOT EXISTS idx_emails_user_slug ON emails(user_id, slug);
+
+-- Sender filter (LOWER(from_address) LIKE '%...%'): a trigram index serves the leading wildcard
+-- when pg_trgm is available; otherwise the filter scans the user's emails
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pg_trgm') THEN
+        CREATE EXTENSION IF NOT EXISTS pg_trgm;
+        CREATE INDEX IF NOT EXISTS idx_emails_from_address_trgm ON emails USING GIN (LOWER(from_address) gin_trgm_ops);
+    END IF;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE NOTICE 'pg_trgm not enabled: %', SQLERRM;
+END
+$$;
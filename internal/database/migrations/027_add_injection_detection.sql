@@ -0,0 +1,7 @@
+-- Prompt-injection detection: score and signals recorded per email, and notifications
+-- withheld from suspicious emails by unknown senders until the user confirms them
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS injection_score REAL NOT NULL DEFAULT 0;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS injection_signals JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS held_notification TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_emails_held_notification ON emails(user_id) WHERE held_notification != '';
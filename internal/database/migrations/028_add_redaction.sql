@@ -0,0 +1,11 @@
+-- Per-user PII redaction settings: built-in detectors on/off, custom patterns and exempt senders
+CREATE TABLE IF NOT EXISTS redaction_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    patterns JSONB NOT NULL DEFAULT '[]',   -- [{"name": "...", "pattern": "..."}]
+    allowlist JSONB NOT NULL DEFAULT '[]',  -- Sender addresses or domains sent unredacted
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Distinct values redacted from each email before it was sent to the AI
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS redaction_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Buckets pipeline mode: the bucket each email was triaged into, why, and the
+-- bucket-specific assessments (notification severity/urgency, newsletter interest)
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS bucket TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS triage_reasoning TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS severity TEXT NOT NULL DEFAULT '';  -- notification: low|medium|high|critical
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS urgency TEXT NOT NULL DEFAULT '';   -- notification: low|medium|high
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS interesting_score INTEGER;          -- newsletter: 0-10
+
+CREATE INDEX IF NOT EXISTS idx_emails_user_bucket ON emails(user_id, bucket, processed_at DESC) WHERE bucket != '';
@@ -0,0 +1,8 @@
+-- Fast paths: emails decided without the AI (inherited from an earlier email in the thread,
+-- or from a consistent sender's history), and a per-sender flag that forces AI triage
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS thread_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS triage_via TEXT NOT NULL DEFAULT '';  -- ai|thread_reply|consistent_sender ('' for cached/rules-only)
+
+CREATE INDEX IF NOT EXISTS idx_emails_user_thread ON emails(user_id, thread_id, processed_at DESC) WHERE thread_id != '';
+
+ALTER TABLE sender_profiles ADD COLUMN IF NOT EXISTS mixed BOOLEAN NOT NULL DEFAULT FALSE;
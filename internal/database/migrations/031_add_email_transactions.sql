@@ -0,0 +1,10 @@
+-- Structured data extracted from transactional emails (receipts, invoices, shipping, refunds, bookings)
+-- for the expense ledger
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS vendor TEXT NOT NULL DEFAULT '';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS document_type TEXT NOT NULL DEFAULT '';  -- receipt|invoice|shipping|refund|booking ('' = not transactional)
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS amount NUMERIC(14, 2);                  -- Total charged, refunded or due
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';       -- ISO 4217 code
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS due_date DATE;                           -- Invoices only
+
+CREATE INDEX IF NOT EXISTS idx_emails_user_transactions ON emails(user_id, processed_at DESC) WHERE document_type != '';
+CREATE INDEX IF NOT EXISTS idx_emails_user_vendor ON emails(user_id, vendor) WHERE document_type != '';
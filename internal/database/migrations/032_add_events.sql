@@ -0,0 +1,34 @@
+-- Calendar events parsed from invites (text/calendar parts and .ics attachments) or extracted
+-- from email bodies by the AI, served to calendar clients through a per-user ICS feed
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email_id TEXT NOT NULL,                      -- Email the latest version came from
+    uid TEXT NOT NULL,                           -- iCalendar UID (generated for AI-extracted events)
+    source TEXT NOT NULL,                        -- ics|ai
+    method TEXT NOT NULL DEFAULT '',             -- iTIP method: REQUEST, CANCEL, PUBLISH...
+    status TEXT NOT NULL DEFAULT 'CONFIRMED',    -- CONFIRMED|TENTATIVE|CANCELLED
+    sequence INTEGER NOT NULL DEFAULT 0,
+    summary TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    organizer TEXT NOT NULL DEFAULT '',
+    attendees JSONB NOT NULL DEFAULT '[]',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    all_day BOOLEAN NOT NULL DEFAULT FALSE,
+    time_zone TEXT NOT NULL DEFAULT '',
+    rrule TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, uid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_user_starts ON events(user_id, starts_at);
+
+-- Secret token in each user's calendar subscription URL
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+-- Action items extracted from emails ("please sign by Friday", "review PR #412")
+CREATE TABLE IF NOT EXISTS tasks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email_id TEXT NOT NULL,                 -- Email the task was extracted from
+    description TEXT NOT NULL,
+    due_date DATE,                          -- NULL when the email states no deadline
+    completed_at TIMESTAMPTZ,               -- NULL while the task is open
+    snoozed_until TIMESTAMPTZ,              -- Hidden from open lists and wrapups until then
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_open ON tasks(user_id, due_date) WHERE completed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_email ON tasks(email_id);
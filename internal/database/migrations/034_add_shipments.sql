@@ -0,0 +1,30 @@
+-- Package tracking: shipping notifications grouped into one shipment per order
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    shipment_key TEXT NOT NULL,                 -- Lowercased order reference, or tracking number when there is none
+    carrier TEXT NOT NULL DEFAULT '',
+    tracking_number TEXT NOT NULL DEFAULT '',
+    order_ref TEXT NOT NULL DEFAULT '',
+    vendor TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,                       -- shipped|out_for_delivery|delivered|exception
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, shipment_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_user_updated ON shipments(user_id, updated_at DESC);
+
+-- One row per shipping email: the shipment's timeline
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,           -- When the email was processed
+    UNIQUE (shipment_id, email_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_events_user_email ON shipment_events(user_id, email_id);
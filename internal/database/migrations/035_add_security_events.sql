@@ -0,0 +1,16 @@
+-- Security emails handled by the security lane: one-time codes, password resets, new-login alerts
+-- and account recovery. No codes are kept here; stored subjects have the code masked.
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email_id TEXT NOT NULL,
+    kind TEXT NOT NULL,                       -- otp|password_reset|new_login|account_recovery
+    detected_by TEXT NOT NULL,                -- rules|ai
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,   -- Unexpected reset or login: unknown sender or likely phishing
+    from_address TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, email_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_created ON security_events(user_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Phishing and spoofing risk: score and signals from sender authentication (SPF/DKIM/DMARC),
+-- display-name mismatches, lookalike domains and first-contact payment or credential requests
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS phishing_score REAL NOT NULL DEFAULT 0;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS phishing_signals JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_emails_user_phishing ON emails(user_id, processed_at DESC) WHERE phishing_score > 0;
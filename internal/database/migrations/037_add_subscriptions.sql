@@ -0,0 +1,28 @@
+-- Subscription inventory: one row per mailing list (List-Id) or subscription sender, with
+-- volume, engagement and an optional timed label applied to every email from it
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    list_key TEXT NOT NULL,                     -- List-Id, or the sender address when there is none
+    list_id TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',              -- Latest sender display name
+    from_address TEXT NOT NULL,                 -- Latest sender
+    from_domain TEXT NOT NULL DEFAULT '',
+    unsubscribe_url TEXT NOT NULL DEFAULT '',
+    unsubscribe_mailto TEXT NOT NULL DEFAULT '',
+    one_click BOOLEAN NOT NULL DEFAULT FALSE,
+    email_count INTEGER NOT NULL DEFAULT 0,
+    emails_archived INTEGER NOT NULL DEFAULT 0,
+    emails_notified INTEGER NOT NULL DEFAULT 0,
+    last_read_at TIMESTAMPTZ,                   -- Newest email the user opened, refreshed by the scheduler
+    label_policy TEXT NOT NULL DEFAULT '',      -- Timed label added to every email, '' for none
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, list_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_seen ON subscriptions(user_id, last_seen_at DESC);
+
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS subscription_id BIGINT REFERENCES subscriptions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_emails_subscription ON emails(subscription_id) WHERE subscription_id IS NOT NULL;
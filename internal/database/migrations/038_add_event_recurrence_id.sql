@@ -0,0 +1,6 @@
+-- Overrides of single instances of a recurring event (RECURRENCE-ID) are stored next to the series
+-- instead of replacing it: the key becomes (user_id, uid, recurrence_id)
+ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_id TEXT NOT NULL DEFAULT ''; -- '' for the series or a single event
+
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_user_id_uid_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_user_uid_recurrence ON events(user_id, uid, recurrence_id);
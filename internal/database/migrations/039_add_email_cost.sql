@@ -0,0 +1,2 @@
+-- Estimated AI cost of processing each email, so experiment variants can be compared on cost
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Explanations for decisions made without a Stage 1/Stage 2 call (fast paths, decision cache,
+-- security lane, rules only) record how the decision was reached and its rule-based reasoning
+ALTER TABLE email_explanations ADD COLUMN IF NOT EXISTS triage_via TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_explanations ADD COLUMN IF NOT EXISTS reasoning TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Emails decided by a fast path or the security lane. They count towards email_count but not the
+-- decision counters (slugs, labels, archived, notified), so fast-path decisions don't reinforce
+-- the consistency that triggers them
+ALTER TABLE sender_profiles ADD COLUMN IF NOT EXISTS emails_fast_path INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- When a prompt snapshot was last referenced by a new explanation. Orphaned snapshots are only
+-- deleted once this is older than the retention period, so cleanup cannot remove a snapshot that
+-- a concurrent explanation has just reused
+ALTER TABLE prompt_snapshots ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
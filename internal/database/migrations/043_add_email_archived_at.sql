@@ -0,0 +1,5 @@
+-- When the archive decided by the pipeline was actually applied in Gmail (NULL if it was not
+-- decided or the Gmail call failed). Only emails that really left the inbox can be un-archived
+-- by the user. Earlier archive decisions are assumed to have been applied.
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+UPDATE emails SET archived_at = processed_at WHERE bypassed_inbox = TRUE AND archived_at IS NULL;
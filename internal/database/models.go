@@ -22,6 +22,10 @@ type User struct {
 	WebhookURL         string   `db:"webhook_url" json:"-"`            // Webhook URL for notifications
 	WebhookHeaderKey   string   `db:"webhook_header_key" json:"-"`     // Optional custom header name
 	WebhookHeaderValue string   `db:"webhook_header_value" json:"-"`   // Optional custom header value
+	DailyBudgetUSD     float64  `db:"daily_budget_usd" json:"daily_budget_usd"`     // AI spend limit per day (0 = server default)
+	MonthlyBudgetUSD   float64  `db:"monthly_budget_usd" json:"monthly_budget_usd"` // AI spend limit per month (0 = server default)
+	PipelineMode       string   `db:"pipeline_mode" json:"pipeline_mode"`           // PipelineModeTwoStage, PipelineModeEconomy or PipelineModeBuckets
+	DecisionCacheEnabled bool   `db:"decision_cache_enabled" json:"decision_cache_enabled"` // Reuse cached AI decisions for duplicate emails
 	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
 }
@@ -43,10 +47,129 @@ type Email struct {
 	FeedbackDirty    bool      `db:"feedback_dirty" json:"feedback_dirty"` // Whether feedback needs to be included in next memory
 	NotificationSent bool      `db:"notification_sent" json:"notification_sent"` // Whether a push notification was sent
 	DraftCreated     bool      `db:"draft_created" json:"draft_created"`       // Whether a draft reply was created
+	ExperimentID      *int64    `db:"experiment_id" json:"experiment_id,omitempty"`   // Prompt experiment this email was assigned to
+	ExperimentVariant string    `db:"experiment_variant" json:"experiment_variant"`   // Assigned experiment variant ("A" or "B")
+	SubscriptionID    *int64    `db:"subscription_id" json:"subscription_id,omitempty"` // Mailing list or subscription the email came from
+	PromptTokens      int       `db:"prompt_tokens" json:"prompt_tokens"`             // Input tokens spent processing this email
+	CompletionTokens  int       `db:"completion_tokens" json:"completion_tokens"`     // Output tokens spent processing this email
+	CostUSD           float64   `db:"cost_usd" json:"cost_usd"`                       // Estimated AI cost of processing this email
+	UserUnarchived    bool      `db:"user_unarchived" json:"user_unarchived"`         // Archived by AI but moved back to the inbox by the user
+	DecisionModel     string    `db:"decision_model" json:"decision_model"`           // Model that made the final Stage 2 decision
+	Confidence        float64   `db:"confidence" json:"confidence"`                   // Stage 2 self-reported confidence (0-1)
+	Escalated         bool      `db:"escalated" json:"escalated"`                     // Decision was retried on the escalation model
+	PipelineMode      string    `db:"pipeline_mode" json:"pipeline_mode"`             // Pipeline mode the email was processed with
+	Cached            bool      `db:"cached" json:"cached"`                           // Decision was served from the decision cache
+	InjectionScore    float64   `db:"injection_score" json:"injection_score"`         // Likelihood (0-1) the content tries to instruct the AI
+	InjectionSignals  []string  `db:"injection_signals" json:"injection_signals"`     // What made the content look like a prompt injection
+	HeldNotification  string    `db:"held_notification" json:"held_notification"`     // Notification withheld until the user confirms it
+	PhishingScore     float64   `db:"phishing_score" json:"phishing_score"`           // Likelihood (0-1) the email is phishing or spoofed
+	PhishingSignals   []string  `db:"phishing_signals" json:"phishing_signals"`       // What made the email look like phishing
+	RedactionCount    int       `db:"redaction_count" json:"redaction_count"`         // Distinct PII values replaced before the AI saw the email
+	Bucket            string    `db:"bucket" json:"bucket"`                           // Bucket assigned in buckets mode ("" otherwise)
+	TriageReasoning   string    `db:"triage_reasoning" json:"triage_reasoning"`       // Why the email was put in its bucket
+	Severity          string    `db:"severity" json:"severity"`                       // Notification bucket: low, medium, high or critical
+	Urgency           string    `db:"urgency" json:"urgency"`                         // Notification bucket: low, medium or high
+	InterestingScore  *int      `db:"interesting_score" json:"interesting_score"`     // Newsletter bucket: 0-10 worth-reading score
+	ThreadID          string    `db:"thread_id" json:"thread_id"`                     // Gmail thread ID
+	TriageVia         string    `db:"triage_via" json:"triage_via"`                   // TriageViaAI, TriageViaCache or a fast path ("" for rules-only decisions)
+	Vendor            string    `db:"vendor" json:"vendor"`                           // Transactional emails: who charged, refunded or invoiced
+	DocumentType      string    `db:"document_type" json:"document_type"`             // DocumentType* ("" if not transactional)
+	Amount            *float64  `db:"amount" json:"amount"`                           // Total charged, refunded or due
+	Currency          string    `db:"currency" json:"currency"`                       // ISO 4217 code of Amount
+	DueDate           *time.Time `db:"due_date" json:"due_date"`                      // Invoices: when payment is due
 	ProcessedAt      time.Time `db:"processed_at" json:"processed_at"`   // When email was processed
 	CreatedAt        time.Time `db:"created_at" json:"created_at"`       // When record was created
 }
 
+// Pipeline modes for users.pipeline_mode and emails.pipeline_mode
+const (
+	PipelineModeTwoStage = "two_stage" // Separate analysis and actions calls
+	PipelineModeEconomy  = "economy"   // One merged call; profile summaries are not evolved
+	PipelineModeBuckets  = "buckets"   // Bucket triage, then a bucket-specific processor
+)
+
+// IsValidPipelineMode returns true for a known pipeline mode
+func IsValidPipelineMode(mode string) bool {
+	return mode == PipelineModeTwoStage || mode == PipelineModeEconomy || mode == PipelineModeBuckets
+}
+
+// How an email's decision was reached, for emails.triage_via
+const (
+	TriageViaAI               = "ai"                // The AI pipeline in the user's mode
+	TriageViaThreadReply      = "thread_reply"      // Inherited from the previous email in the Gmail thread
+	TriageViaConsistentSender = "consistent_sender" // The sender's dominant decision from their profile
+	TriageViaSecurity         = "security"          // The security lane's rules for codes and account alerts
+	TriageViaCache            = "cache"             // Reused from the decision cache for a templated email
+)
+
+// IsValidTriageVia returns true for a known triage_via value
+func IsValidTriageVia(via string) bool {
+	return via == TriageViaAI || via == TriageViaThreadReply || via == TriageViaConsistentSender || via == TriageViaSecurity || via == TriageViaCache
+}
+
+// IsFastPathTriage returns true for decisions made by rules without the AI: the fast paths and the
+// security lane
+func IsFastPathTriage(via string) bool {
+	return via == TriageViaThreadReply || via == TriageViaConsistentSender || via == TriageViaSecurity
+}
+
+// Document types for emails.document_type (transactional emails)
+const (
+	DocumentTypeReceipt  = "receipt"
+	DocumentTypeInvoice  = "invoice"
+	DocumentTypeShipping = "shipping"
+	DocumentTypeRefund   = "refund"
+	DocumentTypeBooking  = "booking"
+)
+
+// DocumentTypes lists every transactional document type
+var DocumentTypes = []string{DocumentTypeReceipt, DocumentTypeInvoice, DocumentTypeShipping, DocumentTypeRefund, DocumentTypeBooking}
+
+// IsValidDocumentType returns true for a known document type
+func IsValidDocumentType(documentType string) bool {
+	for _, t := range DocumentTypes {
+		if t == documentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPostedDocumentType returns true for the document types that record money actually spent or
+// returned (receipts and refunds). Invoices are usually followed by a receipt for the same payment,
+// and shipping notices and bookings repeat an order's amount, so they are listed but never totalled.
+func IsPostedDocumentType(documentType string) bool {
+	return documentType == DocumentTypeReceipt || documentType == DocumentTypeRefund
+}
+
+// Buckets for emails.bucket (buckets pipeline mode); every email lands in exactly one
+const (
+	BucketNewsletter    = "newsletter"    // Marketing and content emails: archived, scored for interest
+	BucketNotification  = "notification"  // Automated alerts: assessed for severity and urgency
+	BucketHuman         = "human"         // Written by a person: labels, notification and draft as usual
+	BucketTransactional = "transactional" // Receipts, orders, bookings: archived with a timed label
+	BucketSecurity      = "security"      // MFA codes, password resets, login alerts: kept in the inbox and pushed
+	BucketCalendar      = "calendar"      // Invites, updates and cancellations: kept in the inbox
+)
+
+// Buckets lists every bucket, in triage priority order (security wins a tie, newsletter loses it)
+var Buckets = []string{BucketSecurity, BucketCalendar, BucketHuman, BucketTransactional, BucketNotification, BucketNewsletter}
+
+// IsValidBucket returns true for a known bucket
+func IsValidBucket(bucket string) bool {
+	for _, b := range Buckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// BucketPromptType is the system prompt type that customizes a bucket's processor, e.g. "bucket_newsletter"
+func BucketPromptType(bucket string) PromptType {
+	return PromptType("bucket_" + bucket)
+}
+
 // HasPushoverConfig returns true if the user has Pushover credentials configured
 func (u *User) HasPushoverConfig() bool {
 	return u.PushoverUserKey != "" && u.PushoverAppToken != ""
@@ -90,6 +213,7 @@ const (
 	PromptTypeMonthlySummary  PromptType = "monthly_summary"  // Monthly memory consolidation
 	PromptTypeYearlySummary   PromptType = "yearly_summary"   // Yearly memory consolidation
 	PromptTypeWrapUpReport    PromptType = "wrapup_report"    // 8AM & 5PM wrap-up reports
+	PromptTypeBucketTriage    PromptType = "bucket_triage"    // Buckets mode: bucket classification (processors use BucketPromptType)
 )
 
 // Memory represents consolidated learning from email processing
@@ -140,12 +264,14 @@ type SenderProfile struct {
 	EmailCount     int            `db:"email_count" json:"email_count"`
 	EmailsArchived int            `db:"emails_archived" json:"emails_archived"`
 	EmailsNotified int            `db:"emails_notified" json:"emails_notified"`
+	EmailsFastPath int            `db:"emails_fast_path" json:"emails_fast_path"` // Decided by a fast path or the security lane; left out of the decision counters
 	SlugCounts     map[string]int `db:"slug_counts" json:"slug_counts"`
 	LabelCounts    map[string]int `db:"label_counts" json:"label_counts"`
 	KeywordCounts  map[string]int `db:"keyword_counts" json:"keyword_counts"`
 
 	SenderType     string    `db:"sender_type" json:"sender_type"`
 	Summary        string    `db:"summary" json:"summary"`
+	Mixed          bool      `db:"mixed" json:"mixed"` // Set by the user: always triage with the AI, never fast-path
 
 	FirstSeenAt    time.Time `db:"first_seen_at" json:"first_seen_at"`
 	LastSeenAt     time.Time `db:"last_seen_at" json:"last_seen_at"`
@@ -226,20 +352,26 @@ func (p *SenderProfile) TopKeywords(n int) []string {
 	return topN(p.KeywordCounts, n)
 }
 
-// BypassInboxRate returns the fraction of emails that were archived
+// DecidedCount returns the number of emails in the decision counters (slugs, labels, archived,
+// notified): every email except those decided by a fast path or the security lane
+func (p *SenderProfile) DecidedCount() int {
+	return p.EmailCount - p.EmailsFastPath
+}
+
+// BypassInboxRate returns the fraction of decided emails that were archived
 func (p *SenderProfile) BypassInboxRate() float64 {
-	if p.EmailCount == 0 {
+	if p.DecidedCount() <= 0 {
 		return 0
 	}
-	return float64(p.EmailsArchived) / float64(p.EmailCount)
+	return float64(p.EmailsArchived) / float64(p.DecidedCount())
 }
 
-// NotificationRate returns the fraction of emails that triggered notifications
+// NotificationRate returns the fraction of decided emails that triggered notifications
 func (p *SenderProfile) NotificationRate() float64 {
-	if p.EmailCount == 0 {
+	if p.DecidedCount() <= 0 {
 		return 0
 	}
-	return float64(p.EmailsNotified) / float64(p.EmailCount)
+	return float64(p.EmailsNotified) / float64(p.DecidedCount())
 }
 
 // FormatForPrompt produces a concise text block for AI context
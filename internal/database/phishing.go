@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PhishingThreshold is the phishing score at which an email is treated as high risk
+const PhishingThreshold = 0.6
+
+// GetKnownDomains returns the domains the user has received at least minEmails emails from
+func (db *DB) GetKnownDomains(ctx context.Context, userID int64, minEmails int) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT identifier FROM sender_profiles
+		WHERE user_id = $1 AND profile_type = $2 AND email_count >= $3
+	`, userID, ProfileTypeDomain, minEmails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query known domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := make([]string, 0)
+	for rows.Next() {
+		var domain string
+		if err := rows.Scan(&domain); err != nil {
+			return nil, fmt.Errorf("failed to scan known domain: %w", err)
+		}
+		domains = append(domains, domain)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating known domains: %w", err)
+	}
+	return domains, nil
+}
+
+// GetHighRiskEmails returns the emails processed in [since, until) that scored at least
+// PhishingThreshold, riskiest first. Only the sender, subject and risk fields are loaded.
+func (db *DB) GetHighRiskEmails(ctx context.Context, userID int64, since, until time.Time) ([]*Email, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, from_address, subject, phishing_score, phishing_signals, processed_at
+		FROM emails
+		WHERE user_id = $1 AND processed_at >= $2 AND processed_at < $3 AND phishing_score >= $4
+		ORDER BY phishing_score DESC, processed_at DESC
+	`, userID, since, until, PhishingThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query high-risk emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := make([]*Email, 0)
+	for rows.Next() {
+		var email Email
+		var signalsJSON []byte
+		if err := rows.Scan(&email.ID, &email.FromAddress, &email.Subject, &email.PhishingScore, &signalsJSON, &email.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan high-risk email: %w", err)
+		}
+		if err := json.Unmarshal(signalsJSON, &email.PhishingSignals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal phishing signals: %w", err)
+		}
+		email.UserID = userID
+		emails = append(emails, &email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating high-risk emails: %w", err)
+	}
+	return emails, nil
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// RedactionPattern is a user-defined regular expression redacted as [NAME_n]
+type RedactionPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// RedactionSettings controls PII redaction of a user's emails before they are sent to the AI
+type RedactionSettings struct {
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Enabled   bool               `db:"enabled" json:"enabled"`
+	Patterns  []RedactionPattern `db:"patterns" json:"patterns"`
+	Allowlist []string           `db:"allowlist" json:"allowlist"` // Sender addresses or domains sent unredacted
+	UpdatedAt time.Time          `db:"updated_at" json:"updated_at"`
+}
+
+// Allows returns true if the sender address or its domain is on the allowlist
+func (s *RedactionSettings) Allows(fromAddress string) bool {
+	address := strings.ToLower(fromAddress)
+	if start, end := strings.LastIndex(address, "<"), strings.LastIndex(address, ">"); start >= 0 && end > start {
+		address = address[start+1 : end] // "Name <user@example.com>"
+	}
+	domain := ExtractDomain(fromAddress)
+	for _, entry := range s.Allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" && (entry == address || entry == domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor returns the built-in detectors followed by the custom patterns. If a custom pattern is
+// invalid the error is returned with a redactor using the built-in detectors alone.
+func (s *RedactionSettings) Redactor() (*redact.Redactor, error) {
+	patterns := make([]redact.Pattern, len(s.Patterns))
+	for i, pattern := range s.Patterns {
+		patterns[i] = redact.Pattern(pattern)
+	}
+	redactor, err := redact.New(patterns)
+	if err != nil {
+		redactor, _ = redact.New(nil)
+		return redactor, err
+	}
+	return redactor, nil
+}
+
+// GetRedactionSettings returns the user's redaction settings (enabled with no custom patterns if never saved)
+func (db *DB) GetRedactionSettings(ctx context.Context, userID int64) (*RedactionSettings, error) {
+	s := &RedactionSettings{UserID: userID}
+	var patternsJSON, allowlistJSON []byte
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT enabled, patterns, allowlist, updated_at
+		FROM redaction_settings
+		WHERE user_id = $1
+	`, userID).Scan(&s.Enabled, &patternsJSON, &allowlistJSON, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		s.Enabled = true
+		s.Patterns = []RedactionPattern{}
+		s.Allowlist = []string{}
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction settings: %w", err)
+	}
+
+	if err := json.Unmarshal(patternsJSON, &s.Patterns); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal redaction patterns: %w", err)
+	}
+	if err := json.Unmarshal(allowlistJSON, &s.Allowlist); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal redaction allowlist: %w", err)
+	}
+	return s, nil
+}
+
+// SaveRedactionSettings creates or replaces the user's redaction settings
+func (db *DB) SaveRedactionSettings(ctx context.Context, s *RedactionSettings) error {
+	if s.Patterns == nil {
+		s.Patterns = []RedactionPattern{}
+	}
+	if s.Allowlist == nil {
+		s.Allowlist = []string{}
+	}
+	patternsJSON, err := json.Marshal(s.Patterns)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction patterns: %w", err)
+	}
+	allowlistJSON, err := json.Marshal(s.Allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction allowlist: %w", err)
+	}
+
+	s.UpdatedAt = time.Now()
+	_, err = db.conn.ExecContext(ctx, `
+		INSERT INTO redaction_settings (user_id, enabled, patterns, allowlist, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, patterns = EXCLUDED.patterns,
+		    allowlist = EXCLUDED.allowlist, updated_at = EXCLUDED.updated_at
+	`, s.UserID, s.Enabled, patternsJSON, allowlistJSON, s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save redaction settings: %w", err)
+	}
+	return nil
+}
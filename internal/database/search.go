@@ -0,0 +1,361 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// anyTermQuery matches documents containing any of the query's words (plainto_tsquery requires all)
+const anyTermQuery = `to_tsquery('english', replace(plainto_tsquery('english', $%d)::text, ' & ', ' | '))`
+
+// webQuery parses search-box syntax: all words must match, with "quoted phrases", -excluded words and OR
+const webQuery = `websearch_to_tsquery('english', $%d)`
+
+// facetLimit is the number of values returned per facet
+const facetLimit = 20
+
+// EmailSearch filters an archive search; empty fields are ignored
+type EmailSearch struct {
+	Text         string // Full-text query over subject, summary, keywords, slug, reasoning and sender
+	MatchAny     bool   // Match emails containing any word of Text instead of all of them
+	Sender       string // Substring of the from address
+	Domain       string // Exact sender domain
+	Slug         string
+	Label        string
+	Bypassed     *bool
+	Notified     *bool
+	DraftCreated *bool
+	HasFeedback  *bool
+	SenderType   string // Sender profile type (e.g. "newsletter", "human")
+	Bucket       string // Bucket assigned in buckets mode
+	TriageVia    string // How the decision was reached (TriageViaAI or a fast path)
+	After        *time.Time
+	Before       *time.Time
+	Cursor       *EmailCursor // Keyset position for SearchEmailPage
+	Limit        int
+}
+
+// EmailCursor is the position after the last email of a search page (newest first)
+type EmailCursor struct {
+	ProcessedAt time.Time
+	ID          string
+}
+
+// Encode returns the opaque cursor string
+func (c *EmailCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.ProcessedAt.Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// DecodeEmailCursor parses a cursor returned by Encode
+func DecodeEmailCursor(cursor string) (*EmailCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	timestamp, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	processedAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return &EmailCursor{ProcessedAt: processedAt, ID: id}, nil
+}
+
+// EmailPage is one page of search results
+type EmailPage struct {
+	Emails     []*Email     `json:"emails"`
+	NextCursor string       `json:"next_cursor"` // Empty on the last page
+	Facets     *EmailFacets `json:"facets,omitempty"`
+}
+
+// FacetCount is the number of matching emails with a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// EmailFacets holds the most common slugs, labels, domains and buckets among matching emails
+type EmailFacets struct {
+	Slugs   []FacetCount `json:"slugs"`
+	Labels  []FacetCount `json:"labels"`
+	Domains []FacetCount `json:"domains"`
+	Buckets []FacetCount `json:"buckets"` // Only emails processed in buckets mode
+}
+
+// likeEscaper escapes the LIKE wildcards (and the escape character) in a search value
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes a value match literally inside a LIKE pattern
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// filter builds the WHERE clause over emails e (excluding the cursor) and returns the
+// full-text query expression, if any, for ranking
+func (s EmailSearch) filter(userID int64) (string, []interface{}, string) {
+	where := "WHERE e.user_id = $1"
+	args := []interface{}{userID}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += " AND " + fmt.Sprintf(condition, len(args))
+	}
+
+	var textQuery string
+	if s.Text != "" {
+		args = append(args, s.Text)
+		if s.MatchAny {
+			textQuery = fmt.Sprintf(anyTermQuery, len(args))
+		} else {
+			textQuery = fmt.Sprintf(webQuery, len(args))
+		}
+		where += " AND e.search_vector @@ " + textQuery
+	}
+	if s.Sender != "" {
+		add("LOWER(e.from_address) LIKE LOWER($%d)", "%"+escapeLike(s.Sender)+"%")
+	}
+	if s.Domain != "" {
+		add("e.from_domain = LOWER($%d)", s.Domain)
+	}
+	if s.Slug != "" {
+		add("e.slug = $%d", s.Slug)
+	}
+	if s.Label != "" {
+		labelJSON, _ := json.Marshal([]string{s.Label})
+		add("e.labels_applied @> $%d::jsonb", string(labelJSON))
+	}
+	if s.Bypassed != nil {
+		add("e.bypassed_inbox = $%d", *s.Bypassed)
+	}
+	if s.Notified != nil {
+		add("e.notification_sent = $%d", *s.Notified)
+	}
+	if s.DraftCreated != nil {
+		add("COALESCE(e.draft_created, FALSE) = $%d", *s.DraftCreated)
+	}
+	if s.HasFeedback != nil {
+		add("(COALESCE(e.human_feedback, '') != '') = $%d", *s.HasFeedback)
+	}
+	if s.SenderType != "" {
+		add(`EXISTS (
+			SELECT 1 FROM sender_profiles sp
+			WHERE sp.user_id = e.user_id AND sp.profile_type = 'sender'
+			  AND sp.identifier = e.from_address AND sp.sender_type = $%d
+		)`, s.SenderType)
+	}
+	if s.Bucket != "" {
+		add("e.bucket = $%d", s.Bucket)
+	}
+	if s.TriageVia != "" {
+		add("e.triage_via = $%d", s.TriageVia)
+	}
+	if s.After != nil {
+		add("e.processed_at >= $%d", *s.After)
+	}
+	if s.Before != nil {
+		add("e.processed_at < $%d", *s.Before)
+	}
+	return where, args, textQuery
+}
+
+// SearchEmails returns the user's processed emails matching the search, best matches first
+// (most recent first when there is no text query)
+func (db *DB) SearchEmails(ctx context.Context, userID int64, search EmailSearch) ([]*Email, error) {
+	where, args, textQuery := search.filter(userID)
+	orderBy := "e.processed_at DESC"
+	if textQuery != "" {
+		orderBy = fmt.Sprintf("ts_rank(e.search_vector, %s) DESC, e.processed_at DESC", textQuery)
+	}
+
+	args = append(args, search.Limit)
+	query := fmt.Sprintf(`
+		SELECT id, user_id, from_address, subject, slug, keywords, summary,
+		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), notification_sent, processed_at, created_at
+		FROM emails e
+		%s
+		ORDER BY %s
+		LIMIT $%d
+	`, where, orderBy, len(args))
+
+	emails, err := db.scanEmails(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("email search failed: %w", err)
+	}
+	return emails, nil
+}
+
+// SearchEmailPage returns a page of matching emails, newest first, using keyset pagination
+func (db *DB) SearchEmailPage(ctx context.Context, userID int64, search EmailSearch) (*EmailPage, error) {
+	where, args, _ := search.filter(userID)
+	if search.Cursor != nil {
+		args = append(args, search.Cursor.ProcessedAt, search.Cursor.ID)
+		where += fmt.Sprintf(" AND (e.processed_at, e.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, search.Limit+1)
+	query := fmt.Sprintf(`
+		SELECT e.id, e.user_id, e.from_address, e.from_domain, e.subject, e.slug, e.keywords, e.summary,
+		       e.labels_applied, e.bypassed_inbox, e.reasoning, COALESCE(e.human_feedback, ''), COALESCE(e.feedback_dirty, FALSE),
+		       e.notification_sent, COALESCE(e.draft_created, FALSE), e.processed_at, e.created_at,
+		       e.injection_score, e.injection_signals, e.held_notification, e.redaction_count,
+		       e.bucket, e.triage_reasoning, e.severity, e.urgency, e.interesting_score, e.thread_id, e.triage_via,
+		       e.vendor, e.document_type, e.amount, e.currency, e.due_date, e.phishing_score, e.phishing_signals
+		FROM emails e
+		%s
+		ORDER BY e.processed_at DESC, e.id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("email search failed: %w", err)
+	}
+	defer rows.Close()
+
+	page := &EmailPage{Emails: make([]*Email, 0)}
+	for rows.Next() {
+		var email Email
+		var keywordsJSON, labelsJSON, signalsJSON, phishingJSON []byte
+		err := rows.Scan(
+			&email.ID, &email.UserID, &email.FromAddress, &email.FromDomain, &email.Subject, &email.Slug, &keywordsJSON, &email.Summary,
+			&labelsJSON, &email.BypassedInbox, &email.Reasoning, &email.HumanFeedback, &email.FeedbackDirty,
+			&email.NotificationSent, &email.DraftCreated, &email.ProcessedAt, &email.CreatedAt,
+			&email.InjectionScore, &signalsJSON, &email.HeldNotification, &email.RedactionCount,
+			&email.Bucket, &email.TriageReasoning, &email.Severity, &email.Urgency, &email.InterestingScore, &email.ThreadID, &email.TriageVia,
+			&email.Vendor, &email.DocumentType, &email.Amount, &email.Currency, &email.DueDate, &email.PhishingScore, &phishingJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		if err := json.Unmarshal(keywordsJSON, &email.Keywords); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal keywords: %w", err)
+		}
+		if err := json.Unmarshal(labelsJSON, &email.LabelsApplied); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+		}
+		if err := json.Unmarshal(signalsJSON, &email.InjectionSignals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal injection signals: %w", err)
+		}
+		if err := json.Unmarshal(phishingJSON, &email.PhishingSignals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal phishing signals: %w", err)
+		}
+		page.Emails = append(page.Emails, &email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating emails: %w", err)
+	}
+
+	if len(page.Emails) > search.Limit {
+		page.Emails = page.Emails[:search.Limit]
+		last := page.Emails[len(page.Emails)-1]
+		page.NextCursor = (&EmailCursor{ProcessedAt: last.ProcessedAt, ID: last.ID}).Encode()
+	}
+	return page, nil
+}
+
+// GetEmailFacets counts matching emails per slug, label, sender domain and bucket (top values only)
+func (db *DB) GetEmailFacets(ctx context.Context, userID int64, search EmailSearch) (*EmailFacets, error) {
+	search.Cursor = nil
+	where, args, _ := search.filter(userID)
+
+	facets := &EmailFacets{}
+	for _, facet := range []struct {
+		value  string
+		from   string
+		extra  string
+		target *[]FacetCount
+	}{
+		{"e.slug", "emails e", "", &facets.Slugs},
+		{"l.label", "emails e CROSS JOIN LATERAL jsonb_array_elements_text(e.labels_applied) AS l(label)", "", &facets.Labels},
+		{"e.from_domain", "emails e", "", &facets.Domains},
+		{"e.bucket", "emails e", " AND e.bucket != ''", &facets.Buckets},
+	} {
+		rows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+			SELECT %[1]s, COUNT(*)
+			FROM %[2]s
+			%[3]s%[5]s
+			GROUP BY %[1]s
+			ORDER BY COUNT(*) DESC, %[1]s
+			LIMIT %[4]d
+		`, facet.value, facet.from, where, facetLimit, facet.extra), args...)
+		if err != nil {
+			return nil, fmt.Errorf("facet query failed: %w", err)
+		}
+		counts, err := scanFacetCounts(rows)
+		if err != nil {
+			return nil, err
+		}
+		*facet.target = counts
+	}
+	return facets, nil
+}
+
+func scanFacetCounts(rows *sql.Rows) ([]FacetCount, error) {
+	defer rows.Close()
+	counts := make([]FacetCount, 0)
+	for rows.Next() {
+		var c FacetCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating facet counts: %w", err)
+	}
+	return counts, nil
+}
+
+// GetEmailsByIDs returns the user's emails with the given IDs, most recent first
+func (db *DB) GetEmailsByIDs(ctx context.Context, userID int64, ids []string) ([]*Email, error) {
+	if len(ids) == 0 {
+		return []*Email{}, nil
+	}
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal email ids: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, from_address, subject, slug, keywords, summary,
+		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), notification_sent, processed_at, created_at
+		FROM emails
+		WHERE user_id = $1 AND id IN (SELECT jsonb_array_elements_text($2::jsonb))
+		ORDER BY processed_at DESC
+	`
+	return db.scanEmails(ctx, query, userID, idsJSON)
+}
+
+// SearchMemories returns the user's memories matching any word of the text, best matches first
+func (db *DB) SearchMemories(ctx context.Context, userID int64, text string, limit int) ([]*Memory, error) {
+	rows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, user_id, type, content, reasoning, start_date, end_date, created_at
+		FROM memories
+		WHERE user_id = $1 AND to_tsvector('english', content) @@ %[1]s
+		ORDER BY ts_rank(to_tsvector('english', content), %[1]s) DESC, start_date DESC
+		LIMIT $3
+	`, fmt.Sprintf(anyTermQuery, 2)), userID, text, limit)
+	if err != nil {
+		return nil, fmt.Errorf("memory search failed: %w", err)
+	}
+	defer rows.Close()
+
+	var memories []*Memory
+	for rows.Next() {
+		var m Memory
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Type, &m.Content, &m.Reasoning, &m.StartDate, &m.EndDate, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memories = append(memories, &m)
+	}
+
+	return memories, rows.Err()
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Kinds of security email
+const (
+	SecurityKindOTP             = "otp"              // One-time / MFA / verification code
+	SecurityKindPasswordReset   = "password_reset"   // Password reset link or password changed notice
+	SecurityKindNewLogin        = "new_login"        // New sign-in or new device alert
+	SecurityKindAccountRecovery = "account_recovery" // Recovery email/phone changes, recovery requests
+)
+
+// SecurityKinds lists the kinds of security email
+var SecurityKinds = []string{SecurityKindOTP, SecurityKindPasswordReset, SecurityKindNewLogin, SecurityKindAccountRecovery}
+
+// IsValidSecurityKind returns true if the kind is a known security email kind
+func IsValidSecurityKind(kind string) bool {
+	for _, k := range SecurityKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityEvent is a security email handled by the security lane
+type SecurityEvent struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	EmailID     string    `json:"email_id"`
+	Kind        string    `json:"kind"`
+	DetectedBy  string    `json:"detected_by"` // "rules" or "ai"
+	Flagged     bool      `json:"flagged"`     // Unexpected reset or login: unknown sender or likely phishing
+	FromAddress string    `json:"from_address"`
+	Subject     string    `json:"subject"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateSecurityEvent records a security email (once per email)
+func (db *DB) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO security_events (user_id, email_id, kind, detected_by, flagged, from_address, subject, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id, email_id) DO NOTHING
+	`, event.UserID, event.EmailID, event.Kind, event.DetectedBy, event.Flagged, event.FromAddress, event.Subject)
+	if err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+	return nil
+}
+
+// GetSecurityEvents returns a user's security events, newest first (flaggedOnly limits them to flagged ones)
+func (db *DB) GetSecurityEvents(ctx context.Context, userID int64, flaggedOnly bool, limit, offset int) ([]*SecurityEvent, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, user_id, email_id, kind, detected_by, flagged, from_address, subject, created_at
+		FROM security_events
+		WHERE user_id = $1 AND (NOT $2 OR flagged)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, flaggedOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query security events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*SecurityEvent, 0)
+	for rows.Next() {
+		var e SecurityEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.EmailID, &e.Kind, &e.DetectedBy, &e.Flagged, &e.FromAddress, &e.Subject, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan security event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating security events: %w", err)
+	}
+	return events, nil
+}
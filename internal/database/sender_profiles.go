@@ -12,9 +12,9 @@ import (
 func (db *DB) GetSenderProfile(ctx context.Context, userID int64, profileType ProfileType, identifier string) (*SenderProfile, error) {
 	query := `
 		SELECT id, user_id, profile_type, identifier,
-		       email_count, emails_archived, emails_notified,
+		       email_count, emails_archived, emails_notified, emails_fast_path,
 		       slug_counts, label_counts, keyword_counts,
-		       sender_type, summary,
+		       sender_type, summary, mixed,
 		       first_seen_at, last_seen_at, modified_at, created_at
 		FROM sender_profiles
 		WHERE user_id = $1 AND profile_type = $2 AND identifier = $3
@@ -25,9 +25,9 @@ func (db *DB) GetSenderProfile(ctx context.Context, userID int64, profileType Pr
 
 	err := db.conn.QueryRowContext(ctx, query, userID, profileType, identifier).Scan(
 		&p.ID, &p.UserID, &p.ProfileType, &p.Identifier,
-		&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified,
+		&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified, &p.EmailsFastPath,
 		&slugCountsJSON, &labelCountsJSON, &keywordCountsJSON,
-		&p.SenderType, &p.Summary,
+		&p.SenderType, &p.Summary, &p.Mixed,
 		&p.FirstSeenAt, &p.LastSeenAt, &p.ModifiedAt, &p.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -58,9 +58,9 @@ func (db *DB) GetSenderProfile(ctx context.Context, userID int64, profileType Pr
 func (db *DB) GetSenderProfileByID(ctx context.Context, userID, profileID int64) (*SenderProfile, error) {
 	query := `
 		SELECT id, user_id, profile_type, identifier,
-		       email_count, emails_archived, emails_notified,
+		       email_count, emails_archived, emails_notified, emails_fast_path,
 		       slug_counts, label_counts, keyword_counts,
-		       sender_type, summary,
+		       sender_type, summary, mixed,
 		       first_seen_at, last_seen_at, modified_at, created_at
 		FROM sender_profiles
 		WHERE id = $1 AND user_id = $2
@@ -71,9 +71,9 @@ func (db *DB) GetSenderProfileByID(ctx context.Context, userID, profileID int64)
 
 	err := db.conn.QueryRowContext(ctx, query, profileID, userID).Scan(
 		&p.ID, &p.UserID, &p.ProfileType, &p.Identifier,
-		&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified,
+		&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified, &p.EmailsFastPath,
 		&slugCountsJSON, &labelCountsJSON, &keywordCountsJSON,
-		&p.SenderType, &p.Summary,
+		&p.SenderType, &p.Summary, &p.Mixed,
 		&p.FirstSeenAt, &p.LastSeenAt, &p.ModifiedAt, &p.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -118,30 +118,32 @@ func (db *DB) UpsertSenderProfile(ctx context.Context, profile *SenderProfile) e
 	query := `
 		INSERT INTO sender_profiles (
 			user_id, profile_type, identifier,
-			email_count, emails_archived, emails_notified,
+			email_count, emails_archived, emails_notified, emails_fast_path,
 			slug_counts, label_counts, keyword_counts,
-			sender_type, summary,
+			sender_type, summary, mixed,
 			first_seen_at, last_seen_at, modified_at, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())
 		ON CONFLICT (user_id, profile_type, identifier)
 		DO UPDATE SET
 			email_count = EXCLUDED.email_count,
 			emails_archived = EXCLUDED.emails_archived,
 			emails_notified = EXCLUDED.emails_notified,
+			emails_fast_path = EXCLUDED.emails_fast_path,
 			slug_counts = EXCLUDED.slug_counts,
 			label_counts = EXCLUDED.label_counts,
 			keyword_counts = EXCLUDED.keyword_counts,
 			sender_type = EXCLUDED.sender_type,
 			summary = EXCLUDED.summary,
+			mixed = EXCLUDED.mixed,
 			last_seen_at = EXCLUDED.last_seen_at,
 			modified_at = NOW()
 	`
 
 	_, err = db.conn.ExecContext(ctx, query,
 		profile.UserID, profile.ProfileType, profile.Identifier,
-		profile.EmailCount, profile.EmailsArchived, profile.EmailsNotified,
+		profile.EmailCount, profile.EmailsArchived, profile.EmailsNotified, profile.EmailsFastPath,
 		slugCountsJSON, labelCountsJSON, keywordCountsJSON,
-		profile.SenderType, profile.Summary,
+		profile.SenderType, profile.Summary, profile.Mixed,
 		profile.FirstSeenAt, profile.LastSeenAt,
 	)
 	if err != nil {
@@ -179,9 +181,9 @@ func (db *DB) GetAllSenderProfiles(ctx context.Context, userID int64, profileTyp
 	// Fetch paginated results
 	dataQuery := fmt.Sprintf(`
 		SELECT id, user_id, profile_type, identifier,
-		       email_count, emails_archived, emails_notified,
+		       email_count, emails_archived, emails_notified, emails_fast_path,
 		       slug_counts, label_counts, keyword_counts,
-		       sender_type, summary,
+		       sender_type, summary, mixed,
 		       first_seen_at, last_seen_at, modified_at, created_at
 		FROM sender_profiles
 		%s
@@ -203,9 +205,9 @@ func (db *DB) GetAllSenderProfiles(ctx context.Context, userID int64, profileTyp
 
 		err := rows.Scan(
 			&p.ID, &p.UserID, &p.ProfileType, &p.Identifier,
-			&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified,
+			&p.EmailCount, &p.EmailsArchived, &p.EmailsNotified, &p.EmailsFastPath,
 			&slugCountsJSON, &labelCountsJSON, &keywordCountsJSON,
-			&p.SenderType, &p.Summary,
+			&p.SenderType, &p.Summary, &p.Mixed,
 			&p.FirstSeenAt, &p.LastSeenAt, &p.ModifiedAt, &p.CreatedAt,
 		)
 		if err != nil {
@@ -248,26 +250,28 @@ func (db *DB) DeleteStaleProfiles(ctx context.Context) (int64, error) {
 	return result.RowsAffected()
 }
 
-// GetHistoricalEmailsFromAddress returns the last N emails from a specific address
+// GetHistoricalEmailsFromAddress returns the last N emails from a specific address.
+// Fast-path and security-lane decisions are skipped, as in the profile counters (see IsFastPathTriage).
 func (db *DB) GetHistoricalEmailsFromAddress(ctx context.Context, userID int64, address string, limit int) ([]*Email, error) {
 	query := `
 		SELECT id, user_id, from_address, subject, slug, keywords, summary,
 		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), notification_sent, processed_at, created_at
 		FROM emails
-		WHERE user_id = $1 AND from_address = $2
+		WHERE user_id = $1 AND from_address = $2 AND triage_via NOT IN ('thread_reply', 'consistent_sender', 'security')
 		ORDER BY processed_at DESC
 		LIMIT $3
 	`
 	return db.scanEmails(ctx, query, userID, address, limit)
 }
 
-// GetHistoricalEmailsFromDomain returns the last N emails from any address at a domain
+// GetHistoricalEmailsFromDomain returns the last N emails from any address at a domain.
+// Fast-path and security-lane decisions are skipped.
 func (db *DB) GetHistoricalEmailsFromDomain(ctx context.Context, userID int64, domain string, limit int) ([]*Email, error) {
 	query := `
 		SELECT id, user_id, from_address, subject, slug, keywords, summary,
 		       labels_applied, bypassed_inbox, reasoning, COALESCE(human_feedback, ''), notification_sent, processed_at, created_at
 		FROM emails
-		WHERE user_id = $1 AND from_domain = $2
+		WHERE user_id = $1 AND from_domain = $2 AND triage_via NOT IN ('thread_reply', 'consistent_sender', 'security')
 		ORDER BY processed_at DESC
 		LIMIT $3
 	`
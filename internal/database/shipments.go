@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/redact"
+	"github.com/lib/pq"
+)
+
+// Shipment statuses, in the order a package normally moves through them
+const (
+	ShipmentStatusShipped        = "shipped"
+	ShipmentStatusOutForDelivery = "out_for_delivery"
+	ShipmentStatusDelivered      = "delivered"
+	ShipmentStatusException      = "exception" // Delayed, failed delivery attempt, returned or lost
+)
+
+// ShipmentStatuses lists the valid shipment statuses
+var ShipmentStatuses = []string{ShipmentStatusShipped, ShipmentStatusOutForDelivery, ShipmentStatusDelivered, ShipmentStatusException}
+
+// IsValidShipmentStatus returns true if the status is a known shipment status
+func IsValidShipmentStatus(status string) bool {
+	for _, s := range ShipmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoShipmentKey is returned for updates whose order reference or tracking number cannot identify a shipment
+var ErrNoShipmentKey = errors.New("shipment update has no usable order reference or tracking number")
+
+// Shipment is one order's package, built from its shipping notifications
+type Shipment struct {
+	ID             int64           `json:"id"`
+	UserID         int64           `json:"user_id"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"tracking_number"`
+	OrderRef       string          `json:"order_ref"`
+	Vendor         string          `json:"vendor"`
+	Status         string          `json:"status"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Timeline       []ShipmentEvent `json:"timeline"` // Oldest first
+}
+
+// ShipmentEvent is one shipping notification in a shipment's timeline
+type ShipmentEvent struct {
+	EmailID    string    `json:"email_id"`
+	Subject    string    `json:"subject"`
+	Status     string    `json:"status"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ShipmentUpdate is the tracking data extracted from one shipping notification
+type ShipmentUpdate struct {
+	UserID         int64
+	EmailID        string
+	Carrier        string
+	TrackingNumber string
+	OrderRef       string
+	Vendor         string
+	Status         string
+	OccurredAt     time.Time
+}
+
+// key groups updates into shipments: by order reference, or by tracking number when there is none.
+// It is empty when the identifier is missing or still holds a redaction placeholder, which would
+// group unrelated packages together.
+func (u *ShipmentUpdate) key() string {
+	switch {
+	case u.OrderRef != "":
+		if redact.HasPlaceholder(u.OrderRef) {
+			return ""
+		}
+		return "order:" + strings.ToLower(u.OrderRef)
+	case u.TrackingNumber != "" && !redact.HasPlaceholder(u.TrackingNumber):
+		return "tracking:" + strings.ToUpper(u.TrackingNumber)
+	default:
+		return ""
+	}
+}
+
+// mergeShipmentStatus is the shipment's status after an update. A late "shipped" or
+// "out for delivery" email does not undo a delivery.
+func mergeShipmentStatus(current, update string) string {
+	if current == ShipmentStatusDelivered && (update == ShipmentStatusShipped || update == ShipmentStatusOutForDelivery) {
+		return current
+	}
+	return update
+}
+
+// RecordShipmentUpdate adds a shipping notification to its shipment's timeline, creating the
+// shipment on its first update. Returns the shipment ID and whether this update delivered it.
+func (db *DB) RecordShipmentUpdate(ctx context.Context, update *ShipmentUpdate) (int64, bool, error) {
+	key := update.key()
+	if key == "" {
+		return 0, false, ErrNoShipmentKey
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deliveredAt *time.Time
+	if update.Status == ShipmentStatusDelivered {
+		deliveredAt = &update.OccurredAt
+	}
+
+	var shipmentID int64
+	previous := ""
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO shipments (user_id, shipment_key, carrier, tracking_number, order_ref, vendor, status, delivered_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (user_id, shipment_key) DO NOTHING
+		RETURNING id
+	`, update.UserID, key, update.Carrier, update.TrackingNumber, update.OrderRef, update.Vendor, update.Status, deliveredAt).Scan(&shipmentID)
+	if err == sql.ErrNoRows {
+		// Existing shipment: fill in newly known fields and move its status on
+		err = tx.QueryRowContext(ctx, `
+			SELECT id, status FROM shipments WHERE user_id = $1 AND shipment_key = $2 FOR UPDATE
+		`, update.UserID, key).Scan(&shipmentID, &previous)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to load shipment: %w", err)
+		}
+		status := mergeShipmentStatus(previous, update.Status)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE shipments SET
+				carrier = CASE WHEN $2 != '' THEN $2 ELSE carrier END,
+				tracking_number = CASE WHEN $3 != '' THEN $3 ELSE tracking_number END,
+				vendor = CASE WHEN $4 != '' THEN $4 ELSE vendor END,
+				status = $5,
+				delivered_at = CASE WHEN $5 = 'delivered' THEN COALESCE(delivered_at, $6) ELSE NULL END,
+				updated_at = NOW()
+			WHERE id = $1
+		`, shipmentID, update.Carrier, update.TrackingNumber, update.Vendor, status, update.OccurredAt)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to update shipment: %w", err)
+		}
+	} else if err != nil {
+		return 0, false, fmt.Errorf("failed to create shipment: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO shipment_events (shipment_id, user_id, email_id, status, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (shipment_id, email_id) DO NOTHING
+	`, shipmentID, update.UserID, update.EmailID, update.Status, update.OccurredAt)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record shipment event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit shipment update: %w", err)
+	}
+	delivered := update.Status == ShipmentStatusDelivered && previous != ShipmentStatusDelivered
+	return shipmentID, delivered, nil
+}
+
+// GetShipments returns a user's shipments with their timelines, most recently updated first.
+// status filters by shipment status ("" = all; "active" = not yet delivered).
+func (db *DB) GetShipments(ctx context.Context, userID int64, status string, limit, offset int) ([]*Shipment, error) {
+	query := `
+		SELECT id, user_id, carrier, tracking_number, order_ref, vendor, status, delivered_at, created_at, updated_at
+		FROM shipments
+		WHERE user_id = $1 AND ($2 = '' OR status = $2 OR ($2 = 'active' AND status != 'delivered'))
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := db.conn.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipments: %w", err)
+	}
+	defer rows.Close()
+
+	shipments := make([]*Shipment, 0)
+	byID := map[int64]*Shipment{}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var s Shipment
+		err := rows.Scan(&s.ID, &s.UserID, &s.Carrier, &s.TrackingNumber, &s.OrderRef, &s.Vendor, &s.Status, &s.DeliveredAt, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		s.Timeline = []ShipmentEvent{}
+		shipments = append(shipments, &s)
+		byID[s.ID] = &s
+		ids = append(ids, s.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipments: %w", err)
+	}
+	if len(ids) == 0 {
+		return shipments, nil
+	}
+
+	eventRows, err := db.conn.QueryContext(ctx, `
+		SELECT se.shipment_id, se.email_id, COALESCE(e.subject, ''), se.status, se.occurred_at
+		FROM shipment_events se
+		LEFT JOIN emails e ON e.id = se.email_id AND e.user_id = se.user_id
+		WHERE se.shipment_id = ANY($1)
+		ORDER BY se.occurred_at, se.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipment events: %w", err)
+	}
+	defer eventRows.Close()
+	for eventRows.Next() {
+		var shipmentID int64
+		var e ShipmentEvent
+		if err := eventRows.Scan(&shipmentID, &e.EmailID, &e.Subject, &e.Status, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment event: %w", err)
+		}
+		if s := byID[shipmentID]; s != nil {
+			s.Timeline = append(s.Timeline, e)
+		}
+	}
+	if err := eventRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipment events: %w", err)
+	}
+	return shipments, nil
+}
+
+// GetShipmentEmailIDs returns the IDs of the emails in a shipment's timeline
+func (db *DB) GetShipmentEmailIDs(ctx context.Context, userID, shipmentID int64) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT email_id FROM shipment_events WHERE user_id = $1 AND shipment_id = $2 ORDER BY occurred_at
+	`, userID, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipment emails: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment email: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipment emails: %w", err)
+	}
+	return ids, nil
+}
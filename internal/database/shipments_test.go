@@ -0,0 +1,24 @@
+package database
+
+import "testing"
+
+func TestShipmentUpdateKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		update ShipmentUpdate
+		want   string
+	}{
+		{"order reference", ShipmentUpdate{OrderRef: "AB-112", TrackingNumber: "1Z999AA10123456784"}, "order:ab-112"},
+		{"tracking number", ShipmentUpdate{TrackingNumber: "774912345678"}, "tracking:774912345678"},
+		{"no identifier", ShipmentUpdate{}, ""},
+		{"placeholder tracking number", ShipmentUpdate{TrackingNumber: "[PHONE_1]"}, ""},
+		{"placeholder order reference", ShipmentUpdate{OrderRef: "[ORDER_1]", TrackingNumber: "774912345678"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.update.key(); got != tt.want {
+				t.Errorf("key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -35,6 +35,15 @@ type KeywordStat struct {
 	Count   int    `json:"count"`
 }
 
+type BucketStat struct {
+	Bucket           string  `json:"bucket"`
+	Count            int     `json:"count"`
+	Today            int     `json:"today"`
+	ThisWeek         int     `json:"this_week"`
+	ArchiveRate      float64 `json:"archive_rate"`
+	NotificationRate float64 `json:"notification_rate"`
+}
+
 type DashboardSummary struct {
 	TotalEmails      int     `json:"total_emails"`
 	EmailsToday      int     `json:"emails_today"`
@@ -48,6 +57,7 @@ type DashboardSummary struct {
 	TopSlugs    []SlugStat    `json:"top_slugs"`
 	LabelDist   []LabelStat   `json:"label_distribution"`
 	TopKeywords []KeywordStat `json:"top_keywords"`
+	BucketDist  []BucketStat  `json:"bucket_distribution"` // Emails processed in buckets mode
 
 	NewSlugsThisWeek       int `json:"new_slugs_this_week"`
 	RecurringSlugsThisWeek int `json:"recurring_slugs_this_week"`
@@ -221,6 +231,31 @@ func (db *DB) GetDashboardSummary(ctx context.Context, userID int64) (*Dashboard
 		s.TopKeywords = []KeywordStat{}
 	}
 
+	// Bucket distribution (buckets pipeline mode only)
+	rows6, err := db.conn.QueryContext(ctx, `
+		SELECT bucket, COUNT(*) as cnt,
+			COUNT(*) FILTER (WHERE processed_at >= $2),
+			COUNT(*) FILTER (WHERE processed_at >= $3),
+			AVG(CASE WHEN bypassed_inbox THEN 1.0 ELSE 0.0 END),
+			AVG(CASE WHEN notification_sent THEN 1.0 ELSE 0.0 END)
+		FROM emails WHERE user_id = $1 AND bucket != ''
+		GROUP BY bucket ORDER BY cnt DESC
+	`, userID, todayStart, weekStart)
+	if err != nil {
+		return nil, fmt.Errorf("bucket distribution query failed: %w", err)
+	}
+	defer rows6.Close()
+	for rows6.Next() {
+		var bs BucketStat
+		if err := rows6.Scan(&bs.Bucket, &bs.Count, &bs.Today, &bs.ThisWeek, &bs.ArchiveRate, &bs.NotificationRate); err != nil {
+			return nil, fmt.Errorf("bucket distribution scan failed: %w", err)
+		}
+		s.BucketDist = append(s.BucketDist, bs)
+	}
+	if s.BucketDist == nil {
+		s.BucketDist = []BucketStat{}
+	}
+
 	// New vs recurring slugs this week
 	err = db.conn.QueryRowContext(ctx, `
 		WITH this_week AS (
@@ -368,3 +403,35 @@ func (db *DB) GetDashboardTimeseries(ctx context.Context, userID int64, days int
 
 	return ts, nil
 }
+
+// FastPathStats reports how many emails were decided without the AI by a fast path
+type FastPathStats struct {
+	Emails           int     `json:"emails"`
+	ThreadReply      int     `json:"thread_reply"`
+	ConsistentSender int     `json:"consistent_sender"`
+	Rate             float64 `json:"rate"`
+	TokensSaved      int     `json:"tokens_saved"` // Estimate: fast-path emails times the average tokens of an AI-triaged email
+}
+
+// GetFastPathStats returns the fast-path counts and estimated token savings for emails processed since the given time
+func (db *DB) GetFastPathStats(ctx context.Context, userID int64, since time.Time) (*FastPathStats, error) {
+	stats := &FastPathStats{}
+	var avgTokens float64
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE triage_via = $3),
+		       COUNT(*) FILTER (WHERE triage_via = $4),
+		       COALESCE(AVG(prompt_tokens + completion_tokens) FILTER (WHERE triage_via = $5), 0)
+		FROM emails
+		WHERE user_id = $1 AND processed_at >= $2
+	`, userID, since, TriageViaThreadReply, TriageViaConsistentSender, TriageViaAI).Scan(&stats.Emails, &stats.ThreadReply, &stats.ConsistentSender, &avgTokens)
+	if err != nil {
+		return nil, fmt.Errorf("fast path stats query failed: %w", err)
+	}
+	fast := stats.ThreadReply + stats.ConsistentSender
+	if stats.Emails > 0 {
+		stats.Rate = float64(fast) / float64(stats.Emails)
+	}
+	stats.TokensSaved = int(float64(fast) * avgTokens)
+	return stats, nil
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Subscription suggestions
+const (
+	SubscriptionSuggestUnsubscribe = "unsubscribe" // High volume, never read
+	SubscriptionSuggestTimedLabel  = "timed_label" // Mostly archived, read now and then
+)
+
+// SubscriptionSuggestedLabel is the label policy suggested for lists that are read only occasionally
+const SubscriptionSuggestedLabel = "🗑️/1m"
+
+// Subscription is a mailing list, or a sender the user is subscribed to, with its volume and engagement
+type Subscription struct {
+	ID                int64      `json:"id"`
+	UserID            int64      `json:"user_id"`
+	ListKey           string     `json:"list_key"` // List-Id, or the sender address when there is none
+	ListID            string     `json:"list_id"`
+	Name              string     `json:"name"`
+	FromAddress       string     `json:"from_address"`
+	FromDomain        string     `json:"from_domain"`
+	UnsubscribeURL    string     `json:"unsubscribe_url"`
+	UnsubscribeMailto string     `json:"unsubscribe_mailto"`
+	OneClick          bool       `json:"one_click"` // The URL supports one-click unsubscribe (RFC 8058)
+	EmailCount        int        `json:"email_count"`
+	EmailsArchived    int        `json:"emails_archived"`
+	EmailsNotified    int        `json:"emails_notified"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	LabelPolicy       string     `json:"label_policy"` // Timed label added to every email, "" for none
+	FirstSeenAt       time.Time  `json:"first_seen_at"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+
+	// Computed by GetSubscriptions
+	EmailsLast30Days int     `json:"emails_last_30_days"`
+	ArchiveRate      float64 `json:"archive_rate"`
+	FeedbackCount    int     `json:"feedback_count"`   // Emails the user left feedback on
+	UnarchivedCount  int     `json:"unarchived_count"` // Emails the user moved back to the inbox
+	NoiseScore       float64 `json:"noise_score"`      // Monthly volume weighted by how little of it is read
+	Suggestion       string  `json:"suggestion"`       // SubscriptionSuggest*, "" to keep as is
+	SuggestedLabel   string  `json:"suggested_label,omitempty"`
+	SuggestionReason string  `json:"suggestion_reason,omitempty"`
+}
+
+// SubscriptionEmail is one email from a subscription, as recorded by RecordSubscriptionEmail
+type SubscriptionEmail struct {
+	UserID            int64
+	ListKey           string
+	ListID            string
+	Name              string
+	FromAddress       string
+	FromDomain        string
+	UnsubscribeURL    string
+	UnsubscribeMailto string
+	OneClick          bool
+	Archived          bool
+	Notified          bool
+}
+
+// RecordSubscriptionEmail counts an email against its subscription, creating the subscription on
+// its first email. Returns the subscription's ID and label policy.
+func (db *DB) RecordSubscriptionEmail(ctx context.Context, e *SubscriptionEmail) (int64, string, error) {
+	var id int64
+	var labelPolicy string
+	err := db.conn.QueryRowContext(ctx, `
+		INSERT INTO subscriptions (user_id, list_key, list_id, name, from_address, from_domain, unsubscribe_url, unsubscribe_mailto, one_click,
+		                           email_count, emails_archived, emails_notified, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1, $10, $11, NOW(), NOW())
+		ON CONFLICT (user_id, list_key) DO UPDATE SET
+			list_id = EXCLUDED.list_id,
+			name = CASE WHEN EXCLUDED.name <> '' THEN EXCLUDED.name ELSE subscriptions.name END,
+			from_address = EXCLUDED.from_address,
+			from_domain = EXCLUDED.from_domain,
+			unsubscribe_url = CASE WHEN EXCLUDED.unsubscribe_url <> '' THEN EXCLUDED.unsubscribe_url ELSE subscriptions.unsubscribe_url END,
+			unsubscribe_mailto = CASE WHEN EXCLUDED.unsubscribe_mailto <> '' THEN EXCLUDED.unsubscribe_mailto ELSE subscriptions.unsubscribe_mailto END,
+			one_click = CASE WHEN EXCLUDED.unsubscribe_url <> '' THEN EXCLUDED.one_click ELSE subscriptions.one_click END,
+			email_count = subscriptions.email_count + 1,
+			emails_archived = subscriptions.emails_archived + EXCLUDED.emails_archived,
+			emails_notified = subscriptions.emails_notified + EXCLUDED.emails_notified,
+			last_seen_at = NOW()
+		RETURNING id, label_policy
+	`, e.UserID, e.ListKey, e.ListID, e.Name, e.FromAddress, e.FromDomain, e.UnsubscribeURL, e.UnsubscribeMailto, e.OneClick,
+		boolToInt(e.Archived), boolToInt(e.Notified)).Scan(&id, &labelPolicy)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to record subscription email: %w", err)
+	}
+	return id, labelPolicy, nil
+}
+
+// boolToInt returns 1 for true and 0 for false
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// GetSubscriptions returns a user's subscriptions ranked by noise, noisiest first, each with a suggestion
+func (db *DB) GetSubscriptions(ctx context.Context, userID int64) ([]*Subscription, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT s.id, s.user_id, s.list_key, s.list_id, s.name, s.from_address, s.from_domain,
+		       s.unsubscribe_url, s.unsubscribe_mailto, s.one_click,
+		       s.email_count, s.emails_archived, s.emails_notified, s.last_read_at, s.label_policy, s.first_seen_at, s.last_seen_at,
+		       COUNT(e.id) FILTER (WHERE e.processed_at >= NOW() - INTERVAL '30 days'),
+		       COUNT(e.id) FILTER (WHERE COALESCE(e.human_feedback, '') <> ''),
+		       COUNT(e.id) FILTER (WHERE e.user_unarchived)
+		FROM subscriptions s
+		LEFT JOIN emails e ON e.subscription_id = s.id
+		WHERE s.user_id = $1
+		GROUP BY s.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	subscriptions := make([]*Subscription, 0)
+	for rows.Next() {
+		var s Subscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.ListKey, &s.ListID, &s.Name, &s.FromAddress, &s.FromDomain,
+			&s.UnsubscribeURL, &s.UnsubscribeMailto, &s.OneClick,
+			&s.EmailCount, &s.EmailsArchived, &s.EmailsNotified, &s.LastReadAt, &s.LabelPolicy, &s.FirstSeenAt, &s.LastSeenAt,
+			&s.EmailsLast30Days, &s.FeedbackCount, &s.UnarchivedCount); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		s.rank(now)
+		subscriptions = append(subscriptions, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		if subscriptions[i].NoiseScore != subscriptions[j].NoiseScore {
+			return subscriptions[i].NoiseScore > subscriptions[j].NoiseScore
+		}
+		return subscriptions[i].EmailsLast30Days > subscriptions[j].EmailsLast30Days
+	})
+	return subscriptions, nil
+}
+
+// engagement is how much of a subscription the user still reads (0-1): recent reads count most,
+// and moving an email back to the inbox shows the list matters
+func (s *Subscription) engagement(now time.Time) float64 {
+	engagement := 0.0
+	if s.LastReadAt != nil {
+		switch age := now.Sub(*s.LastReadAt); {
+		case age <= 7*24*time.Hour:
+			engagement = 0.8
+		case age <= 30*24*time.Hour:
+			engagement = 0.5
+		case age <= 90*24*time.Hour:
+			engagement = 0.2
+		}
+	}
+	if s.UnarchivedCount > 0 {
+		engagement = math.Max(engagement, 0.5)
+	}
+	return engagement
+}
+
+// rank computes the archive rate, noise score and suggestion.
+// Noise is the last 30 days' volume, scaled down by engagement and up by how much is archived unread.
+func (s *Subscription) rank(now time.Time) {
+	if s.EmailCount > 0 {
+		s.ArchiveRate = float64(s.EmailsArchived) / float64(s.EmailCount)
+	}
+	engagement := s.engagement(now)
+	noise := float64(s.EmailsLast30Days) * (1 - engagement) * (0.5 + 0.5*s.ArchiveRate)
+	s.NoiseScore = math.Round(noise*100) / 100
+
+	readRecently := s.LastReadAt != nil && now.Sub(*s.LastReadAt) <= 30*24*time.Hour
+	switch {
+	case s.EmailsLast30Days >= 4 && !readRecently && s.UnarchivedCount == 0 && s.EmailsNotified == 0:
+		s.Suggestion = SubscriptionSuggestUnsubscribe
+		if s.LastReadAt == nil {
+			s.SuggestionReason = fmt.Sprintf("%d emails in the last 30 days, none read", s.EmailsLast30Days)
+		} else {
+			s.SuggestionReason = fmt.Sprintf("%d emails in the last 30 days, last read %s", s.EmailsLast30Days, s.LastReadAt.Format("2006-01-02"))
+		}
+	case s.LabelPolicy == "" && s.EmailsLast30Days >= 2 && s.ArchiveRate >= 0.8 && engagement > 0 && engagement < 0.8:
+		s.Suggestion = SubscriptionSuggestTimedLabel
+		s.SuggestedLabel = SubscriptionSuggestedLabel
+		s.SuggestionReason = fmt.Sprintf("%.0f%% archived and read only occasionally", s.ArchiveRate*100)
+	}
+}
+
+// SetSubscriptionLabelPolicy sets the timed label added to every future email of the given subscriptions
+// ("" clears it). Returns the number of subscriptions updated.
+func (db *DB) SetSubscriptionLabelPolicy(ctx context.Context, userID int64, ids []int64, label string) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE subscriptions SET label_policy = $3
+		WHERE user_id = $1 AND id = ANY($2)
+	`, userID, pq.Array(ids), label)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set subscription label policy: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get updated subscriptions: %w", err)
+	}
+	return updated, nil
+}
+
+// MarkSubscriptionsRead records the given emails as read on their subscriptions: each subscription's
+// last read time moves forward to the newest of its emails in the list. Returns the number of
+// subscriptions updated.
+func (db *DB) MarkSubscriptionsRead(ctx context.Context, userID int64, emailIDs []string) (int64, error) {
+	if len(emailIDs) == 0 {
+		return 0, nil
+	}
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE subscriptions s
+		SET last_read_at = r.read_at
+		FROM (
+			SELECT subscription_id, MAX(processed_at) AS read_at
+			FROM emails
+			WHERE user_id = $1 AND id = ANY($2) AND subscription_id IS NOT NULL
+			GROUP BY subscription_id
+		) r
+		WHERE s.id = r.subscription_id AND s.user_id = $1
+		  AND (s.last_read_at IS NULL OR s.last_read_at < r.read_at)
+	`, userID, pq.Array(emailIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark subscriptions read: %w", err)
+	}
+	return result.RowsAffected()
+}
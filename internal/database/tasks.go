@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Task is an action item extracted from an email
+type Task struct {
+	ID           int64      `db:"id" json:"id"`
+	UserID       int64      `db:"user_id" json:"user_id"`
+	EmailID      string     `db:"email_id" json:"email_id"`
+	Description  string     `db:"description" json:"description"`
+	DueDate      *time.Time `db:"due_date" json:"due_date"`
+	CompletedAt  *time.Time `db:"completed_at" json:"completed_at"`
+	SnoozedUntil *time.Time `db:"snoozed_until" json:"snoozed_until"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+	// From the source email
+	EmailFrom    string `json:"email_from"`
+	EmailSubject string `json:"email_subject"`
+}
+
+// Task list statuses for GetTasks
+const (
+	TaskStatusOpen    = "open"    // Not completed and not snoozed
+	TaskStatusSnoozed = "snoozed" // Not completed, snoozed until a later time
+	TaskStatusDone    = "done"
+	TaskStatusAll     = "all"
+)
+
+// TaskStatuses lists the valid task list statuses
+var TaskStatuses = []string{TaskStatusOpen, TaskStatusSnoozed, TaskStatusDone, TaskStatusAll}
+
+// IsValidTaskStatus returns true if the status is a known task list status
+func IsValidTaskStatus(status string) bool {
+	for _, s := range TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTasks stores the action items extracted from one email.
+// Tasks the email already has (same description) are skipped, so reprocessing adds no duplicates.
+func (db *DB) CreateTasks(ctx context.Context, tasks []*Task) error {
+	for _, t := range tasks {
+		err := db.conn.QueryRowContext(ctx, `
+			INSERT INTO tasks (user_id, email_id, description, due_date, created_at, updated_at)
+			SELECT $1, $2, $3, $4, NOW(), NOW()
+			WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE user_id = $1 AND email_id = $2 AND description = $3)
+			RETURNING id, created_at, updated_at
+		`, t.UserID, t.EmailID, t.Description, t.DueDate).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetTasks returns a user's tasks with the given status. Open and snoozed tasks are ordered by
+// due date (undated last); done tasks by completion, most recent first. limit 0 = no limit.
+func (db *DB) GetTasks(ctx context.Context, userID int64, status string, limit, offset int) ([]*Task, error) {
+	where := "t.user_id = $1"
+	order := "t.due_date NULLS LAST, t.created_at"
+	switch status {
+	case TaskStatusOpen:
+		where += " AND t.completed_at IS NULL AND (t.snoozed_until IS NULL OR t.snoozed_until <= NOW())"
+	case TaskStatusSnoozed:
+		where += " AND t.completed_at IS NULL AND t.snoozed_until > NOW()"
+		order = "t.snoozed_until, t.due_date NULLS LAST"
+	case TaskStatusDone:
+		where += " AND t.completed_at IS NOT NULL"
+		order = "t.completed_at DESC"
+	case TaskStatusAll:
+		order = "t.completed_at IS NOT NULL, t.due_date NULLS LAST, t.created_at"
+	default:
+		return nil, fmt.Errorf("unknown task status %q", status)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.user_id, t.email_id, t.description, t.due_date, t.completed_at, t.snoozed_until,
+		       t.created_at, t.updated_at, COALESCE(e.from_address, ''), COALESCE(e.subject, '')
+		FROM tasks t
+		LEFT JOIN emails e ON e.id = t.email_id AND e.user_id = t.user_id
+		WHERE %s
+		ORDER BY %s, t.id
+	`, where, order)
+	args := []interface{}{userID}
+	if limit > 0 {
+		query += " LIMIT $2 OFFSET $3"
+		args = append(args, limit, offset)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*Task, 0)
+	for rows.Next() {
+		var t Task
+		err := rows.Scan(&t.ID, &t.UserID, &t.EmailID, &t.Description, &t.DueDate, &t.CompletedAt, &t.SnoozedUntil,
+			&t.CreatedAt, &t.UpdatedAt, &t.EmailFrom, &t.EmailSubject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// CompleteTask marks a task done, or reopens it when completed is false.
+// Returns false if the task does not exist or belongs to another user.
+func (db *DB) CompleteTask(ctx context.Context, userID, taskID int64, completed bool) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE tasks
+		SET completed_at = CASE WHEN $3 THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+		    snoozed_until = NULL,
+		    updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, taskID, userID, completed)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// SnoozeTask hides an open task until the given time (nil unsnoozes it).
+// Returns false if the task does not exist, belongs to another user or is already done.
+func (db *DB) SnoozeTask(ctx context.Context, userID, taskID int64, until *time.Time) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE tasks
+		SET snoozed_until = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND completed_at IS NULL
+	`, taskID, userID, until)
+	if err != nil {
+		return false, fmt.Errorf("failed to snooze task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Transaction is a transactional email's extracted data, as listed in the expense ledger
+type Transaction struct {
+	EmailID      string     `json:"email_id"`
+	FromAddress  string     `json:"from_address"`
+	Subject      string     `json:"subject"`
+	Vendor       string     `json:"vendor"`
+	DocumentType string     `json:"document_type"`
+	Amount       *float64   `json:"amount"`
+	Currency     string     `json:"currency"`
+	DueDate      *time.Time `json:"due_date"`
+	ProcessedAt  time.Time  `json:"processed_at"`
+}
+
+// TransactionFilter narrows the ledger; empty fields are ignored
+type TransactionFilter struct {
+	Vendor       string // Exact vendor (case-insensitive)
+	DocumentType string
+	Currency     string
+	After        *time.Time
+	Before       *time.Time
+	Limit        int // 0 = no limit
+	Offset       int
+}
+
+// VendorTotal sums a vendor's transactions in one currency
+type VendorTotal struct {
+	Vendor   string  `json:"vendor"`
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Total    float64 `json:"total"`
+}
+
+// MonthTotal sums a month's transactions in one currency
+type MonthTotal struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Total    float64 `json:"total"`
+}
+
+// TransactionTotals are the ledger totals by vendor and by month. Only receipts and refunds are
+// summed (refunds negative, see IsPostedDocumentType); invoices, shipping notices and bookings
+// only add to the counts.
+type TransactionTotals struct {
+	Count    int           `json:"count"`
+	ByVendor []VendorTotal `json:"by_vendor"`
+	ByMonth  []MonthTotal  `json:"by_month"`
+}
+
+// where builds the WHERE clause shared by the ledger queries
+func (f TransactionFilter) where(userID int64) (string, []interface{}) {
+	where := "WHERE user_id = $1 AND document_type != ''"
+	args := []interface{}{userID}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += " AND " + fmt.Sprintf(condition, len(args))
+	}
+	if f.Vendor != "" {
+		add("LOWER(vendor) = LOWER($%d)", f.Vendor)
+	}
+	if f.DocumentType != "" {
+		add("document_type = $%d", f.DocumentType)
+	}
+	if f.Currency != "" {
+		add("currency = UPPER($%d)", f.Currency)
+	}
+	if f.After != nil {
+		add("processed_at >= $%d", *f.After)
+	}
+	if f.Before != nil {
+		add("processed_at < $%d", *f.Before)
+	}
+	return where, args
+}
+
+// signedAmount is the amount a transaction adds to the totals: receipts count positive, refunds
+// negative, and other document types not at all (NULL is skipped by SUM)
+const signedAmount = `CASE document_type WHEN 'receipt' THEN amount WHEN 'refund' THEN -amount END`
+
+// GetTransactions returns transactional emails matching the filter, newest first
+func (db *DB) GetTransactions(ctx context.Context, userID int64, filter TransactionFilter) ([]*Transaction, error) {
+	where, args := filter.where(userID)
+	query := fmt.Sprintf(`
+		SELECT id, from_address, subject, vendor, document_type, amount, currency, due_date, processed_at
+		FROM emails
+		%s
+		ORDER BY processed_at DESC, id DESC
+	`, where)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]*Transaction, 0)
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.EmailID, &t.FromAddress, &t.Subject, &t.Vendor, &t.DocumentType, &t.Amount, &t.Currency, &t.DueDate, &t.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// GetTransactionTotals returns the count of matching transactions and their totals by vendor and by month
+func (db *DB) GetTransactionTotals(ctx context.Context, userID int64, filter TransactionFilter) (*TransactionTotals, error) {
+	where, args := filter.where(userID)
+	totals := &TransactionTotals{ByVendor: []VendorTotal{}, ByMonth: []MonthTotal{}}
+
+	if err := db.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM emails "+where, args...).Scan(&totals.Count); err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT vendor, currency, COUNT(*), COALESCE(SUM(%s), 0)
+		FROM emails
+		%s
+		GROUP BY vendor, currency
+		ORDER BY COALESCE(SUM(%s), 0) DESC, vendor
+	`, signedAmount, where, signedAmount), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vendor totals: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v VendorTotal
+		if err := rows.Scan(&v.Vendor, &v.Currency, &v.Count, &v.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan vendor total: %w", err)
+		}
+		totals.ByVendor = append(totals.ByVendor, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vendor totals: %w", err)
+	}
+
+	monthRows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT TO_CHAR(processed_at, 'YYYY-MM') AS month, currency, COUNT(*), COALESCE(SUM(%s), 0)
+		FROM emails
+		%s
+		GROUP BY month, currency
+		ORDER BY month DESC, currency
+	`, signedAmount, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly totals: %w", err)
+	}
+	defer monthRows.Close()
+	for monthRows.Next() {
+		var m MonthTotal
+		if err := monthRows.Scan(&m.Month, &m.Currency, &m.Count, &m.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly total: %w", err)
+		}
+		totals.ByMonth = append(totals.ByMonth, m)
+	}
+	if err := monthRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monthly totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// IsTransactionalSender returns true if any email from the address was a receipt, invoice or
+// other transaction, or a shipping update
+func (db *DB) IsTransactionalSender(ctx context.Context, userID int64, fromAddress string) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM emails e
+			WHERE e.user_id = $1 AND e.from_address = $2
+			  AND (e.document_type != '' OR EXISTS(SELECT 1 FROM shipment_events se WHERE se.user_id = $1 AND se.email_id = e.id))
+		)
+	`, userID, fromAddress).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check sender transactions: %w", err)
+	}
+	return exists, nil
+}
@@ -11,14 +11,16 @@ import (
 // CreateUser creates a new user with OAuth tokens
 func (db *DB) CreateUser(ctx context.Context, email, googleID string, token *oauth2.Token) (*User, error) {
 	user := &User{
-		Email:        email,
-		GoogleID:     googleID,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenExpiry:  token.Expiry,
-		IsActive:     false,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		Email:                email,
+		GoogleID:             googleID,
+		AccessToken:          token.AccessToken,
+		RefreshToken:         token.RefreshToken,
+		TokenExpiry:          token.Expiry,
+		IsActive:             false,
+		PipelineMode:         PipelineModeTwoStage,
+		DecisionCacheEnabled: true,
+		CreatedAt:            time.Now(),
+		UpdatedAt:            time.Now(),
 	}
 
 	query := `
@@ -57,7 +59,7 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	user := &User{}
 
 	query := `
-		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, created_at, updated_at
+		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, daily_budget_usd, monthly_budget_usd, pipeline_mode, decision_cache_enabled, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -76,6 +78,10 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 		&user.WebhookURL,
 		&user.WebhookHeaderKey,
 		&user.WebhookHeaderValue,
+		&user.DailyBudgetUSD,
+		&user.MonthlyBudgetUSD,
+		&user.PipelineMode,
+		&user.DecisionCacheEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -92,7 +98,7 @@ func (db *DB) GetUserByGoogleID(ctx context.Context, googleID string) (*User, er
 	user := &User{}
 
 	query := `
-		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, created_at, updated_at
+		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, daily_budget_usd, monthly_budget_usd, pipeline_mode, decision_cache_enabled, created_at, updated_at
 		FROM users
 		WHERE google_id = $1
 	`
@@ -111,6 +117,10 @@ func (db *DB) GetUserByGoogleID(ctx context.Context, googleID string) (*User, er
 		&user.WebhookURL,
 		&user.WebhookHeaderKey,
 		&user.WebhookHeaderValue,
+		&user.DailyBudgetUSD,
+		&user.MonthlyBudgetUSD,
+		&user.PipelineMode,
+		&user.DecisionCacheEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -150,7 +160,7 @@ func (db *DB) UpdateUserToken(ctx context.Context, userID int64, token *oauth2.T
 // GetAllActiveUsers retrieves all users with monitoring enabled
 func (db *DB) GetAllActiveUsers(ctx context.Context) ([]*User, error) {
 	query := `
-		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, created_at, updated_at
+		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, daily_budget_usd, monthly_budget_usd, pipeline_mode, decision_cache_enabled, created_at, updated_at
 		FROM users
 		WHERE is_active = true
 		ORDER BY created_at ASC
@@ -179,6 +189,10 @@ func (db *DB) GetAllActiveUsers(ctx context.Context) ([]*User, error) {
 			&user.WebhookURL,
 			&user.WebhookHeaderKey,
 			&user.WebhookHeaderValue,
+			&user.DailyBudgetUSD,
+			&user.MonthlyBudgetUSD,
+			&user.PipelineMode,
+			&user.DecisionCacheEnabled,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -240,7 +254,7 @@ func (u *User) GetOAuth2Token() *oauth2.Token {
 // GetActiveUsers retrieves all active users
 func (db *DB) GetActiveUsers(ctx context.Context) ([]*User, error) {
 	query := `
-		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, created_at, updated_at
+		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, daily_budget_usd, monthly_budget_usd, pipeline_mode, decision_cache_enabled, created_at, updated_at
 		FROM users
 		WHERE is_active = true
 		ORDER BY email
@@ -269,6 +283,10 @@ func (db *DB) GetActiveUsers(ctx context.Context) ([]*User, error) {
 			&user.WebhookURL,
 			&user.WebhookHeaderKey,
 			&user.WebhookHeaderValue,
+			&user.DailyBudgetUSD,
+			&user.MonthlyBudgetUSD,
+			&user.PipelineMode,
+			&user.DecisionCacheEnabled,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -286,7 +304,7 @@ func (db *DB) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 	user := &User{}
 
 	query := `
-		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, created_at, updated_at
+		SELECT id, email, google_id, access_token, refresh_token, token_expiry, is_active, last_checked_at, pushover_user_key, pushover_app_token, webhook_url, webhook_header_key, webhook_header_value, daily_budget_usd, monthly_budget_usd, pipeline_mode, decision_cache_enabled, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -305,6 +323,10 @@ func (db *DB) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 		&user.WebhookURL,
 		&user.WebhookHeaderKey,
 		&user.WebhookHeaderValue,
+		&user.DailyBudgetUSD,
+		&user.MonthlyBudgetUSD,
+		&user.PipelineMode,
+		&user.DecisionCacheEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -347,3 +369,35 @@ func (db *DB) UpdateWebhookConfig(ctx context.Context, userID int64, url, header
 
 	return nil
 }
+
+// UpdatePipelineMode sets how a user's emails are processed (PipelineModeTwoStage, PipelineModeEconomy or PipelineModeBuckets)
+func (db *DB) UpdatePipelineMode(ctx context.Context, userID int64, mode string) error {
+	query := `
+		UPDATE users
+		SET pipeline_mode = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, mode, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update pipeline mode: %w", err)
+	}
+
+	return nil
+}
+
+// SetDecisionCacheEnabled turns the AI decision cache on or off for a user
+func (db *DB) SetDecisionCacheEnabled(ctx context.Context, userID int64, enabled bool) error {
+	query := `
+		UPDATE users
+		SET decision_cache_enabled = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	_, err := db.conn.ExecContext(ctx, query, enabled, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update decision cache setting: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package gmail
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// gmailAuthServID identifies the Authentication-Results header Gmail adds on receipt.
+// Headers with any other authserv-id were written by the sender or a relay and are ignored.
+const gmailAuthServID = "mx.google.com"
+
+// AuthResults are the SPF, DKIM and DMARC verdicts Gmail recorded for a message.
+// Each is "pass", "fail", "softfail", "neutral", "none", ... or "" if not reported.
+type AuthResults struct {
+	SPF   string
+	DKIM  string // "pass" if any signature passed
+	DMARC string
+}
+
+// parseAuthResults reads Gmail's Authentication-Results header value, e.g.
+// "mx.google.com; dkim=pass header.i=@example.com; spf=pass (...) smtp.mailfrom=...; dmarc=pass (p=NONE) header.from=example.com".
+// Returns nil for headers Gmail did not write.
+func parseAuthResults(value string) *AuthResults {
+	parts := strings.Split(value, ";")
+	if strings.ToLower(strings.TrimSpace(parts[0])) != gmailAuthServID {
+		return nil
+	}
+
+	results := &AuthResults{}
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(strings.ToLower(fields[0]), "=")
+		if !ok {
+			continue
+		}
+		switch method {
+		case "spf":
+			results.SPF = result
+		case "dkim":
+			// A message may carry several signatures; one passing is enough
+			if results.DKIM != "pass" {
+				results.DKIM = result
+			}
+		case "dmarc":
+			results.DMARC = result
+		}
+	}
+	return results
+}
+
+// parseDisplayName extracts the display name from an RFC 5322 From header ("" if none).
+// "John Doe <john@example.com>" → "John Doe"
+func parseDisplayName(raw string) string {
+	addr, err := mail.ParseAddress(raw)
+	if err != nil {
+		return ""
+	}
+	return addr.Name
+}
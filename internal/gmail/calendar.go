@@ -0,0 +1,325 @@
+package gmail
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// CalendarEvent is a VEVENT parsed from a text/calendar part or .ics attachment
+type CalendarEvent struct {
+	UID          string
+	Method       string // iTIP method of the calendar object: REQUEST, CANCEL, PUBLISH, REPLY...
+	Status       string // CONFIRMED, TENTATIVE or CANCELLED
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Organizer    string   // Email address
+	Attendees    []string // Email addresses
+	Start        time.Time
+	End          *time.Time // nil if the event has no end or duration
+	AllDay       bool
+	TimeZone     string // TZID of DTSTART ("" for UTC or floating times)
+	RRule        string // Recurrence rule of a recurring event
+	RecurrenceID string // Instance of a recurring event this VEVENT overrides ("20250310T090000Z" in UTC, or "20250310"), "" for the series
+}
+
+// Cancelled returns true if the event was cancelled by its organizer
+func (e *CalendarEvent) Cancelled() bool {
+	return e.Method == "CANCEL" || e.Status == "CANCELLED"
+}
+
+// icsProperty is one unfolded content line: NAME;PARAM=VALUE:value
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ParseICS parses the VEVENTs of an iCalendar document. Events without a UID or a valid
+// DTSTART are skipped; an unparseable document yields no events.
+func ParseICS(data string) []*CalendarEvent {
+	var events []*CalendarEvent
+	var method string
+	var event *CalendarEvent
+	var duration time.Duration
+	depth := 0 // Nesting inside the VEVENT (VALARM components are skipped)
+
+	for _, prop := range unfoldICS(data) {
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VEVENT"):
+			event = &CalendarEvent{Method: method}
+			duration = 0
+			depth = 0
+			continue
+		case event == nil:
+			if prop.Name == "METHOD" {
+				method = strings.ToUpper(prop.Value)
+			}
+			continue
+		case prop.Name == "BEGIN":
+			depth++
+			continue
+		case prop.Name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VEVENT"):
+			if event.End == nil && duration > 0 {
+				end := event.Start.Add(duration)
+				event.End = &end
+			}
+			if event.UID != "" && !event.Start.IsZero() {
+				events = append(events, event)
+			}
+			event = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch prop.Name {
+		case "UID":
+			event.UID = prop.Value
+		case "STATUS":
+			event.Status = strings.ToUpper(prop.Value)
+		case "SEQUENCE":
+			event.Sequence, _ = strconv.Atoi(prop.Value)
+		case "SUMMARY":
+			event.Summary = unescapeICS(prop.Value)
+		case "DESCRIPTION":
+			event.Description = unescapeICS(prop.Value)
+		case "LOCATION":
+			event.Location = unescapeICS(prop.Value)
+		case "ORGANIZER":
+			event.Organizer = calendarAddress(prop.Value)
+		case "ATTENDEE":
+			if address := calendarAddress(prop.Value); address != "" {
+				event.Attendees = append(event.Attendees, address)
+			}
+		case "DTSTART":
+			if start, allDay, ok := parseICSTime(prop); ok {
+				event.Start, event.AllDay = start, allDay
+				event.TimeZone = prop.Params["TZID"]
+			}
+		case "DTEND":
+			if end, _, ok := parseICSTime(prop); ok {
+				event.End = &end
+			}
+		case "DURATION":
+			duration = parseICSDuration(prop.Value)
+		case "RRULE":
+			event.RRule = prop.Value
+		case "RECURRENCE-ID":
+			if instance, allDay, ok := parseICSTime(prop); ok {
+				event.RecurrenceID = formatRecurrenceID(instance, allDay)
+			}
+		}
+	}
+	return events
+}
+
+// formatRecurrenceID writes an instance start the way the feed emits it: a date, or a UTC time
+func formatRecurrenceID(instance time.Time, allDay bool) string {
+	if allDay {
+		return instance.Format("20060102")
+	}
+	return instance.UTC().Format("20060102T150405Z")
+}
+
+// unfoldICS joins folded lines and splits each content line into name, parameters and value
+func unfoldICS(data string) []icsProperty {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	props := make([]icsProperty, 0, len(lines))
+	for _, line := range lines {
+		// The value starts at the first colon outside a quoted parameter value
+		colon, quoted := -1, false
+		for i, r := range line {
+			if r == '"' {
+				quoted = !quoted
+			} else if r == ':' && !quoted {
+				colon = i
+				break
+			}
+		}
+		if colon < 0 {
+			continue
+		}
+		parts := strings.Split(line[:colon], ";")
+		prop := icsProperty{Name: strings.ToUpper(strings.TrimSpace(parts[0])), Params: map[string]string{}, Value: strings.TrimSpace(line[colon+1:])}
+		for _, param := range parts[1:] {
+			if key, value, ok := strings.Cut(param, "="); ok {
+				prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+		}
+		props = append(props, prop)
+	}
+	return props
+}
+
+// parseICSTime parses a DTSTART/DTEND value: a DATE (all-day), a UTC time, a time in its TZID
+// zone, or a floating time (read as UTC)
+func parseICSTime(prop icsProperty) (time.Time, bool, bool) {
+	value := prop.Value
+	if prop.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		return t, true, err == nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err == nil
+	}
+	loc := time.UTC
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		loc = loadTimeZone(tzid)
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err == nil
+}
+
+// windowsTimeZones maps the Windows zone names Outlook and Exchange use to IANA zones
+var windowsTimeZones = map[string]string{
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Mountain Standard Time":         "America/Denver",
+	"Central Standard Time":          "America/Chicago",
+	"Eastern Standard Time":          "America/New_York",
+	"Atlantic Standard Time":         "America/Halifax",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Russian Standard Time":          "Europe/Moscow",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+	"UTC":                            "UTC",
+}
+
+// loadTimeZone resolves an IANA or Windows zone name, falling back to UTC
+func loadTimeZone(tzid string) *time.Location {
+	// Some clients prefix the zone with a vendor path, e.g. "/mozilla.org/20050126_1/Europe/Berlin"
+	candidates := []string{tzid, windowsTimeZones[tzid]}
+	if strings.HasPrefix(tzid, "/") {
+		parts := strings.Split(tzid, "/")
+		if len(parts) >= 2 {
+			candidates = append(candidates, strings.Join(parts[len(parts)-2:], "/"))
+		}
+	}
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// parseICSDuration parses an RFC 5545 duration such as "PT1H30M" or "P1D"
+func parseICSDuration(value string) time.Duration {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	var total time.Duration
+	number := 0
+	inTime := false
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0
+		}
+		number = 0
+	}
+	return total
+}
+
+// calendarAddress extracts the email address from a "mailto:" calendar user address
+func calendarAddress(value string) string {
+	if i := strings.Index(strings.ToLower(value), "mailto:"); i >= 0 {
+		value = value[i+len("mailto:"):]
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// unescapeICS reverses RFC 5545 text escaping
+func unescapeICS(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// isCalendarPart returns true for text/calendar parts and .ics attachments
+func isCalendarPart(part *gmail.MessagePart) bool {
+	mimeType := strings.ToLower(part.MimeType)
+	return mimeType == "text/calendar" || mimeType == "application/ics" ||
+		strings.HasSuffix(strings.ToLower(part.Filename), ".ics")
+}
+
+// extractEvents parses every calendar part of a message. Inline parts are decoded directly;
+// attachments are fetched with fetch. Events repeated across parts (the same invite as a
+// text/calendar alternative and an .ics attachment) are returned once.
+func extractEvents(payload *gmail.MessagePart, fetch func(attachmentID string) (string, error)) []*CalendarEvent {
+	var events []*CalendarEvent
+	seen := map[string]bool{}
+
+	var walk func(part *gmail.MessagePart)
+	walk = func(part *gmail.MessagePart) {
+		if part == nil {
+			return
+		}
+		if isCalendarPart(part) && part.Body != nil {
+			data := part.Body.Data
+			if data == "" && part.Body.AttachmentId != "" && fetch != nil {
+				fetched, err := fetch(part.Body.AttachmentId)
+				if err == nil {
+					data = fetched
+				}
+			}
+			if decoded, err := base64.URLEncoding.DecodeString(data); err == nil {
+				for _, event := range ParseICS(string(decoded)) {
+					key := event.UID + "|" + strconv.Itoa(event.Sequence) + "|" + event.Start.String()
+					if !seen[key] {
+						seen[key] = true
+						events = append(events, event)
+					}
+				}
+			}
+		}
+		for _, child := range part.Parts {
+			walk(child)
+		}
+	}
+	walk(payload)
+	return events
+}
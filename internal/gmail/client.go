@@ -36,13 +36,20 @@ func NewClient(ctx context.Context, config *oauth2.Config, token *oauth2.Token)
 
 // Message represents a simplified Gmail message
 type Message struct {
-	ID          string
-	ThreadID    string
-	Subject     string
-	From        string
-	Body        string
-	LabelIDs    []string
-	InternalDate int64
+	ID                  string
+	ThreadID            string
+	Subject             string
+	From                string
+	Body                string
+	LabelIDs            []string
+	InternalDate        int64
+	Events              []*CalendarEvent // Parsed from text/calendar parts and .ics attachments
+	FromName            string           // Display name of the From header
+	Auth                *AuthResults     // Gmail's SPF/DKIM/DMARC verdicts, nil if it recorded none
+	ListID              string           // Mailing list identifier from the List-Id header
+	UnsubscribeURL      string           // HTTPS target of the List-Unsubscribe header
+	UnsubscribeMailto   string           // mailto target of the List-Unsubscribe header
+	OneClickUnsubscribe bool             // List-Unsubscribe-Post allows one-click unsubscribe (RFC 8058)
 }
 
 // GetUnreadMessages fetches unread messages from the inbox
@@ -72,7 +79,20 @@ func (c *Client) GetMessagesSince(ctx context.Context, since int64, maxResults i
 	// Gmail query format: after:YYYY/MM/DD
 	// But we'll use "newer_than" with a relative timestamp
 	query := fmt.Sprintf("in:inbox newer_than:%dd", daysAgo(since))
+	return c.listMessages(ctx, query, since, maxResults)
+}
+
+// securitySubjectQuery matches subjects of one-time code, password, sign-in and account recovery emails
+const securitySubjectQuery = `{subject:code subject:verification subject:verify subject:otp subject:passcode subject:password subject:"sign-in" subject:"sign in" subject:login subject:security subject:recovery}`
+
+// GetSecurityMessagesSince retrieves inbox messages since a timestamp whose subject looks like a security email
+func (c *Client) GetSecurityMessagesSince(ctx context.Context, since int64, maxResults int64) ([]*Message, error) {
+	query := fmt.Sprintf("in:inbox newer_than:%dd %s", daysAgo(since), securitySubjectQuery)
+	return c.listMessages(ctx, query, since, maxResults)
+}
 
+// listMessages fetches the messages matching a Gmail query, keeping those received since the timestamp
+func (c *Client) listMessages(ctx context.Context, query string, since int64, maxResults int64) ([]*Message, error) {
 	req := c.service.Users.Messages.List(c.userID).Q(query).MaxResults(maxResults)
 	res, err := req.Do()
 	if err != nil {
@@ -114,41 +134,69 @@ func (c *Client) GetMessage(ctx context.Context, messageID string) (*Message, er
 	}
 
 	message := &Message{
-		ID:          msg.Id,
-		ThreadID:    msg.ThreadId,
-		LabelIDs:    msg.LabelIds,
+		ID:           msg.Id,
+		ThreadID:     msg.ThreadId,
+		LabelIDs:     msg.LabelIds,
 		InternalDate: msg.InternalDate,
 	}
 
 	// Extract subject and from headers
+	authSeen := false
 	for _, header := range msg.Payload.Headers {
 		switch header.Name {
 		case "Subject":
 			message.Subject = header.Value
 		case "From":
 			message.From = parseAddress(header.Value)
+			message.FromName = parseDisplayName(header.Value)
+		case "Authentication-Results":
+			// Only the first is Gmail's own; later ones were added before Gmail received the message
+			if !authSeen {
+				authSeen = true
+				message.Auth = parseAuthResults(header.Value)
+			}
+		case "List-Id", "List-ID":
+			message.ListID = parseListID(header.Value)
+		case "List-Unsubscribe":
+			message.UnsubscribeURL, message.UnsubscribeMailto = parseListUnsubscribe(header.Value)
+		case "List-Unsubscribe-Post":
+			message.OneClickUnsubscribe = strings.Contains(strings.ToLower(header.Value), "list-unsubscribe=one-click")
 		}
 	}
 
 	// Extract body
 	message.Body = extractBody(msg.Payload)
 
+	// Parse calendar invites
+	message.Events = extractEvents(msg.Payload, func(attachmentID string) (string, error) {
+		attachment, err := c.service.Users.Messages.Attachments.Get(c.userID, msg.Id, attachmentID).Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("failed to get attachment: %w", err)
+		}
+		return attachment.Data, nil
+	})
+
 	return message, nil
 }
 
-// extractBody extracts the plain text body from a message payload
+// extractBody extracts the plain text body from a message payload, falling back to the HTML
+// body for HTML-only messages (the preprocessor renders it as text)
 func extractBody(payload *gmail.MessagePart) string {
-	if payload.MimeType == "text/plain" && payload.Body.Data != "" {
+	if body := findPartData(payload, "text/plain"); body != "" {
+		return body
+	}
+	return findPartData(payload, "text/html")
+}
+
+// findPartData returns the data of the first part with the MIME type, searching nested parts
+func findPartData(payload *gmail.MessagePart, mimeType string) string {
+	if payload.MimeType == mimeType && payload.Body != nil && payload.Body.Data != "" {
 		return payload.Body.Data
 	}
 
 	// Check parts recursively
 	for _, part := range payload.Parts {
-		if part.MimeType == "text/plain" && part.Body.Data != "" {
-			return part.Body.Data
-		}
-		// Check nested parts
-		if body := extractBody(part); body != "" {
+		if body := findPartData(part, mimeType); body != "" {
 			return body
 		}
 	}
@@ -189,6 +237,40 @@ func (c *Client) ArchiveMessage(ctx context.Context, messageID string) error {
 	return c.RemoveLabels(ctx, messageID, []string{"INBOX"})
 }
 
+// GetMessageLabelIDs returns the current label IDs of a message without fetching its body
+func (c *Client) GetMessageLabelIDs(ctx context.Context, messageID string) ([]string, error) {
+	msg, err := c.service.Users.Messages.Get(c.userID, messageID).Format("minimal").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message labels: %w", err)
+	}
+	return msg.LabelIds, nil
+}
+
+// ListMessageIDs returns the IDs of messages matching a Gmail search query, newest first, paging
+// until limit IDs are collected. Only IDs are listed, so this costs one API call per 500 messages.
+func (c *Client) ListMessageIDs(ctx context.Context, query string, limit int) ([]string, error) {
+	var ids []string
+	pageToken := ""
+	for len(ids) < limit {
+		req := c.service.Users.Messages.List(c.userID).Q(query).MaxResults(int64(min(limit-len(ids), 500))).Context(ctx)
+		if pageToken != "" {
+			req = req.PageToken(pageToken)
+		}
+		res, err := req.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, m := range res.Messages {
+			ids = append(ids, m.Id)
+		}
+		if res.NextPageToken == "" {
+			break
+		}
+		pageToken = res.NextPageToken
+	}
+	return ids, nil
+}
+
 // TrashMessage moves a message to the trash
 func (c *Client) TrashMessage(ctx context.Context, messageID string) error {
 	_, err := c.service.Users.Messages.Trash(c.userID, messageID).Context(ctx).Do()
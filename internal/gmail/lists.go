@@ -0,0 +1,31 @@
+package gmail
+
+import "strings"
+
+// parseListID extracts the list identifier from a List-Id header (RFC 2919).
+// "Weekly News <weekly.news.example.com>" → "weekly.news.example.com"
+func parseListID(value string) string {
+	start := strings.LastIndex(value, "<")
+	end := strings.LastIndex(value, ">")
+	if start < 0 || end <= start {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+	return strings.ToLower(strings.TrimSpace(value[start+1 : end]))
+}
+
+// parseListUnsubscribe extracts the HTTPS and mailto targets of a List-Unsubscribe header (RFC 2369).
+// "<mailto:leave@example.com?subject=unsubscribe>, <https://example.com/u/abc>" →
+// "https://example.com/u/abc", "mailto:leave@example.com?subject=unsubscribe"
+func parseListUnsubscribe(value string) (httpURL, mailto string) {
+	for _, part := range strings.Split(value, ",") {
+		target := strings.Trim(strings.TrimSpace(part), "<>")
+		lower := strings.ToLower(target)
+		switch {
+		case httpURL == "" && (strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")):
+			httpURL = target
+		case mailto == "" && strings.HasPrefix(lower, "mailto:"):
+			mailto = target
+		}
+	}
+	return httpURL, mailto
+}
@@ -17,6 +17,25 @@ type MultiUserMonitor struct {
 	oauthConfig   *oauth2.Config
 	checkInterval time.Duration
 	handler       UserMessageHandler
+
+	// Security lane: a frequent check for one-time codes and account alerts between regular polls
+	securityInterval time.Duration
+	securityHandler  UserMessageHandler
+
+	// Per-message locks, so the two lanes never handle the same message at once
+	messageLocksMu sync.Mutex
+	messageLocks   map[string]*messageLock
+
+	// Per-user locks, so the two lanes never refresh and save the same token at once
+	tokenLocksMu sync.Mutex
+	tokenLocks   map[int64]*sync.Mutex
+}
+
+// messageLock serialises the lanes on one message; refs counts the lanes holding or waiting for it,
+// so the entry is only removed once nobody can still be using it
+type messageLock struct {
+	mu   sync.Mutex
+	refs int
 }
 
 // UserMessageHandler is a callback function for handling new messages for a specific user
@@ -29,13 +48,28 @@ func NewMultiUserMonitor(db *database.DB, oauthConfig *oauth2.Config, checkInter
 		oauthConfig:   oauthConfig,
 		checkInterval: checkInterval,
 		handler:       handler,
+		messageLocks:  make(map[string]*messageLock),
+		tokenLocks:    make(map[int64]*sync.Mutex),
 	}
 }
 
+// WithSecurityLane adds a security lane: every interval, recent inbox messages whose subject looks
+// like a security email are passed to handler ahead of the regular poll. The regular poll still
+// sees them and its checkpoint is unaffected.
+func (m *MultiUserMonitor) WithSecurityLane(interval time.Duration, handler UserMessageHandler) *MultiUserMonitor {
+	m.securityInterval = interval
+	m.securityHandler = handler
+	return m
+}
+
 // Start begins monitoring Gmail for all active users
 func (m *MultiUserMonitor) Start(ctx context.Context) error {
 	log.Printf("Starting multi-user Gmail monitor (checking every %v)", m.checkInterval)
 
+	if m.securityHandler != nil && m.securityInterval > 0 {
+		go m.runSecurityLane(ctx)
+	}
+
 	ticker := time.NewTicker(m.checkInterval)
 	defer ticker.Stop()
 
@@ -87,10 +121,32 @@ func (m *MultiUserMonitor) checkAllUsers(ctx context.Context) error {
 	return nil
 }
 
-// checkUserMessages checks Gmail messages for a single user
-func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database.User) error {
-	// Get user's OAuth token
+// tokenLock returns the lock serialising token refreshes for a user
+func (m *MultiUserMonitor) tokenLock(userID int64) *sync.Mutex {
+	m.tokenLocksMu.Lock()
+	defer m.tokenLocksMu.Unlock()
+	lock, ok := m.tokenLocks[userID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.tokenLocks[userID] = lock
+	}
+	return lock
+}
+
+// clientForUser creates a Gmail client for a user, refreshing their token if it has expired.
+// Refreshes are serialised per user; a lane that waited picks up the token the other lane saved.
+func (m *MultiUserMonitor) clientForUser(ctx context.Context, user *database.User) (*Client, error) {
+	lock := m.tokenLock(user.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Get user's OAuth token, reloading it in case the other lane refreshed it since the user was loaded
 	token := user.GetOAuth2Token()
+	if time.Now().After(token.Expiry) {
+		if current, err := m.db.GetUserByID(ctx, user.ID); err == nil {
+			token = current.GetOAuth2Token()
+		}
+	}
 
 	// Check if token needs refresh
 	if time.Now().After(token.Expiry) {
@@ -98,12 +154,12 @@ func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database
 		tokenSource := m.oauthConfig.TokenSource(ctx, token)
 		newToken, err := tokenSource.Token()
 		if err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
+			return nil, fmt.Errorf("failed to refresh token: %w", err)
 		}
 
 		// Update token in database
 		if err := m.db.UpdateUserToken(ctx, user.ID, newToken); err != nil {
-			return fmt.Errorf("failed to update token in database: %w", err)
+			return nil, fmt.Errorf("failed to update token in database: %w", err)
 		}
 
 		token = newToken
@@ -113,7 +169,16 @@ func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database
 	// Create Gmail client for this user
 	client, err := NewClient(ctx, m.oauthConfig, token)
 	if err != nil {
-		return fmt.Errorf("failed to create Gmail client: %w", err)
+		return nil, fmt.Errorf("failed to create Gmail client: %w", err)
+	}
+	return client, nil
+}
+
+// checkUserMessages checks Gmail messages for a single user
+func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database.User) error {
+	client, err := m.clientForUser(ctx, user)
+	if err != nil {
+		return err
 	}
 
 	// Get messages since last check
@@ -154,7 +219,7 @@ func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database
 	// Process each message and track errors
 	var processingErrors []error
 	for _, message := range messages {
-		if err := m.handler(ctx, user, message); err != nil {
+		if err := m.handleMessage(ctx, m.handler, user, message); err != nil {
 			log.Printf("Error handling message %s for user %s: %v", message.ID, user.Email, err)
 			processingErrors = append(processingErrors, err)
 			// Continue processing other messages even if one fails
@@ -178,3 +243,95 @@ func (m *MultiUserMonitor) checkUserMessages(ctx context.Context, user *database
 	log.Printf("Updated checkpoint for %s to %v", user.Email, newCheckpoint.Format(time.RFC3339))
 	return nil
 }
+
+// handleMessage passes a message to a lane's handler, waiting while the other lane handles it
+func (m *MultiUserMonitor) handleMessage(ctx context.Context, handler UserMessageHandler, user *database.User, message *Message) error {
+	m.messageLocksMu.Lock()
+	lock := m.messageLocks[message.ID]
+	if lock == nil {
+		lock = &messageLock{}
+		m.messageLocks[message.ID] = lock
+	}
+	lock.refs++
+	m.messageLocksMu.Unlock()
+
+	lock.mu.Lock()
+	defer func() {
+		lock.mu.Unlock()
+		m.messageLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(m.messageLocks, message.ID)
+		}
+		m.messageLocksMu.Unlock()
+	}()
+	return handler(ctx, user, message)
+}
+
+// runSecurityLane checks every active user for security emails until the context is cancelled
+func (m *MultiUserMonitor) runSecurityLane(ctx context.Context) {
+	log.Printf("Starting security lane (checking every %v)", m.securityInterval)
+
+	ticker := time.NewTicker(m.securityInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Security lane stopped")
+			return
+		case <-ticker.C:
+			users, err := m.db.GetAllActiveUsers(ctx)
+			if err != nil {
+				log.Printf("Security lane: failed to get active users: %v", err)
+				continue
+			}
+
+			var wg sync.WaitGroup
+			for _, user := range users {
+				wg.Add(1)
+				go func(u *database.User) {
+					defer wg.Done()
+					if err := m.checkSecurityMessages(ctx, u); err != nil {
+						log.Printf("Security lane: error checking messages for user %s: %v", u.Email, err)
+					}
+				}(user)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// checkSecurityMessages hands a user's security-looking messages since their checkpoint to the
+// security handler. The checkpoint is left to the regular poll.
+func (m *MultiUserMonitor) checkSecurityMessages(ctx context.Context, user *database.User) error {
+	if user.LastCheckedAt == nil {
+		return nil
+	}
+
+	client, err := m.clientForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	// Messages after the checkpoint are the ones the regular poll has not reached yet
+	sinceMs := user.LastCheckedAt.UnixNano() / 1000000
+	messages, err := client.GetSecurityMessagesSince(ctx, sinceMs, 10)
+	if err != nil {
+		return fmt.Errorf("failed to get security messages since %v: %w", user.LastCheckedAt, err)
+	}
+
+	for _, message := range messages {
+		exists, err := m.db.EmailExists(ctx, message.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check if email exists: %w", err)
+		}
+		if exists {
+			continue
+		}
+		if err := m.handleMessage(ctx, m.securityHandler, user, message); err != nil {
+			log.Printf("Security lane: error handling message %s for user %s: %v", message.ID, user.Email, err)
+		}
+	}
+	return nil
+}
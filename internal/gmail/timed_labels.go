@@ -31,6 +31,21 @@ var TimedDeleteLabels = []TimedLabel{
 // ArchiveAfterReadLabel is applied to emails that should be archived once the user reads them.
 const ArchiveAfterReadLabel = "📥/read"
 
+// IsTimedLabel returns true for the timed archive and delete labels and the archive-after-read label
+func IsTimedLabel(name string) bool {
+	if name == ArchiveAfterReadLabel {
+		return true
+	}
+	for _, labels := range [][]TimedLabel{TimedArchiveLabels, TimedDeleteLabels} {
+		for _, tl := range labels {
+			if tl.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // ProcessTimedLabels searches for emails with expired timed labels and archives/trashes them.
 // Also processes the archive-after-read label.
 func (c *Client) ProcessTimedLabels(ctx context.Context) error {
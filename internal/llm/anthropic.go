@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	anthropicDefaultBaseURL = "https://api.anthropic.com"
+	anthropicVersion        = "2023-06-01"
+	anthropicDefaultTokens  = 4096
+)
+
+// Anthropic talks to the Anthropic Messages API.
+// Structured completions are implemented as a forced tool call whose input schema is the response schema.
+type Anthropic struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAnthropic creates an Anthropic client. An empty baseURL uses the public API.
+func NewAnthropic(apiKey, model, baseURL string) *Anthropic {
+	if baseURL == "" {
+		baseURL = anthropicDefaultBaseURL
+	}
+	return &Anthropic{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model      string             `json:"model"`
+	MaxTokens  int                `json:"max_tokens"`
+	System     string             `json:"system,omitempty"`
+	Messages   []anthropicMessage `json:"messages"`
+	Tools      []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice map[string]string  `json:"tool_choice,omitempty"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete returns a plain text completion
+func (a *Anthropic) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := a.send(ctx, a.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &Response{Content: text.String(), Model: resp.Model, Usage: resp.usage()}, nil
+}
+
+// CompleteJSON returns the input of a forced tool call as the JSON document
+func (a *Anthropic) CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error) {
+	body := a.buildRequest(req)
+	body.Tools = []anthropicTool{{
+		Name:        schema.Name,
+		Description: schema.Description,
+		InputSchema: schema.Definition,
+	}}
+	body.ToolChoice = map[string]string{"type": "tool", "name": schema.Name}
+
+	resp, err := a.send(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == schema.Name {
+			return &Response{Content: string(block.Input), Model: resp.Model, Usage: resp.usage()}, nil
+		}
+	}
+	return nil, fmt.Errorf("no structured output from anthropic (stop_reason: %s)", resp.StopReason)
+}
+
+func (a *Anthropic) buildRequest(req Request) *anthropicRequest {
+	model := a.model
+	if req.Model != "" {
+		model = req.Model
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultTokens
+	}
+	return &anthropicRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System:    req.SystemPrompt,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.UserPrompt}},
+	}
+}
+
+func (a *Anthropic) send(ctx context.Context, body *anthropicRequest) (*anthropicResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal anthropic request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", a.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	httpResp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic api error: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+
+	var resp anthropicResponse
+	parseErr := json.Unmarshal(respBody, &resp)
+	if httpResp.StatusCode >= 300 {
+		statusErr := &StatusError{Provider: "anthropic", StatusCode: httpResp.StatusCode}
+		if parseErr == nil && resp.Error != nil {
+			statusErr.Message = resp.Error.Type + ": " + resp.Error.Message
+		}
+		return nil, statusErr
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse anthropic response (status %d): %w", httpResp.StatusCode, parseErr)
+	}
+
+	return &resp, nil
+}
+
+func (r *anthropicResponse) usage() Usage {
+	return Usage{PromptTokens: r.Usage.InputTokens, CompletionTokens: r.Usage.OutputTokens}
+}
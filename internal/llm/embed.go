@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// EmbedRequest asks for one embedding vector per input text
+type EmbedRequest struct {
+	Name   string // Call name for logging (e.g. "EmbedEmail")
+	Model  string // Overrides the embedder's default model when set
+	Inputs []string
+}
+
+// EmbedResponse holds the vectors in input order
+type EmbedResponse struct {
+	Vectors [][]float32
+	Model   string
+	Usage   Usage
+}
+
+// Embedder is implemented by providers that can embed text
+type Embedder interface {
+	Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error)
+}
+
+// NewEmbedder creates an Embedder for the configured provider.
+// The fake and local providers use the in-process hashing embedder.
+func NewEmbedder(opts Options) (Embedder, error) {
+	switch opts.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAI(opts.APIKey, opts.Model, opts.BaseURL), nil
+	case ProviderFake, ProviderLocal:
+		return NewLocal(), nil
+	case ProviderAnthropic:
+		return nil, fmt.Errorf("anthropic has no embeddings API; use the openai or local embedding provider")
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", opts.Provider)
+	}
+}
+
+// localDims is the vector size of the local embedder
+const localDims = 256
+
+// Local is an in-process embedder with no API: word unigrams and bigrams are hashed into
+// a fixed-size vector. Similarity is lexical only, but needs no network or credentials.
+type Local struct{}
+
+// NewLocal creates the hashing embedder
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Embed returns a normalized hashed bag-of-words vector for each input
+func (l *Local) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	response := &EmbedResponse{Model: fmt.Sprintf("local-hash-%d", localDims)}
+	for _, input := range req.Inputs {
+		response.Vectors = append(response.Vectors, hashEmbedding(input))
+		response.Usage.PromptTokens += len(input) / 4
+	}
+	return response, nil
+}
+
+func hashEmbedding(text string) []float32 {
+	vector := make([]float32, localDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	add := func(feature string) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		// The top bit picks the sign so that collisions tend to cancel out
+		if sum&(1<<31) != 0 {
+			vector[sum%localDims]--
+		} else {
+			vector[sum%localDims]++
+		}
+	}
+	for i, word := range words {
+		add(word)
+		if i > 0 {
+			add(words[i-1] + " " + word)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Fake is a deterministic in-memory LLM for tests and offline development.
+// Responses are looked up by request name; structured requests without a canned
+// response get the zero value of their schema.
+type Fake struct {
+	mu        sync.Mutex
+	responses map[string]string
+	calls     []FakeCall
+}
+
+// FakeCall records a request received by the fake
+type FakeCall struct {
+	Request Request
+	Schema  *Schema
+}
+
+// NewFake creates a fake with no canned responses
+func NewFake() *Fake {
+	return &Fake{responses: make(map[string]string)}
+}
+
+// SetResponse registers the content returned for requests with the given name
+func (f *Fake) SetResponse(name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[name] = content
+}
+
+// Calls returns the requests received so far
+func (f *Fake) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]FakeCall, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// Complete returns the canned response for the request name, or an empty string
+func (f *Fake) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, FakeCall{Request: req})
+	return f.respond(req, f.responses[req.Name]), nil
+}
+
+// CompleteJSON returns the canned response for the request name, or the schema's zero value
+func (f *Fake) CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, FakeCall{Request: req, Schema: &schema})
+
+	content, ok := f.responses[req.Name]
+	if !ok {
+		zero, err := json.Marshal(zeroValue(schema.Definition))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build fake response: %w", err)
+		}
+		content = string(zero)
+	}
+	return f.respond(req, content), nil
+}
+
+func (f *Fake) respond(req Request, content string) *Response {
+	model := req.Model
+	if model == "" {
+		model = "fake"
+	}
+	return &Response{
+		Content: content,
+		Model:   model,
+		Usage: Usage{
+			PromptTokens:     (len(req.SystemPrompt) + len(req.UserPrompt)) / 4,
+			CompletionTokens: len(content) / 4,
+		},
+	}
+}
+
+// zeroValue builds the smallest document that satisfies a JSON schema
+func zeroValue(schema map[string]interface{}) interface{} {
+	switch schema["type"] {
+	case "object":
+		obj := map[string]interface{}{}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range props {
+			if p, ok := prop.(map[string]interface{}); ok {
+				obj[name] = zeroValue(p)
+			}
+		}
+		return obj
+	case "array":
+		return []interface{}{}
+	case "string":
+		if values, ok := schema["enum"].([]string); ok && len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	case "boolean":
+		return false
+	case "number", "integer":
+		return 0
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+// Package llm defines a provider-agnostic interface for chat completions and embeddings
+// and implementations for OpenAI (and OpenAI-compatible servers), Anthropic and a deterministic fake.
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Request is a single system + user prompt completion request
+type Request struct {
+	Name         string // Call name for logging and tracing (e.g. "AnalyzeEmail")
+	Task         string // Task category used for model routing (see Task constants)
+	Model        string // Overrides the provider's default model when set
+	SystemPrompt string
+	UserPrompt   string
+	MaxTokens    int
+
+	// Validate optionally checks the response content. When it fails, Resilient re-prompts once
+	// with the error before falling back to the next provider.
+	Validate func(content string) error
+}
+
+// Schema describes the JSON object a structured completion must return
+type Schema struct {
+	Name        string
+	Description string
+	Definition  map[string]interface{} // JSON Schema for the response object
+}
+
+// Usage holds the token counts reported by the provider
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Response is the result of a completion
+type Response struct {
+	Content string // Plain text, or the JSON document for structured completions
+	Model   string // Model that produced the response
+	Usage   Usage
+}
+
+// LLM is implemented by every model provider
+type LLM interface {
+	// Complete returns a plain text completion
+	Complete(ctx context.Context, req Request) (*Response, error)
+	// CompleteJSON returns a JSON document conforming to the schema
+	CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error)
+}
+
+// Task categories used to route requests to per-task models
+const (
+	TaskAnalyze = "analyze"
+	TaskActions = "actions"
+	TaskTriage  = "triage" // Merged Stage 1 + Stage 2 call (economy mode)
+	TaskDraft   = "draft"
+	TaskProfile = "profile"
+	TaskMemory  = "memory"
+	TaskWrapup  = "wrapup"
+	TaskWizard  = "wizard"
+	TaskEmbed   = "embed"  // Embeddings for similar-email retrieval
+	TaskAsk     = "ask"    // Questions about the mail archive
+	TaskGuard   = "guard"  // Prompt-injection and security-email classifiers
+	TaskBucket  = "bucket" // Bucket classification (buckets mode)
+)
+
+// BucketTask is the task of a bucket-specific processor, e.g. "bucket_newsletter"
+func BucketTask(bucket string) string {
+	return TaskBucket + "_" + bucket
+}
+
+// Provider names accepted by New
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderFake      = "fake"
+	ProviderLocal     = "local" // In-process hashing embedder (embeddings only)
+)
+
+// Options configures a provider
+type Options struct {
+	Provider string
+	APIKey   string
+	Model    string
+	BaseURL  string
+}
+
+// New creates an LLM for the configured provider
+func New(opts Options) (LLM, error) {
+	switch opts.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAI(opts.APIKey, opts.Model, opts.BaseURL), nil
+	case ProviderAnthropic:
+		return NewAnthropic(opts.APIKey, opts.Model, opts.BaseURL), nil
+	case ProviderFake:
+		return NewFake(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", opts.Provider)
+	}
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/packages/param"
+	"github.com/openai/openai-go/shared"
+)
+
+// OpenAI talks to the OpenAI Chat Completions API or any compatible server (Ollama, llama.cpp, vLLM)
+type OpenAI struct {
+	client openai.Client
+	model  string
+}
+
+// NewOpenAI creates an OpenAI-compatible client. An empty baseURL uses the OpenAI API.
+// The SDK's own retries are disabled; Resilient owns retry policy.
+func NewOpenAI(apiKey, model, baseURL string) *OpenAI {
+	opts := []option.RequestOption{option.WithAPIKey(apiKey), option.WithMaxRetries(0)}
+	if baseURL != "" {
+		opts = append(opts, option.WithBaseURL(baseURL))
+	}
+	return &OpenAI{
+		client: openai.NewClient(opts...),
+		model:  model,
+	}
+}
+
+// Complete returns a plain text completion
+func (o *OpenAI) Complete(ctx context.Context, req Request) (*Response, error) {
+	return o.complete(ctx, req, nil)
+}
+
+// CompleteJSON returns a JSON document using strict structured outputs
+func (o *OpenAI) CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error) {
+	return o.complete(ctx, req, &schema)
+}
+
+func (o *OpenAI) complete(ctx context.Context, req Request, schema *Schema) (*Response, error) {
+	model := o.model
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model: shared.ChatModel(model),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(req.SystemPrompt),
+			openai.UserMessage(req.UserPrompt),
+		},
+	}
+	if req.MaxTokens > 0 {
+		params.MaxCompletionTokens = openai.Int(int64(req.MaxTokens))
+	}
+	if schema != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        schema.Name,
+					Description: param.NewOpt(schema.Description),
+					Strict:      param.NewOpt(true),
+					Schema:      schema.Definition,
+				},
+			},
+		}
+	}
+
+	response, err := o.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("openai api error: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from openai")
+	}
+
+	choice := response.Choices[0]
+
+	// Check for refusal
+	if choice.Message.Refusal != "" {
+		return nil, fmt.Errorf("openai refused request: %s", choice.Message.Refusal)
+	}
+
+	if schema != nil && choice.Message.Content == "" {
+		return nil, fmt.Errorf("empty content from openai (finish_reason: %s)", choice.FinishReason)
+	}
+
+	return &Response{
+		Content: choice.Message.Content,
+		Model:   response.Model,
+		Usage: Usage{
+			PromptTokens:     int(response.Usage.PromptTokens),
+			CompletionTokens: int(response.Usage.CompletionTokens),
+		},
+	}, nil
+}
+
+// Embed returns embeddings from the OpenAI (or compatible) embeddings API
+func (o *OpenAI) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	model := o.model
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	response, err := o.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(model),
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: req.Inputs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai embeddings error: %w", err)
+	}
+	if len(response.Data) != len(req.Inputs) {
+		return nil, fmt.Errorf("openai returned %d embeddings for %d inputs", len(response.Data), len(req.Inputs))
+	}
+
+	vectors := make([][]float32, len(response.Data))
+	for _, data := range response.Data {
+		if data.Index < 0 || int(data.Index) >= len(vectors) {
+			return nil, fmt.Errorf("openai returned embedding with invalid index %d", data.Index)
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, v := range data.Embedding {
+			vector[i] = float32(v)
+		}
+		vectors[data.Index] = vector
+	}
+
+	return &EmbedResponse{
+		Vectors: vectors,
+		Model:   response.Model,
+		Usage:   Usage{PromptTokens: int(response.Usage.PromptTokens)},
+	}, nil
+}
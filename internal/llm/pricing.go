@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Prices maps model names to their token prices
+type Prices map[string]Price
+
+// DefaultPrices are list prices for commonly used models; override or extend them with ParsePrices
+var DefaultPrices = Prices{
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-5-nano":        {Input: 0.05, Output: 0.40},
+	"gpt-5-mini":        {Input: 0.25, Output: 2.00},
+	"gpt-5":             {Input: 1.25, Output: 10.00},
+	"claude-haiku-4-5":  {Input: 1.00, Output: 5.00},
+	"claude-sonnet-4-5": {Input: 3.00, Output: 15.00},
+	"fake":              {Input: 0, Output: 0},
+
+	"text-embedding-3-small": {Input: 0.02, Output: 0},
+	"text-embedding-3-large": {Input: 0.13, Output: 0},
+	"local-hash":             {Input: 0, Output: 0},
+}
+
+// ParsePrices parses a price table of the form "model=input:output,model=input:output"
+// (USD per million tokens) and merges it over DefaultPrices.
+func ParsePrices(spec string) (Prices, error) {
+	prices := make(Prices, len(DefaultPrices))
+	for model, price := range DefaultPrices {
+		prices[model] = price
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid price entry %q: expected model=input:output", entry)
+		}
+		input, output, ok := strings.Cut(rates, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid price entry %q: expected model=input:output", entry)
+		}
+		in, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input price in %q: %w", entry, err)
+		}
+		out, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output price in %q: %w", entry, err)
+		}
+		prices[strings.TrimSpace(model)] = Price{Input: in, Output: out}
+	}
+
+	return prices, nil
+}
+
+// Lookup returns the price for a model. Dated or suffixed model IDs (e.g. "gpt-5-nano-2025-08-07")
+// match the longest configured prefix.
+func (p Prices) Lookup(model string) (Price, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range p {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return p[best], true
+}
+
+// Cost estimates the USD cost of a completion. Unknown models cost 0.
+func (p Prices) Cost(model string, usage Usage) float64 {
+	price, ok := p.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1_000_000
+}
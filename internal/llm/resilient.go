@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// StatusError is an HTTP error returned by a provider API
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s api error (status %d)", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s api error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// IsTransient reports whether an error is worth retrying: timeouts, network failures,
+// rate limits and server errors. Bad requests, auth failures and refusals are not.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return transientStatus(statusErr.StatusCode)
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return transientStatus(openaiErr.StatusCode)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func transientStatus(code int) bool {
+	return code == 408 || code == 409 || code == 429 || code >= 500
+}
+
+// Fallback is a secondary provider tried after the previous one in the chain fails.
+// Model overrides the provider's default model; the request's own model is not carried over.
+type Fallback struct {
+	LLM   LLM
+	Model string
+}
+
+// ResilienceOptions controls per-call deadlines and retries
+type ResilienceOptions struct {
+	Timeout    time.Duration // Deadline for each attempt (0 = none)
+	MaxRetries int           // Retries of transient errors per provider
+	Backoff    time.Duration // Delay before the first retry, doubled for each further retry
+}
+
+// Resilient is the shared call layer: every call gets a deadline, transient errors are retried
+// with backoff, responses failing Request.Validate are re-prompted once with the validation error,
+// and only then is the next provider in the fallback chain tried.
+type Resilient struct {
+	chain []Fallback
+	opts  ResilienceOptions
+}
+
+// NewResilient wraps a primary LLM with retries, validation and an optional fallback chain
+func NewResilient(primary LLM, opts ResilienceOptions, fallbacks ...Fallback) *Resilient {
+	chain := append([]Fallback{{LLM: primary}}, fallbacks...)
+	return &Resilient{chain: chain, opts: opts}
+}
+
+// Complete runs a plain text completion through the chain
+func (r *Resilient) Complete(ctx context.Context, req Request) (*Response, error) {
+	return r.run(ctx, req, func(ctx context.Context, provider LLM, req Request) (*Response, error) {
+		return provider.Complete(ctx, req)
+	})
+}
+
+// CompleteJSON runs a structured completion through the chain
+func (r *Resilient) CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error) {
+	return r.run(ctx, req, func(ctx context.Context, provider LLM, req Request) (*Response, error) {
+		return provider.CompleteJSON(ctx, req, schema)
+	})
+}
+
+type callFunc func(ctx context.Context, provider LLM, req Request) (*Response, error)
+
+func (r *Resilient) run(ctx context.Context, req Request, call callFunc) (*Response, error) {
+	var lastErr error
+	for i, target := range r.chain {
+		attempt := req
+		if i > 0 {
+			attempt.Model = target.Model
+			log.Printf("LLM %s: falling back to provider #%d (model %q) after: %v", req.Name, i, target.Model, lastErr)
+		}
+
+		response, err := r.callValidated(ctx, target.LLM, attempt, call)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// callValidated calls one provider and, if the response fails validation, re-prompts it once
+func (r *Resilient) callValidated(ctx context.Context, provider LLM, req Request, call callFunc) (*Response, error) {
+	response, err := r.callWithRetry(ctx, provider, req, call)
+	if err != nil || req.Validate == nil {
+		return response, err
+	}
+
+	validationErr := req.Validate(response.Content)
+	if validationErr == nil {
+		return response, nil
+	}
+	log.Printf("LLM %s: response failed validation, re-prompting: %v", req.Name, validationErr)
+
+	corrected := req
+	corrected.UserPrompt = fmt.Sprintf("%s\n\nYour previous response was rejected: %v\n\nPrevious response:\n%s\n\nRespond again, fixing this problem.",
+		req.UserPrompt, validationErr, response.Content)
+	response, err = r.callWithRetry(ctx, provider, corrected, call)
+	if err != nil {
+		return nil, err
+	}
+	if validationErr := req.Validate(response.Content); validationErr != nil {
+		return nil, validationErr
+	}
+	return response, nil
+}
+
+// callWithRetry calls one provider with a per-attempt deadline, retrying transient errors
+func (r *Resilient) callWithRetry(ctx context.Context, provider LLM, req Request, call callFunc) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if r.opts.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		}
+		response, err := call(callCtx, provider, req)
+		cancel()
+		if err == nil {
+			return response, nil
+		}
+		if attempt >= r.opts.MaxRetries || ctx.Err() != nil || !IsTransient(err) {
+			return nil, err
+		}
+
+		// Exponential backoff with up to 50% jitter
+		delay := r.opts.Backoff << attempt
+		if delay > 0 {
+			delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		}
+		log.Printf("LLM %s: transient error (attempt %d of %d), retrying in %v: %v", req.Name, attempt+1, r.opts.MaxRetries+1, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
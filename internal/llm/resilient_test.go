@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// flaky fails its first calls with the queued errors, then delegates to a Fake
+type flaky struct {
+	*Fake
+	errs []error
+}
+
+func (f *flaky) Complete(ctx context.Context, req Request) (*Response, error) {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		f.mu.Lock()
+		f.calls = append(f.calls, FakeCall{Request: req})
+		f.mu.Unlock()
+		return nil, err
+	}
+	return f.Fake.Complete(ctx, req)
+}
+
+func TestResilientRetriesTransientErrors(t *testing.T) {
+	fake := NewFake()
+	fake.SetResponse("Call", "ok")
+	primary := &flaky{Fake: fake, errs: []error{
+		&StatusError{Provider: "test", StatusCode: 429},
+		&StatusError{Provider: "test", StatusCode: 503},
+	}}
+
+	r := NewResilient(primary, ResilienceOptions{MaxRetries: 2})
+	response, err := r.Complete(context.Background(), Request{Name: "Call"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if response.Content != "ok" {
+		t.Errorf("content = %q, want %q", response.Content, "ok")
+	}
+	if calls := len(fake.Calls()); calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestResilientDoesNotRetryPermanentErrors(t *testing.T) {
+	fake := NewFake()
+	primary := &flaky{Fake: fake, errs: []error{&StatusError{Provider: "test", StatusCode: 400}}}
+
+	r := NewResilient(primary, ResilienceOptions{MaxRetries: 3})
+	if _, err := r.Complete(context.Background(), Request{Name: "Call"}); err == nil {
+		t.Fatal("Complete succeeded, want the bad request error")
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestResilientRepromptsInvalidResponse(t *testing.T) {
+	fake := NewFake()
+	fake.SetResponse("Call", "bad")
+	validate := func(content string) error {
+		if content != "good" {
+			return errors.New("content must be good")
+		}
+		return nil
+	}
+
+	r := NewResilient(fake, ResilienceOptions{})
+	if _, err := r.Complete(context.Background(), Request{Name: "Call", UserPrompt: "prompt", Validate: validate}); err == nil {
+		t.Fatal("Complete succeeded, want the validation error")
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want 2 (original and re-prompt)", len(calls))
+	}
+	reprompt := calls[1].Request.UserPrompt
+	if !strings.HasPrefix(reprompt, "prompt") || !strings.Contains(reprompt, "content must be good") || !strings.Contains(reprompt, "bad") {
+		t.Errorf("re-prompt does not carry the original prompt, error and response: %q", reprompt)
+	}
+}
+
+func TestResilientFallsBackAfterFailure(t *testing.T) {
+	primary := &flaky{Fake: NewFake(), errs: []error{
+		&StatusError{Provider: "primary", StatusCode: 500},
+		&StatusError{Provider: "primary", StatusCode: 500},
+	}}
+	secondary := NewFake()
+	secondary.SetResponse("Call", "fallback")
+
+	r := NewResilient(primary, ResilienceOptions{MaxRetries: 1}, Fallback{LLM: secondary, Model: "backup-model"})
+	response, err := r.Complete(context.Background(), Request{Name: "Call", Model: "primary-model"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if response.Content != "fallback" {
+		t.Errorf("content = %q, want %q", response.Content, "fallback")
+	}
+	if response.Model != "backup-model" {
+		t.Errorf("model = %q, want the fallback's model", response.Model)
+	}
+	if calls := len(primary.Calls()); calls != 2 {
+		t.Errorf("primary calls = %d, want 2", calls)
+	}
+}
+
+func TestResilientFallsBackAfterRepeatedInvalidResponse(t *testing.T) {
+	primary := NewFake()
+	primary.SetResponse("Call", "bad")
+	secondary := NewFake()
+	secondary.SetResponse("Call", "good")
+	validate := func(content string) error {
+		if content != "good" {
+			return errors.New("content must be good")
+		}
+		return nil
+	}
+
+	r := NewResilient(primary, ResilienceOptions{}, Fallback{LLM: secondary})
+	response, err := r.Complete(context.Background(), Request{Name: "Call", Validate: validate})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if response.Content != "good" {
+		t.Errorf("content = %q, want %q", response.Content, "good")
+	}
+	if calls := len(primary.Calls()); calls != 2 {
+		t.Errorf("primary calls = %d, want 2", calls)
+	}
+}
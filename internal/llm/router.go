@@ -0,0 +1,38 @@
+package llm
+
+import "context"
+
+// Router sends each request to the model configured for its task.
+// Requests with an explicit Model, or whose task has no configured model, use the provider default.
+type Router struct {
+	llm    LLM
+	models map[string]string
+}
+
+// NewRouter wraps an LLM with per-task model selection. Empty model names are ignored.
+func NewRouter(provider LLM, models map[string]string) *Router {
+	routes := make(map[string]string)
+	for task, model := range models {
+		if model != "" {
+			routes[task] = model
+		}
+	}
+	return &Router{llm: provider, models: routes}
+}
+
+// Complete routes a plain text completion
+func (r *Router) Complete(ctx context.Context, req Request) (*Response, error) {
+	return r.llm.Complete(ctx, r.route(req))
+}
+
+// CompleteJSON routes a structured completion
+func (r *Router) CompleteJSON(ctx context.Context, req Request, schema Schema) (*Response, error) {
+	return r.llm.CompleteJSON(ctx, r.route(req), schema)
+}
+
+func (r *Router) route(req Request) Request {
+	if req.Model == "" {
+		req.Model = r.models[req.Task]
+	}
+	return req
+}
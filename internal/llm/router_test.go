@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRouterSelectsTaskModel(t *testing.T) {
+	fake := NewFake()
+	router := NewRouter(fake, map[string]string{TaskTriage: "small-model", TaskDraft: ""})
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"configured task", Request{Task: TaskTriage}, "small-model"},
+		{"explicit model wins", Request{Task: TaskTriage, Model: "big-model"}, "big-model"},
+		{"empty route ignored", Request{Task: TaskDraft}, ""},
+		{"unconfigured task", Request{Task: TaskAnalyze}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := router.CompleteJSON(context.Background(), tt.req, Schema{Definition: map[string]interface{}{"type": "object"}}); err != nil {
+				t.Fatalf("CompleteJSON: %v", err)
+			}
+			calls := fake.Calls()
+			if got := calls[len(calls)-1].Request.Model; got != tt.want {
+				t.Errorf("model = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,25 +7,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
 	"github.com/den/gmail-triage-assistant/internal/database"
-	"github.com/den/gmail-triage-assistant/internal/openai"
+	"github.com/den/gmail-triage-assistant/internal/llm"
 )
 
 type Service struct {
-	db     *database.DB
-	openai *openai.Client
+	db *database.DB
+	ai *ai.Client
 }
 
-func NewService(db *database.DB, openaiClient *openai.Client) *Service {
+func NewService(db *database.DB, provider llm.LLM) *Service {
 	return &Service{
-		db:     db,
-		openai: openaiClient,
+		db: db,
+		ai: ai.NewClient(provider),
 	}
 }
 
 // GenerateDailyMemory creates a memory from the previous day's email processing
 func (s *Service) GenerateDailyMemory(ctx context.Context, userID int64) error {
 	log.Printf("Generating daily memory for user %d", userID)
+	ctx = ai.WithUser(ctx, userID)
 
 	// Get yesterday's date range
 	now := time.Now()
@@ -126,7 +128,7 @@ func (s *Service) GenerateDailyMemory(ctx context.Context, userID int64) error {
 }
 
 // generateMemoryFromEmails uses AI to analyze email patterns and generate insights
-func (s *Service) generateMemoryFromEmails(ctx context.Context, emails []*database.Email, labelDetails []*database.Label, customPrompt string) (*openai.MemoryResult, error) {
+func (s *Service) generateMemoryFromEmails(ctx context.Context, emails []*database.Email, labelDetails []*database.Label, customPrompt string) (*ai.MemoryResult, error) {
 	// Build available labels section
 	labelsSection := ""
 	if len(labelDetails) > 0 {
@@ -228,7 +230,7 @@ These human corrections should be given highest priority in your learnings.
 Focus on creating actionable insights that will help process similar emails better in the future. What patterns should be reinforced? What should be done differently?`, len(emails), strings.Join(emailSummaries, "\n"), humanFeedbackSection)
 
 	// Call AI to generate memory with reasoning
-	result, err := s.openai.GenerateMemoryWithReasoning(ctx, systemPrompt, userPrompt)
+	result, err := s.ai.GenerateMemoryWithReasoning(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -239,6 +241,7 @@ Focus on creating actionable insights that will help process similar emails bett
 // GenerateWeeklyMemory consolidates the past week's daily memories
 func (s *Service) GenerateWeeklyMemory(ctx context.Context, userID int64) error {
 	log.Printf("Generating weekly memory for user %d", userID)
+	ctx = ai.WithUser(ctx, userID)
 
 	// Get last week's date range (last 7 days)
 	now := time.Now()
@@ -322,6 +325,7 @@ func (s *Service) GenerateWeeklyMemory(ctx context.Context, userID int64) error
 // GenerateMonthlyMemory consolidates the past month's weekly memories
 func (s *Service) GenerateMonthlyMemory(ctx context.Context, userID int64) error {
 	log.Printf("Generating monthly memory for user %d", userID)
+	ctx = ai.WithUser(ctx, userID)
 
 	// Get last month's date range
 	now := time.Now()
@@ -404,6 +408,7 @@ func (s *Service) GenerateMonthlyMemory(ctx context.Context, userID int64) error
 // GenerateYearlyMemory consolidates the past year's monthly memories
 func (s *Service) GenerateYearlyMemory(ctx context.Context, userID int64) error {
 	log.Printf("Generating yearly memory for user %d", userID)
+	ctx = ai.WithUser(ctx, userID)
 
 	// Get last year's date range
 	now := time.Now()
@@ -484,7 +489,7 @@ func (s *Service) GenerateYearlyMemory(ctx context.Context, userID int64) error
 }
 
 // consolidateMemories uses AI to evolve an existing memory by incorporating new lower-level memories
-func (s *Service) consolidateMemories(ctx context.Context, previousMemory *database.Memory, newMemories []*database.Memory, period string, customPrompt string, labelDetails []*database.Label) (*openai.MemoryResult, error) {
+func (s *Service) consolidateMemories(ctx context.Context, previousMemory *database.Memory, newMemories []*database.Memory, period string, customPrompt string, labelDetails []*database.Label) (*ai.MemoryResult, error) {
 	// Build available labels section for context
 	labelsSection := ""
 	if len(labelDetails) > 0 {
@@ -606,7 +611,7 @@ Provide a concise %s summary with key patterns and strategic insights.`,
 	}
 
 	// Call AI to generate consolidated memory with reasoning
-	result, err := s.openai.GenerateMemoryWithReasoning(ctx, systemPrompt, userPrompt)
+	result, err := s.ai.GenerateMemoryWithReasoning(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -621,6 +626,8 @@ Provide a concise %s summary with key patterns and strategic insights.`,
 // 3. Loads the most recent weekly memory
 // 4. Generates a new AI prompt version
 func (s *Service) GenerateAIPrompts(ctx context.Context, userID int64) error {
+	ctx = ai.WithUser(ctx, userID)
+
 	// Get the most recent weekly memory
 	weeklyMemories, err := s.db.GetMemoriesByType(ctx, userID, database.MemoryTypeWeekly, 1)
 	if err != nil {
@@ -709,7 +716,7 @@ Write the first version of supplementary instructions based on the patterns and
 	}
 
 	// 4. Generate via OpenAI
-	content, err := s.openai.GenerateMemory(ctx, systemPrompt, userPrompt)
+	content, err := s.ai.GenerateMemory(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return fmt.Errorf("failed to generate AI prompt: %w", err)
 	}
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// Timed labels applied by the bucket defaults when the processor did not pick one
+const (
+	newsletterTimedLabel    = "🗑️/1m"
+	notificationTimedLabel  = "🗑️/1w"
+	transactionalTimedLabel = "🗑️/1m"
+	otpTimedLabel           = "🗑️/1d"
+)
+
+// bucketOutcome is what the buckets pipeline decided beyond the common analysis and actions
+type bucketOutcome struct {
+	Triage *ai.BucketTriage
+	Result *ai.BucketResult // nil if the bucket processor failed
+}
+
+// runBuckets classifies an email into a bucket, runs that bucket's processor with its own prompt and
+// model, and applies the bucket's default actions. The outcome is returned even when the processor
+// fails, so the bucket is still recorded.
+func (p *Processor) runBuckets(ctx context.Context, user *database.User, aiClient *ai.Client, from, subject string, promptCtx *promptContext, labelNames []string) (*ai.EmailAnalysis, *ai.EmailActions, *bucketOutcome, error) {
+	triage, err := aiClient.ClassifyBucket(ctx, from, subject, promptCtx.Body, promptCtx.SenderContext, p.loadPrompt(ctx, user.ID, database.PromptTypeBucketTriage))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("bucket triage failed: %w", err)
+	}
+	outcome := &bucketOutcome{Triage: triage}
+
+	result, err := aiClient.ProcessBucket(ctx, triage.Bucket, from, subject, promptCtx.Body, labelNames, promptCtx.FormattedLabels, promptCtx.SenderContext, promptCtx.MemoryContext, p.loadPrompt(ctx, user.ID, database.BucketPromptType(triage.Bucket)))
+	if err != nil {
+		return nil, nil, outcome, fmt.Errorf("%s processor failed: %w", triage.Bucket, err)
+	}
+	outcome.Result = result
+
+	analysis := &ai.EmailAnalysis{
+		Slug:        result.Slug,
+		Keywords:    result.Keywords,
+		Summary:     result.Summary,
+		Transaction: result.Transaction,
+		Event:       result.Event,
+		Tasks:       result.Tasks,
+		Shipment:    result.Shipment,
+	}
+	actions := bucketActions(triage.Bucket, result)
+	actions.Confidence = triage.Confidence
+	actions.Model = result.Model
+	actions.Escalated = triage.Escalated || result.Escalated
+	return analysis, actions, outcome, nil
+}
+
+// bucketActions applies a bucket's default actions to its processor's result:
+//   - newsletter: archived with a timed delete label, never pushed
+//   - notification: kept and pushed only at high severity or urgency, otherwise archived with a timed delete label
+//   - human: the processor's labels, notification and draft decision as-is
+//   - transactional: archived with a timed label
+//   - security: kept in the inbox and always pushed; one-time codes are deleted after a day
+//   - calendar: kept in the inbox
+func bucketActions(bucket string, result *ai.BucketResult) *ai.EmailActions {
+	actions := &ai.EmailActions{
+		Labels:              append([]string{}, result.Labels...),
+		NotificationMessage: result.NotificationMessage,
+		Reasoning:           result.Reasoning,
+	}
+
+	switch bucket {
+	case database.BucketNewsletter:
+		actions.BypassInbox = true
+		actions.NotificationMessage = ""
+		actions.Labels = withTimedLabel(actions.Labels, newsletterTimedLabel)
+		actions.Reasoning = fmt.Sprintf("Newsletter (interest %d/10). %s", result.InterestingScore, result.Reasoning)
+
+	case database.BucketNotification:
+		if !isHighPriority(result.Severity, result.Urgency) {
+			actions.BypassInbox = true
+			actions.NotificationMessage = ""
+			actions.Labels = withTimedLabel(actions.Labels, notificationTimedLabel)
+		}
+		actions.Reasoning = fmt.Sprintf("Notification (severity %s, urgency %s). %s", result.Severity, result.Urgency, result.Reasoning)
+
+	case database.BucketHuman:
+		actions.DraftReply = result.DraftReply
+
+	case database.BucketTransactional:
+		actions.BypassInbox = true
+		actions.Labels = withTimedLabel(actions.Labels, transactionalTimedLabel)
+
+	case database.BucketSecurity:
+		if result.IsOTP {
+			actions.Labels = withTimedLabel(actions.Labels, otpTimedLabel)
+		}
+		if actions.NotificationMessage == "" {
+			actions.NotificationMessage = result.Summary
+		}
+		actions.Reasoning = "Security. " + result.Reasoning
+	}
+
+	return actions
+}
+
+// isHighPriority returns true for notifications worth keeping in the inbox and pushing
+func isHighPriority(severity, urgency string) bool {
+	return severity == "high" || severity == "critical" || urgency == "high"
+}
+
+// withTimedLabel adds a timed label unless the labels already include one
+func withTimedLabel(labels []string, timed string) []string {
+	for _, label := range labels {
+		if strings.HasPrefix(label, "📥/") || strings.HasPrefix(label, "🗑️/") {
+			return labels
+		}
+	}
+	return append(labels, timed)
+}
+
+// apply records the bucket and its assessments on the email
+func (o *bucketOutcome) apply(email *database.Email) {
+	if o == nil {
+		return
+	}
+	email.Bucket = o.Triage.Bucket
+	email.TriageReasoning = o.Triage.Reasoning
+	if o.Result == nil {
+		return
+	}
+	email.Severity = o.Result.Severity
+	email.Urgency = o.Result.Urgency
+	if o.Triage.Bucket == database.BucketNewsletter {
+		score := o.Result.InterestingScore
+		email.InterestingScore = &score
+	}
+}
+
+// loadPrompt returns the user's system prompt of a type, or "" to use the built-in default
+func (p *Processor) loadPrompt(ctx context.Context, userID int64, promptType database.PromptType) string {
+	prompt, err := p.db.GetSystemPrompt(ctx, userID, promptType)
+	if err != nil || prompt == nil {
+		return ""
+	}
+	return prompt.Content
+}
@@ -0,0 +1,145 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// Budget levels, from least to most restrictive
+const (
+	BudgetOK        = "ok"        // Full pipeline
+	BudgetLow       = "low"       // Past the soft limit: skip optional AI stages (drafts, profile summaries)
+	BudgetExhausted = "exhausted" // Limit reached: rules-only triage, no AI calls
+)
+
+// BudgetStatus reports a user's AI spend against their effective budgets (0 budget = unlimited)
+type BudgetStatus struct {
+	DailyBudgetUSD   float64 `json:"daily_budget_usd"`
+	DailySpendUSD    float64 `json:"daily_spend_usd"`
+	MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+	MonthlySpendUSD  float64 `json:"monthly_spend_usd"`
+	Level            string  `json:"level"`
+}
+
+// BudgetStatus returns the user's spend today and this month against their budgets.
+// Per-user budgets override the server defaults.
+func (p *Processor) BudgetStatus(ctx context.Context, user *database.User) (*BudgetStatus, error) {
+	status := &BudgetStatus{
+		DailyBudgetUSD:   user.DailyBudgetUSD,
+		MonthlyBudgetUSD: user.MonthlyBudgetUSD,
+		Level:            BudgetOK,
+	}
+	if status.DailyBudgetUSD == 0 {
+		status.DailyBudgetUSD = p.config.BudgetDailyUSD
+	}
+	if status.MonthlyBudgetUSD == 0 {
+		status.MonthlyBudgetUSD = p.config.BudgetMonthlyUSD
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var err error
+	if status.DailySpendUSD, err = p.db.GetAISpendSince(ctx, user.ID, dayStart); err != nil {
+		return nil, err
+	}
+	if status.MonthlySpendUSD, err = p.db.GetAISpendSince(ctx, user.ID, monthStart); err != nil {
+		return nil, err
+	}
+
+	for _, limit := range [][2]float64{
+		{status.DailySpendUSD, status.DailyBudgetUSD},
+		{status.MonthlySpendUSD, status.MonthlyBudgetUSD},
+	} {
+		spend, budget := limit[0], limit[1]
+		if budget <= 0 {
+			continue
+		}
+		if spend >= budget {
+			status.Level = BudgetExhausted
+		} else if spend >= budget*float64(p.config.BudgetSoftPercent)/100 && status.Level == BudgetOK {
+			status.Level = BudgetLow
+		}
+	}
+
+	return status, nil
+}
+
+// budgetLevel returns the user's budget level, failing open if spend cannot be read
+func (p *Processor) budgetLevel(ctx context.Context, user *database.User) string {
+	if p.config.BudgetDailyUSD <= 0 && p.config.BudgetMonthlyUSD <= 0 && user.DailyBudgetUSD <= 0 && user.MonthlyBudgetUSD <= 0 {
+		return BudgetOK
+	}
+	status, err := p.BudgetStatus(ctx, user)
+	if err != nil {
+		log.Printf("[%s] Failed to check AI budget: %v", user.Email, err)
+		return BudgetOK
+	}
+	if status.Level != BudgetOK {
+		log.Printf("[%s] AI budget %s (today $%.4f of $%.2f, month $%.4f of $%.2f)", user.Email, status.Level,
+			status.DailySpendUSD, status.DailyBudgetUSD, status.MonthlySpendUSD, status.MonthlyBudgetUSD)
+	}
+	return status.Level
+}
+
+// rulesOnlyTriage classifies an email from sender/domain history alone, without calling the AI.
+// Labels the sender usually gets are applied, and the email is archived if the sender is usually archived.
+func rulesOnlyTriage(message *gmail.Message, senderProfile, domainProfile *database.SenderProfile, labelNames []string) (*ai.EmailAnalysis, *ai.EmailActions) {
+	analysis := defaultAnalysis(message)
+	actions := &ai.EmailActions{
+		Labels:    []string{},
+		Reasoning: "Rules-only triage (AI budget exhausted): no sender history, left in inbox",
+		Model:     "rules",
+	}
+
+	profile := senderProfile
+	if profile == nil || profile.DecidedCount() <= 0 {
+		profile = domainProfile
+	}
+	if profile == nil || profile.DecidedCount() <= 0 {
+		return analysis, actions
+	}
+
+	if slug := topKey(profile.SlugCounts); slug != "" {
+		analysis.Slug = slug
+	}
+
+	known := make(map[string]bool, len(labelNames))
+	for _, name := range labelNames {
+		known[name] = true
+	}
+	for label, count := range profile.LabelCounts {
+		if float64(count)/float64(profile.DecidedCount()) < 0.5 {
+			continue
+		}
+		if known[label] || strings.HasPrefix(label, "📥/") || strings.HasPrefix(label, "🗑️/") {
+			actions.Labels = append(actions.Labels, label)
+		}
+	}
+	sort.Strings(actions.Labels)
+	actions.BypassInbox = profile.BypassInboxRate() >= 0.8
+	actions.Reasoning = fmt.Sprintf("Rules-only triage (AI budget exhausted): based on %d previous emails from %s (archive rate %.0f%%)",
+		profile.DecidedCount(), profile.Identifier, profile.BypassInboxRate()*100)
+
+	return analysis, actions
+}
+
+// topKey returns the key with the highest count (ties broken alphabetically)
+func topKey(counts map[string]int) string {
+	best, bestCount := "", 0
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best, bestCount = key, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,160 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+var (
+	urlPattern      = regexp.MustCompile(`(?i)\bhttps?://\S+|\bwww\.\S+`)
+	addressPattern  = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+	digitPattern    = regexp.MustCompile(`\d+`)
+	greetingPattern = regexp.MustCompile(`\b((?i:hi|hello|hey|dear|thanks|thank you),?)\s+[A-Z][\w'-]*`)
+	spacePattern    = regexp.MustCompile(`\s+`)
+)
+
+// normalizeSkeleton reduces text to its template: greeting names, URLs, email addresses
+// and digits are masked, case is folded and whitespace collapsed.
+// Template blasts (CI notifications, OTPs, marketing sends) normalize to the same skeleton.
+func normalizeSkeleton(text string) string {
+	text = greetingPattern.ReplaceAllString(text, "$1 <name>")
+	text = urlPattern.ReplaceAllString(text, "<url>")
+	text = addressPattern.ReplaceAllString(text, "<email>")
+	text = digitPattern.ReplaceAllString(text, "#")
+	text = strings.ToLower(text)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+}
+
+// decisionCacheKey hashes the normalized sender, subject and body together with the
+// prompt version (system prompts, labels and pipeline mode) and the model
+func decisionCacheKey(message *gmail.Message, body string, prompts *systemPrompts, formattedLabels, mode, model string) string {
+	sender := strings.ToLower(message.From)
+	if address := addressPattern.FindString(sender); address != "" {
+		sender = address
+	}
+	sender = digitPattern.ReplaceAllString(sender, "#")
+
+	promptVersion := database.HashContent(strings.Join([]string{prompts.Analyze, prompts.Actions, formattedLabels, mode}, "\x00"))
+
+	h := sha256.New()
+	for _, part := range []string{sender, normalizeSkeleton(message.Subject), normalizeSkeleton(body), promptVersion, model} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// modelKey identifies the model(s) that would make the decision, for the cache key
+func (p *Processor) modelKey(client *ai.Client) string {
+	if model := client.Model(); model != "" {
+		return model
+	}
+	return strings.Join([]string{p.config.LLMProvider, p.config.OpenAIModel, p.config.AnthropicModel,
+		p.config.ModelAnalyze, p.config.ModelActions, p.config.ModelTriage, p.config.EscalationModel}, "|")
+}
+
+// cachedReasoning is the reasoning recorded for a decision reused from the cache
+const cachedReasoning = "Decision reused from the cache: an earlier email had the same sender and template"
+
+// lookupCachedDecision returns a cached decision for the key, or nils on a miss. The cache holds only
+// the template-level decision; the summary and keywords are rebuilt from this email's subject and slug.
+func (p *Processor) lookupCachedDecision(ctx context.Context, user *database.User, message *gmail.Message, cacheKey string) (*ai.EmailAnalysis, *ai.EmailActions) {
+	cached, err := p.db.GetCachedDecision(ctx, user.ID, cacheKey)
+	if err != nil {
+		log.Printf("[%s] Failed to read decision cache: %v", user.Email, err)
+		return nil, nil
+	}
+	if cached == nil {
+		return nil, nil
+	}
+
+	var analysis ai.EmailAnalysis
+	var actions ai.EmailActions
+	if err := json.Unmarshal(cached.Analysis, &analysis); err != nil {
+		log.Printf("[%s] Ignoring unreadable cached analysis: %v", user.Email, err)
+		return nil, nil
+	}
+	if err := json.Unmarshal(cached.Actions, &actions); err != nil {
+		log.Printf("[%s] Ignoring unreadable cached actions: %v", user.Email, err)
+		return nil, nil
+	}
+	// Entries written before notifications were excluded would quote another email
+	if actions.NotificationMessage != "" || hasExtractedData(&analysis) {
+		return nil, nil
+	}
+	actions.Model = cached.Model
+	actions.Reasoning = cachedReasoning
+	analysis.Summary = message.Subject
+	analysis.Keywords = slugKeywords(analysis.Slug)
+
+	return &analysis, &actions
+}
+
+// isCacheable returns true if a decision can be reused for other emails with the same template.
+// The cache key masks digits, names, URLs and addresses, so decisions carrying data specific to one
+// email are not cached: amounts, dates, action items and tracking numbers, and notifications, whose
+// text quotes the email.
+func isCacheable(analysis *ai.EmailAnalysis, actions *ai.EmailActions) bool {
+	return !hasExtractedData(analysis) && actions.NotificationMessage == ""
+}
+
+// hasExtractedData returns true if the analysis carries data specific to one email
+func hasExtractedData(analysis *ai.EmailAnalysis) bool {
+	return analysis.Transaction != nil || analysis.Event != nil || len(analysis.Tasks) > 0 || analysis.Shipment != nil
+}
+
+// slugKeywords derives keywords from a slug: "invoice_due" → ["invoice", "due"]
+func slugKeywords(slug string) []string {
+	keywords := []string{}
+	for _, word := range strings.Split(slug, "_") {
+		if word != "" {
+			keywords = append(keywords, word)
+		}
+	}
+	return keywords
+}
+
+// storeCachedDecision saves an AI decision for reuse until the cache TTL expires (non-critical).
+// Only the template-level decision is stored: the slug, labels and routing. The summary, keywords
+// and reasoning describe this one email and are left out.
+func (p *Processor) storeCachedDecision(ctx context.Context, user *database.User, cacheKey string, analysis *ai.EmailAnalysis, actions *ai.EmailActions) {
+	analysisJSON, err := json.Marshal(&ai.EmailAnalysis{Slug: analysis.Slug})
+	if err != nil {
+		log.Printf("[%s] Failed to encode analysis for cache: %v", user.Email, err)
+		return
+	}
+	actionsJSON, err := json.Marshal(&ai.EmailActions{
+		Labels:      actions.Labels,
+		BypassInbox: actions.BypassInbox,
+		DraftReply:  actions.DraftReply,
+		Confidence:  actions.Confidence,
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to encode actions for cache: %v", user.Email, err)
+		return
+	}
+
+	now := time.Now()
+	decision := &database.CachedDecision{
+		UserID:    user.ID,
+		CacheKey:  cacheKey,
+		Analysis:  analysisJSON,
+		Actions:   actionsJSON,
+		Model:     actions.Model,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(p.config.DecisionCacheTTLHours) * time.Hour),
+	}
+	if err := p.db.PutCachedDecision(ctx, decision); err != nil {
+		log.Printf("[%s] Failed to store cached decision: %v", user.Email, err)
+	}
+}
@@ -0,0 +1,236 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// minBodyTokens is the smallest body the context budget will truncate to
+const minBodyTokens = 150
+
+// Label list detail levels, from most to least verbose
+const (
+	labelDetailFull         = iota // Names, descriptions and example reasons
+	labelDetailDescriptions        // Names and descriptions
+	labelDetailNames               // Names only
+)
+
+// promptContext is the variable context assembled into the AI prompts for one email
+type promptContext struct {
+	Body            string
+	SenderContext   string
+	MemoryContext   string
+	FormattedLabels string
+	MemoryIDs       []int64                // Memories actually included
+	Trims           []database.ContextTrim // What was cut to fit the budget
+	ExampleEmailIDs []string               // Similar past emails added to the Stage 2 prompt
+}
+
+// contextAssembler fits the body, profiles, labels and memories into a token budget
+type contextAssembler struct {
+	p             *Processor
+	body          string
+	memories      []*database.Memory
+	senderProfile *database.SenderProfile
+	domainProfile *database.SenderProfile
+	labels        []*database.Label
+	labelDetail   int
+	trims         []database.ContextTrim
+}
+
+// estimateTokens approximates the token count of text (about 4 bytes per token)
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// assembleContext renders the prompt context, reducing the lowest-value sections first
+// until it fits the configured token budget (0 = unlimited):
+//  1. daily memories, oldest first (the weekly and longer memories already summarize them)
+//  2. example reasons in the label list
+//  3. the domain profile
+//  4. the remaining memories, shortest period first (weekly, then monthly, then yearly)
+//  5. label descriptions (label names are always kept)
+//  6. the body, down to minBodyTokens
+//  7. the sender profile
+func (p *Processor) assembleContext(body string, memories []*database.Memory, senderProfile, domainProfile *database.SenderProfile, labels []*database.Label) *promptContext {
+	a := &contextAssembler{
+		p:             p,
+		body:          body,
+		memories:      memories,
+		senderProfile: senderProfile,
+		domainProfile: domainProfile,
+		labels:        labels,
+	}
+
+	budget := p.config.ContextTokenBudget
+	if budget > 0 {
+		steps := []func() bool{
+			a.dropMemory(database.MemoryTypeDaily),
+			a.compactLabels(labelDetailDescriptions, "dropped example reasons"),
+			a.dropDomainProfile,
+			a.dropMemory(""),
+			a.compactLabels(labelDetailNames, "dropped descriptions"),
+			func() bool { return a.truncateBody(budget) },
+			a.dropSenderProfile,
+		}
+		for _, step := range steps {
+			for a.tokens() > budget && step() {
+			}
+		}
+	}
+
+	return a.render()
+}
+
+func (a *contextAssembler) render() *promptContext {
+	memoryContext, memoryIDs := formatMemories(a.memories)
+	return &promptContext{
+		Body:            a.body,
+		SenderContext:   a.p.formatProfilesForPrompt(a.senderProfile, a.domainProfile),
+		MemoryContext:   memoryContext,
+		FormattedLabels: formatLabels(a.labels, a.labelDetail),
+		MemoryIDs:       memoryIDs,
+		Trims:           a.trims,
+	}
+}
+
+func (a *contextAssembler) tokens() int {
+	c := a.render()
+	return estimateTokens(c.Body) + estimateTokens(c.SenderContext) + estimateTokens(c.MemoryContext) + estimateTokens(c.FormattedLabels)
+}
+
+// reduce applies a change and records it with the tokens it saved
+func (a *contextAssembler) reduce(section, action, detail string, apply func()) {
+	before := a.tokens()
+	apply()
+	a.trims = append(a.trims, database.ContextTrim{
+		Section:     section,
+		Action:      action,
+		Detail:      detail,
+		TokensSaved: before - a.tokens(),
+	})
+}
+
+// dropMemory returns a step that drops the last memory of the given type (any type if empty)
+func (a *contextAssembler) dropMemory(memoryType database.MemoryType) func() bool {
+	return func() bool {
+		for i := len(a.memories) - 1; i >= 0; i-- {
+			mem := a.memories[i]
+			if memoryType != "" && mem.Type != memoryType {
+				continue
+			}
+			detail := fmt.Sprintf("%s memory #%d (%s)", mem.Type, mem.ID, mem.StartDate.Format("2006-01-02"))
+			a.reduce("memories", "dropped", detail, func() {
+				a.memories = append(a.memories[:i:i], a.memories[i+1:]...)
+			})
+			return true
+		}
+		return false
+	}
+}
+
+// compactLabels returns a step that renders the label list at a lower detail level
+func (a *contextAssembler) compactLabels(detail int, description string) func() bool {
+	return func() bool {
+		if a.labelDetail >= detail {
+			return false
+		}
+		a.reduce("labels", "compacted", description, func() { a.labelDetail = detail })
+		return true
+	}
+}
+
+func (a *contextAssembler) dropDomainProfile() bool {
+	if a.domainProfile == nil {
+		return false
+	}
+	a.reduce("domain_profile", "dropped", a.domainProfile.Identifier, func() { a.domainProfile = nil })
+	return true
+}
+
+func (a *contextAssembler) dropSenderProfile() bool {
+	if a.senderProfile == nil {
+		return false
+	}
+	a.reduce("sender_profile", "dropped", a.senderProfile.Identifier, func() { a.senderProfile = nil })
+	return true
+}
+
+// truncateBody cuts the body by the amount the context is over budget, keeping at least minBodyTokens
+func (a *contextAssembler) truncateBody(budget int) bool {
+	bodyTokens := estimateTokens(a.body)
+	target := bodyTokens - (a.tokens() - budget)
+	if target < minBodyTokens {
+		target = minBodyTokens
+	}
+	if target >= bodyTokens {
+		return false
+	}
+	a.reduce("body", "truncated", fmt.Sprintf("to about %d tokens", target), func() {
+		a.body = truncateToTokens(a.body, target)
+	})
+	return false // One cut is enough; later steps handle any remainder
+}
+
+// truncateToTokens shortens text to about the given number of tokens on a UTF-8 boundary
+func truncateToTokens(text string, tokens int) string {
+	limit := tokens * 4
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit] + "..."
+}
+
+// formatMemories formats memories for the Stage 2 prompt and returns the IDs of the memories used
+func formatMemories(memories []*database.Memory) (string, []int64) {
+	memoryIDs := []int64{}
+	if len(memories) == 0 {
+		return "", memoryIDs
+	}
+
+	var b strings.Builder
+	b.WriteString("Past learnings from email processing:\n\n")
+	for _, mem := range memories {
+		fmt.Fprintf(&b, "**%s Memory:**\n%s\n\n", strings.ToUpper(string(mem.Type)), mem.Content)
+		memoryIDs = append(memoryIDs, mem.ID)
+	}
+	return b.String(), memoryIDs
+}
+
+// formatLabels formats the user's labels as bullet points at the given detail level,
+// followed by the system timed action labels
+func formatLabels(labels []*database.Label, detail int) string {
+	var labelLines []string
+	for _, l := range labels {
+		line := fmt.Sprintf(`- "%s"`, l.Name)
+		if l.Description != "" && detail <= labelDetailDescriptions {
+			line += ": " + l.Description
+		}
+		if len(l.Reasons) > 0 && detail == labelDetailFull {
+			line += " (e.g. " + strings.Join(l.Reasons, ", ") + ")"
+		}
+		labelLines = append(labelLines, line)
+	}
+	formattedLabels := strings.Join(labelLines, "\n")
+
+	// Append timed action labels
+	formattedLabels += "\n\n--- Timed Action Labels (system) ---\n"
+	formattedLabels += `- "📥/1d": Archive this email after 1 day (use for time-sensitive items the user should see briefly)` + "\n"
+	formattedLabels += `- "📥/1w": Archive this email after 1 week (use for newsletters, digests, weekly content)` + "\n"
+	formattedLabels += `- "📥/1m": Archive this email after 1 month` + "\n"
+	formattedLabels += `- "📥/1y": Archive this email after 1 year` + "\n"
+	formattedLabels += `- "📥/read": Archive this email after the user reads it (use for emails worth glancing at but not keeping in inbox)` + "\n"
+	formattedLabels += `- "🗑️/1d": Delete this email after 1 day (use for truly disposable emails like OTP codes, shipping notifications after delivery)` + "\n"
+	formattedLabels += `- "🗑️/1w": Delete this email after 1 week` + "\n"
+	formattedLabels += `- "🗑️/1m": Delete this email after 1 month` + "\n"
+	formattedLabels += `- "🗑️/1y": Delete this email after 1 year` + "\n"
+	formattedLabels += "\nYou may apply ONE timed label alongside regular labels. Use these instead of bypass_inbox when the user might want to see the email briefly before it's archived. Use delete labels sparingly — only for emails with no long-term value."
+
+	return formattedLabels
+}
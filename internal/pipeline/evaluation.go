@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// labelSettleTime is how old a decision must be before it counts as accepted: the user has had
+// time to move it back to the inbox or leave feedback
+const labelSettleTime = 3 * 24 * time.Hour
+
+// ModeEvalRequest replays the user's labelled history through several pipeline modes
+type ModeEvalRequest struct {
+	Modes []string `json:"modes"` // Pipeline modes to compare (default two_stage and economy)
+	Limit int      `json:"limit"` // Emails to replay (default 20, max 50)
+}
+
+// ModeEvalScore is how closely one pipeline mode reproduced the accepted decisions, and what it cost
+type ModeEvalScore struct {
+	Mode              string  `json:"mode"`
+	Emails            int     `json:"emails"` // Emails replayed successfully
+	Errors            int     `json:"errors"`
+	ArchiveAgreement  float64 `json:"archive_agreement"` // Share of emails whose archive decision matches
+	LabelAgreement    float64 `json:"label_agreement"`   // Mean overlap (Jaccard) of the chosen and accepted labels
+	PromptTokens      int     `json:"prompt_tokens"`
+	CompletionTokens  int     `json:"completion_tokens"`
+	CostUSD           float64 `json:"cost_usd"`
+	AvgTokensPerEmail float64 `json:"avg_tokens_per_email"`
+	AvgCostPerEmail   float64 `json:"avg_cost_per_email"`
+}
+
+// ModeEvalResult compares pipeline modes on the same labelled emails
+type ModeEvalResult struct {
+	Emails  int              `json:"emails"`  // Labelled emails replayed
+	Skipped int              `json:"skipped"` // Labelled emails no longer in Gmail
+	Modes   []*ModeEvalScore `json:"modes"`
+}
+
+// CompareModes dry-runs recent emails whose decision the user accepted through each mode and scores
+// the modes' decisions against them, with their token usage and estimated cost. Each email is
+// fetched from Gmail once; nothing is written to Gmail or the emails table.
+func (p *Processor) CompareModes(ctx context.Context, user *database.User, req *ModeEvalRequest) (*ModeEvalResult, error) {
+	modes := req.Modes
+	if len(modes) == 0 {
+		modes = []string{database.PipelineModeTwoStage, database.PipelineModeEconomy}
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	labelled, err := p.db.GetLabelledEmails(ctx, user.ID, time.Now().Add(-labelSettleTime), limit)
+	if err != nil {
+		return nil, err
+	}
+	client, err := gmail.NewClient(ctx, p.oauthConfig, user.GetOAuth2Token())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gmail client: %w", err)
+	}
+
+	result := &ModeEvalResult{Modes: make([]*ModeEvalScore, len(modes))}
+	for i, mode := range modes {
+		result.Modes[i] = &ModeEvalScore{Mode: mode}
+	}
+
+	for _, expected := range labelled {
+		message, err := client.GetMessage(ctx, expected.ID)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+		result.Emails++
+		body := prepareBody(message.Body)
+
+		for _, score := range result.Modes {
+			run, err := p.dryRun(ctx, user, &PlaygroundRequest{EmailID: expected.ID, Mode: score.Mode}, message.From, message.Subject, body)
+			if err != nil {
+				log.Printf("[%s] Mode comparison: %s failed on %s: %v", user.Email, score.Mode, expected.ID, err)
+				score.Errors++
+				continue
+			}
+			score.Emails++
+			if run.Actions.BypassInbox == expected.BypassInbox {
+				score.ArchiveAgreement++
+			}
+			score.LabelAgreement += labelOverlap(run.Actions.Labels, expected.Labels)
+			score.PromptTokens += run.PromptTokens
+			score.CompletionTokens += run.CompletionTokens
+			score.CostUSD += run.CostUSD
+		}
+	}
+
+	for _, score := range result.Modes {
+		if score.Emails > 0 {
+			n := float64(score.Emails)
+			score.ArchiveAgreement /= n
+			score.LabelAgreement /= n
+			score.AvgTokensPerEmail = float64(score.PromptTokens+score.CompletionTokens) / n
+			score.AvgCostPerEmail = score.CostUSD / n
+		}
+	}
+	return result, nil
+}
+
+// labelOverlap is the Jaccard similarity of two label sets (1 when both are empty)
+func labelOverlap(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, label := range a {
+		set[label] = true
+	}
+	union := len(set)
+	shared := 0
+	seen := make(map[string]bool, len(b))
+	for _, label := range b {
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		if set[label] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// eventUIDDomain suffixes the UIDs generated for AI-extracted events
+const eventUIDDomain = "gmail-triage-assistant"
+
+// saveEvents stores an email's calendar events (non-critical). Invites attached to the email are
+// authoritative; the AI-extracted event is only used when the email carries no invite, and has the
+// values redacted from the email put back.
+func (p *Processor) saveEvents(ctx context.Context, user *database.User, emailID string, invites []*gmail.CalendarEvent, extracted *ai.Event, redactions *redact.Result) {
+	if len(invites) > 0 {
+		for _, invite := range invites {
+			if err := p.db.UpsertEvent(ctx, inviteEvent(user.ID, emailID, invite)); err != nil {
+				log.Printf("[%s] Failed to save calendar invite: %v", user.Email, err)
+			}
+		}
+		return
+	}
+	if extracted == nil {
+		return
+	}
+
+	start, end, err := extracted.Times()
+	if err != nil {
+		log.Printf("[%s] Failed to save extracted event: %v", user.Email, err)
+		return
+	}
+	event := &database.Event{
+		UserID:   user.ID,
+		EmailID:  emailID,
+		UID:      fmt.Sprintf("email-%s@%s", emailID, eventUIDDomain),
+		Source:   database.EventSourceAI,
+		Status:   database.EventStatusConfirmed,
+		Summary:  redactions.Restore(extracted.Title),
+		Location: redactions.Restore(extracted.Location),
+		StartsAt: start,
+		EndsAt:   end,
+		AllDay:   extracted.AllDay,
+		TimeZone: extracted.TimeZone,
+	}
+	if err := p.db.UpsertEvent(ctx, event); err != nil {
+		log.Printf("[%s] Failed to save extracted event: %v", user.Email, err)
+	}
+}
+
+// inviteEvent converts a parsed invite to a stored event; cancellations keep the UID so they replace the original
+func inviteEvent(userID int64, emailID string, invite *gmail.CalendarEvent) *database.Event {
+	status := invite.Status
+	if invite.Cancelled() {
+		status = database.EventStatusCancelled
+	} else if status == "" {
+		status = database.EventStatusConfirmed
+	}
+	return &database.Event{
+		UserID:       userID,
+		EmailID:      emailID,
+		UID:          invite.UID,
+		Source:       database.EventSourceICS,
+		Method:       invite.Method,
+		Status:       status,
+		Sequence:     invite.Sequence,
+		Summary:      invite.Summary,
+		Description:  invite.Description,
+		Location:     invite.Location,
+		Organizer:    invite.Organizer,
+		Attendees:    invite.Attendees,
+		StartsAt:     invite.Start,
+		EndsAt:       invite.End,
+		AllDay:       invite.AllDay,
+		TimeZone:     invite.TimeZone,
+		RRule:        invite.RRule,
+		RecurrenceID: invite.RecurrenceID,
+	}
+}
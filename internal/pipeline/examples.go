@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+const (
+	minExampleSimilarity = 0.5 // Less similar emails are not useful examples
+	correctedBoost       = 0.1 // Ranks corrected decisions above slightly more similar uncorrected ones
+)
+
+// embedEmail embeds an email's subject and summary.
+// Returns a nil vector when embeddings are disabled or the call fails (non-critical).
+func (p *Processor) embedEmail(ctx context.Context, user *database.User, subject, summary string) ([]float32, string) {
+	if p.embedder == nil {
+		return nil, ""
+	}
+	response, err := p.embedder.Embed(ctx, llm.EmbedRequest{
+		Name:   "EmbedEmail",
+		Inputs: []string{subject + "\n" + summary},
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to embed email: %v", user.Email, err)
+		return nil, ""
+	}
+	return response.Vectors[0], response.Model
+}
+
+// findExamples returns up to FewShotExamples similar past decisions, corrected ones first.
+// Their senders and subjects are stored unredacted, so they are redacted into redactions.
+func (p *Processor) findExamples(ctx context.Context, user *database.User, emailID string, vector []float32, model string, redactions *redact.Result) []*database.SimilarEmail {
+	if vector == nil {
+		return nil
+	}
+
+	// Over-fetch so that corrected decisions just outside the top k can be promoted
+	limit := p.config.FewShotExamples
+	candidates, err := p.db.FindSimilarEmails(ctx, user.ID, model, vector, emailID, limit*3)
+	if err != nil {
+		log.Printf("[%s] Failed to find similar emails: %v", user.Email, err)
+		return nil
+	}
+
+	var examples []*database.SimilarEmail
+	for _, c := range candidates {
+		if c.Similarity >= minExampleSimilarity {
+			examples = append(examples, c)
+		}
+	}
+	rank := func(s *database.SimilarEmail) float64 {
+		if s.Corrected() {
+			return s.Similarity + correctedBoost
+		}
+		return s.Similarity
+	}
+	sort.SliceStable(examples, func(i, j int) bool { return rank(examples[i]) > rank(examples[j]) })
+	if len(examples) > limit {
+		examples = examples[:limit]
+	}
+
+	if len(examples) > 0 {
+		redactor := p.loadRedactor(ctx, user)
+		for _, ex := range examples {
+			ex.Subject = redactor.redact(ex.FromAddress, ex.Subject, redactions)
+			ex.FromAddress = redactor.redact(ex.FromAddress, ex.FromAddress, redactions)
+		}
+	}
+	return examples
+}
+
+// saveEmbedding stores an email's embedding for future retrieval, embedding it first if needed (non-critical)
+func (p *Processor) saveEmbedding(ctx context.Context, user *database.User, emailID string, vector []float32, model, subject, summary string) {
+	if vector == nil {
+		vector, model = p.embedEmail(ctx, user, subject, summary)
+		if vector == nil {
+			return
+		}
+	}
+	if err := p.db.SaveEmailEmbedding(ctx, emailID, user.ID, model, vector); err != nil {
+		log.Printf("[%s] Failed to save email embedding: %v", user.Email, err)
+	}
+}
+
+// formatExamples formats similar past decisions as few-shot examples for the Stage 2 prompt
+func formatExamples(examples []*database.SimilarEmail) string {
+	if len(examples) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Similar past emails and what was decided (follow the user's corrections):\n\n")
+	for i, ex := range examples {
+		fmt.Fprintf(&b, "%d. From: %s | Subject: %s\n", i+1, ex.FromAddress, ex.Subject)
+		fmt.Fprintf(&b, "   Summary: %s\n", truncateToTokens(ex.Summary, 60))
+
+		decision := "no labels"
+		if len(ex.LabelsApplied) > 0 {
+			decision = "labels " + strings.Join(ex.LabelsApplied, ", ")
+		}
+		if ex.BypassedInbox {
+			decision += ", archived"
+		}
+		fmt.Fprintf(&b, "   Decision: %s\n", decision)
+
+		if ex.HumanFeedback != "" {
+			fmt.Fprintf(&b, "   User correction: %s\n", ex.HumanFeedback)
+		}
+		if ex.UserUnarchived {
+			b.WriteString("   User correction: moved it back to the inbox (should not have been archived)\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// exampleIDs returns the email IDs of the examples
+func exampleIDs(examples []*database.SimilarEmail) []string {
+	ids := make([]string, len(examples))
+	for i, ex := range examples {
+		ids[i] = ex.EmailID
+	}
+	return ids
+}
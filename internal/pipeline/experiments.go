@@ -0,0 +1,29 @@
+package pipeline
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// assignExperimentVariant returns the user's running experiment and the variant this message belongs to.
+// Assignment is a stable hash of the message ID so retries land in the same variant.
+func (p *Processor) assignExperimentVariant(ctx context.Context, user *database.User, messageID string) (*database.PromptExperiment, string) {
+	experiment, err := p.db.GetActiveExperiment(ctx, user.ID)
+	if err != nil {
+		log.Printf("[%s] Failed to load active experiment: %v", user.Email, err)
+		return nil, ""
+	}
+	if experiment == nil {
+		return nil, ""
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(messageID))
+	if int(h.Sum32()%100) < experiment.TrafficSplit {
+		return experiment, database.ExperimentVariantB
+	}
+	return experiment, database.ExperimentVariantA
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// saveExplanation stores the exact Stage 1 and Stage 2 inputs and outputs for an email,
+// with references to the prompt versions, memories and profiles that went into them
+// and the sections trimmed to fit the context budget. Every decision path is recorded with how it
+// was reached (triageVia) and its reasoning; decisions made without a Stage 1/Stage 2 call (fast
+// paths, decision cache, rules only) have empty stages, and the security lane's stage 1 is its
+// classifier call, if one was made.
+func (p *Processor) saveExplanation(ctx context.Context, user *database.User, emailID string, prompts *systemPrompts, promptCtx *promptContext, senderProfile, domainProfile *database.SenderProfile, labelNames []string, trace *ai.Trace, triageVia, reasoning string) {
+	stage1 := trace.Find("AnalyzeEmail")
+	stage2 := trace.Find("DetermineActions")
+	if triage := trace.Find("TriageEmail"); triage != nil {
+		// Economy mode: one merged call serves as both stages
+		stage1, stage2 = triage, triage
+	}
+	if bucket := trace.Find("ClassifyBucket"); bucket != nil {
+		// Buckets mode: the bucket classification, then the bucket's processor (if it has one)
+		stage1, stage2 = bucket, trace.FindPrefix("ProcessBucket:")
+	}
+	if triageVia == database.TriageViaSecurity {
+		stage1, stage2 = trace.Find("ClassifySecurity"), nil
+	}
+
+	explanation := &database.EmailExplanation{
+		EmailID:           emailID,
+		UserID:            user.ID,
+		TriageVia:         triageVia,
+		Reasoning:         reasoning,
+		AIAnalyzePromptID: prompts.AIAnalyzePromptID,
+		AIActionsPromptID: prompts.AIActionsPromptID,
+		MemoryIDs:         promptCtx.MemoryIDs,
+		LabelNames:        labelNames,
+		ContextTrims:      promptCtx.Trims,
+		ExampleEmailIDs:   promptCtx.ExampleEmailIDs,
+	}
+	var stage1System, stage2System string
+	if stage1 != nil {
+		explanation.Model = stage1.Model
+		explanation.Stage1, stage1System = explanationStage(stage1), stage1.SystemPrompt
+	}
+	if stage2 != nil {
+		explanation.Model = stage2.Model
+		explanation.Stage2, stage2System = explanationStage(stage2), stage2.SystemPrompt
+	}
+	if explanation.LabelNames == nil {
+		explanation.LabelNames = []string{}
+	}
+	if senderProfile != nil && senderProfile.ID != 0 {
+		explanation.SenderProfileID = &senderProfile.ID
+	}
+	if domainProfile != nil && domainProfile.ID != 0 {
+		explanation.DomainProfileID = &domainProfile.ID
+	}
+
+	if err := p.db.CreateEmailExplanation(ctx, explanation, stage1System, stage2System); err != nil {
+		log.Printf("[%s] Failed to save explanation: %v", user.Email, err)
+	}
+}
+
+func explanationStage(call *ai.TraceCall) database.ExplanationStage {
+	return database.ExplanationStage{
+		UserPrompt:       call.UserPrompt,
+		Output:           call.Output,
+		PromptTokens:     call.PromptTokens,
+		CompletionTokens: call.CompletionTokens,
+	}
+}
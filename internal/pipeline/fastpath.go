@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// fastPath is a decision reached without calling the AI
+type fastPath struct {
+	Via       string // database.TriageViaThreadReply or database.TriageViaConsistentSender
+	Bucket    string // Bucket inherited from the thread ("" if none)
+	Reasoning string
+}
+
+// findFastPath returns a decision for an email that does not need the AI: a reply in a thread that
+// was already triaged inherits that decision, and a sender whose history agrees on the slug, labels
+// and archiving gets that decision. Returns nil when the email needs AI triage, including for
+// senders the user marked as mixed, replies in threads whose earlier decision is not inheritable
+// and senders of receipts, invoices or shipping updates, whose data only the AI extracts.
+func (p *Processor) findFastPath(ctx context.Context, user *database.User, message *gmail.Message, senderProfile *database.SenderProfile, labelNames []string) (*ai.EmailAnalysis, *ai.EmailActions, *fastPath) {
+	if senderProfile != nil && senderProfile.Mixed {
+		return nil, nil, nil
+	}
+
+	var analysis *ai.EmailAnalysis
+	var actions *ai.EmailActions
+	var fast *fastPath
+
+	if p.config.FastPathThreads && message.ThreadID != "" {
+		prior, err := p.db.GetLatestEmailInThread(ctx, user.ID, message.ThreadID, message.ID)
+		if err != nil {
+			log.Printf("[%s] Failed to look up thread %s: %v", user.Email, message.ThreadID, err)
+		} else if prior != nil {
+			if !inheritable(prior) {
+				return nil, nil, nil
+			}
+			analysis, actions, fast = threadReplyDecision(message, prior, labelNames)
+		}
+	}
+
+	if fast == nil && p.config.FastPathSenders && senderProfile != nil {
+		analysis, actions, fast = consistentSenderDecision(message, senderProfile, labelNames, p.config.FastPathMinEmails, p.config.FastPathConsistency)
+	}
+	if fast == nil {
+		return nil, nil, nil
+	}
+
+	// Receipts, invoices and shipping updates still need the AI to extract their data
+	transactional, err := p.db.IsTransactionalSender(ctx, user.ID, message.From)
+	if err != nil {
+		log.Printf("[%s] Failed to check sender transactions: %v", user.Email, err)
+		return nil, nil, nil
+	}
+	if transactional {
+		return nil, nil, nil
+	}
+	return analysis, actions, fast
+}
+
+// inheritable returns true if a thread's earlier decision can be reused for a reply. Threads the
+// user corrected or unarchived, that the AI could not decide, or that warranted a notification or a
+// draft go back to the AI, since the reply may need the same attention.
+func inheritable(prior *database.Email) bool {
+	return prior.HumanFeedback == "" &&
+		!prior.UserUnarchived &&
+		!prior.NotificationSent &&
+		!prior.DraftCreated &&
+		prior.DecisionModel != "default" &&
+		prior.Slug != "unclassified"
+}
+
+// threadReplyDecision reuses the slug, labels, archiving and bucket of the thread's earlier email
+func threadReplyDecision(message *gmail.Message, prior *database.Email, labelNames []string) (*ai.EmailAnalysis, *ai.EmailActions, *fastPath) {
+	fast := &fastPath{
+		Via:       database.TriageViaThreadReply,
+		Bucket:    prior.Bucket,
+		Reasoning: fmt.Sprintf("Reply in a thread already triaged: inherited the decision for %s", prior.ID),
+	}
+	analysis := &ai.EmailAnalysis{
+		Slug:     prior.Slug,
+		Keywords: append([]string{}, prior.Keywords...),
+		Summary:  message.Subject,
+	}
+	actions := &ai.EmailActions{
+		Labels:      knownLabels(prior.LabelsApplied, labelNames),
+		BypassInbox: prior.BypassedInbox,
+		Reasoning:   fast.Reasoning,
+		Model:       database.TriageViaThreadReply,
+	}
+	return analysis, actions, fast
+}
+
+// consistentSenderDecision applies a sender's dominant decision when at least minEmails emails
+// agree on it: the top slug, archiving either way and each label either way must reach the
+// consistency share, and the sender must rarely warrant a notification. Returns nil otherwise.
+// Only emails decided by the AI are counted (see SenderProfile.DecidedCount), so earlier
+// fast-path decisions never vouch for this one.
+func consistentSenderDecision(message *gmail.Message, profile *database.SenderProfile, labelNames []string, minEmails int, consistency float64) (*ai.EmailAnalysis, *ai.EmailActions, *fastPath) {
+	decided := profile.DecidedCount()
+	if decided <= 0 || decided < minEmails {
+		return nil, nil, nil
+	}
+	total := float64(decided)
+	inconsistent := 1 - consistency
+
+	slug := topKey(profile.SlugCounts)
+	if slug == "" || float64(profile.SlugCounts[slug])/total < consistency {
+		return nil, nil, nil
+	}
+
+	archiveRate := profile.BypassInboxRate()
+	if archiveRate < consistency && archiveRate > inconsistent {
+		return nil, nil, nil
+	}
+	if float64(profile.EmailsNotified)/total > inconsistent {
+		return nil, nil, nil
+	}
+
+	var labels []string
+	for label, count := range profile.LabelCounts {
+		share := float64(count) / total
+		if share >= consistency {
+			labels = append(labels, label)
+		} else if share > inconsistent {
+			return nil, nil, nil
+		}
+	}
+
+	fast := &fastPath{
+		Via: database.TriageViaConsistentSender,
+		Reasoning: fmt.Sprintf("Consistent sender: %d previous emails from %s (%.0f%% %s, archive rate %.0f%%)",
+			decided, profile.Identifier, float64(profile.SlugCounts[slug])/total*100, slug, archiveRate*100),
+	}
+	analysis := &ai.EmailAnalysis{
+		Slug:     slug,
+		Keywords: topKeys(profile.KeywordCounts, 5),
+		Summary:  message.Subject,
+	}
+	actions := &ai.EmailActions{
+		Labels:      knownLabels(labels, labelNames),
+		BypassInbox: archiveRate >= consistency,
+		Reasoning:   fast.Reasoning,
+		Model:       database.TriageViaConsistentSender,
+	}
+	return analysis, actions, fast
+}
+
+// knownLabels keeps the labels the user still has, plus timed labels, sorted
+func knownLabels(labels []string, labelNames []string) []string {
+	known := make(map[string]bool, len(labelNames))
+	for _, name := range labelNames {
+		known[name] = true
+	}
+	kept := []string{}
+	for _, label := range labels {
+		if known[label] || strings.HasPrefix(label, "📥/") || strings.HasPrefix(label, "🗑️/") {
+			kept = append(kept, label)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// topKeys returns up to n keys with the highest counts (ties broken alphabetically)
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// apply records the fast path and any inherited bucket on the email
+func (f *fastPath) apply(email *database.Email) {
+	if f == nil {
+		return
+	}
+	email.TriageVia = f.Via
+	if f.Bucket != "" {
+		email.Bucket = f.Bucket
+		email.TriageReasoning = f.Reasoning
+	}
+}
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// ErrNoHeldNotification is returned when releasing an email that has no held notification
+var ErrNoHeldNotification = errors.New("email has no held notification")
+
+// checkInjection scans an email for prompt-injection attempts. When the classifier is enabled
+// and useAI is set, it confirms or clears emails the heuristics flag.
+func (p *Processor) checkInjection(ctx context.Context, user *database.User, from, subject, body string, useAI bool) *ai.InjectionReport {
+	report := ai.DetectInjection(subject, body)
+	if report.Score == 0 {
+		return report
+	}
+
+	if p.config.InjectionClassifier && useAI {
+		verdict, err := p.ai.ClassifyInjection(ctx, from, subject, body)
+		if err != nil {
+			log.Printf("[%s] Injection classifier failed, keeping heuristic score: %v", user.Email, err)
+		} else {
+			report.ApplyVerdict(verdict)
+		}
+	}
+
+	if report.Suspicious() {
+		log.Printf("[%s] Suspected prompt injection (score %.2f): %v", user.Email, report.Score, report.Signals)
+	}
+	return report
+}
+
+// isNewSender returns true when there is no history with the sender
+func isNewSender(profile *database.SenderProfile) bool {
+	return profile == nil || profile.EmailCount == 0
+}
+
+// capSuspiciousActions limits what a suspected injection can trigger. The email always stays in
+// the inbox; from a new sender, the notification is held for the user to confirm and no draft is
+// written. Returns the held notification message ("" if none).
+func capSuspiciousActions(report *ai.InjectionReport, senderProfile *database.SenderProfile, actions *ai.EmailActions) string {
+	if !report.Suspicious() {
+		return ""
+	}
+
+	var capped []string
+	if actions.BypassInbox {
+		actions.BypassInbox = false
+		capped = append(capped, "kept in inbox")
+	}
+
+	var held string
+	if isNewSender(senderProfile) {
+		if actions.NotificationMessage != "" {
+			held = actions.NotificationMessage
+			actions.NotificationMessage = ""
+			capped = append(capped, "notification held for confirmation")
+		}
+		if actions.DraftReply {
+			actions.DraftReply = false
+			capped = append(capped, "draft skipped")
+		}
+	}
+
+	if len(capped) > 0 {
+		actions.Reasoning += " [Suspected prompt injection: " + strings.Join(capped, ", ") + "]"
+	}
+	return held
+}
+
+// ReleaseHeldNotification sends a notification that was held because the email looked like a
+// prompt injection, after the user has confirmed it
+func (p *Processor) ReleaseHeldNotification(ctx context.Context, user *database.User, emailID string) error {
+	email, err := p.db.GetEmailByID(ctx, user.ID, emailID)
+	if err != nil {
+		return err
+	}
+	if email == nil || email.HeldNotification == "" {
+		return ErrNoHeldNotification
+	}
+
+	// Claim the held notification first so concurrent confirmations send it once
+	released, err := p.db.ReleaseHeldNotification(ctx, user.ID, emailID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrNoHeldNotification
+	}
+
+	p.sendNotification(ctx, user, &outgoingNotification{
+		EmailID: email.ID,
+		From:    email.FromAddress,
+		Subject: email.Subject,
+		Slug:    email.Slug,
+		Labels:  email.LabelsApplied,
+		Message: email.HeldNotification,
+	})
+	return nil
+}
@@ -0,0 +1,308 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+	"golang.org/x/net/idna"
+)
+
+// knownDomainMinEmails is how many emails make a domain known for the lookalike check
+const knownDomainMinEmails = 3
+
+// phishingReport is the phishing and spoofing risk of an email
+type phishingReport struct {
+	Score   float64  // 0-1
+	Signals []string // Human-readable reasons, empty when nothing was found
+}
+
+// highRisk returns true when the score reaches database.PhishingThreshold
+func (r *phishingReport) highRisk() bool {
+	return r != nil && r.Score >= database.PhishingThreshold
+}
+
+// Requests a first-contact sender should not be making
+var (
+	paymentRequestPattern    = regexp.MustCompile(`(?i)\b(wire|bank) transfer\b|\bbank (account )?details\b|\bgift ?cards?\b|\b(payment|invoice) (is )?(overdue|past due|required|outstanding)\b|\bpay (the|this) invoice\b|\bupdate (your )?(payment|billing|banking) (details|information|info|method)\b|\bnew (bank|payment) (account|details)\b`)
+	credentialRequestPattern = regexp.MustCompile(`(?i)\b(verify|confirm|validate|update) your (account|identity|password|login|credentials)\b|\b(enter|provide|re-?enter) your (password|credentials|login details)\b|\byour (account|mailbox) (will be|has been) (suspended|locked|disabled|deactivated|closed)\b`)
+)
+
+// displayNameAddressPattern finds an email address written into a display name
+var displayNameAddressPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+)`)
+
+// checkPhishing scores an email for phishing and spoofing: failed sender authentication, a display
+// name that claims someone else, a domain imitating one the user knows, and a first-contact sender
+// asking for payment or credentials
+func (p *Processor) checkPhishing(ctx context.Context, user *database.User, message *gmail.Message, body string, senderProfile *database.SenderProfile) *phishingReport {
+	// Free email providers have no domain profiles, so consumer domains never count as brands
+	known, err := p.db.GetKnownDomains(ctx, user.ID, knownDomainMinEmails)
+	if err != nil {
+		log.Printf("[%s] Failed to load known domains, skipping lookalike check: %v", user.Email, err)
+	}
+
+	report := assessPhishing(message, body, isNewSender(senderProfile), known)
+	if report.Score > 0 {
+		log.Printf("[%s] Phishing risk %.2f: %v", user.Email, report.Score, report.Signals)
+	}
+	return report
+}
+
+// assessPhishing scores an email from its authentication results, display name, domain and content
+func assessPhishing(message *gmail.Message, body string, newSender bool, knownDomains []string) *phishingReport {
+	report := &phishingReport{Signals: []string{}}
+	add := func(weight float64, signal string) {
+		report.Score += weight
+		report.Signals = append(report.Signals, signal)
+	}
+
+	if auth := message.Auth; auth != nil {
+		if auth.DMARC == "fail" {
+			add(0.5, "DMARC failed")
+		}
+		switch auth.SPF {
+		case "fail":
+			add(0.3, "SPF failed")
+		case "softfail":
+			add(0.15, "SPF soft-failed")
+		}
+		if auth.DKIM == "fail" {
+			add(0.2, "DKIM failed")
+		}
+	}
+
+	domain := database.ExtractDomain(message.From)
+	if domain != "" && !isKnownDomain(domain, knownDomains) {
+		if strings.HasPrefix(domain, "xn--") || strings.Contains(domain, ".xn--") {
+			add(0.2, "internationalised (punycode) domain "+domain)
+		}
+		if imitated, weight := lookalikeOf(unicodeDomain(domain), knownDomains); imitated != "" {
+			add(weight, fmt.Sprintf("domain %s imitates %s", domain, imitated))
+			// A lookalike alone stays below the threshold; a first contact from it corroborates
+			if newSender {
+				add(0.2, "first email from "+domain)
+			}
+		}
+	}
+
+	if m := displayNameAddressPattern.FindStringSubmatch(message.FromName); m != nil && !sameSite(strings.ToLower(m[1]), domain) {
+		add(0.4, fmt.Sprintf("display name shows %s but the sender is %s", m[0], message.From))
+	} else if claimed := claimedDomain(message.FromName, domain, knownDomains); claimed != "" {
+		add(0.3, fmt.Sprintf("display name %q claims %s but the sender is %s", message.FromName, claimed, message.From))
+	}
+
+	if newSender {
+		content := message.Subject + "\n" + body
+		if paymentRequestPattern.MatchString(content) {
+			add(0.4, "first contact asking for payment")
+		}
+		if credentialRequestPattern.MatchString(content) {
+			add(0.4, "first contact asking for credentials")
+		}
+	}
+
+	if report.Score > 1 {
+		report.Score = 1
+	}
+	return report
+}
+
+// capRiskyActions limits what a high-risk email can trigger: it gets the warning label, no draft
+// reply is written, and its notification carries a warning instead of any links
+func capRiskyActions(report *phishingReport, label string, actions *ai.EmailActions) {
+	if !report.highRisk() {
+		return
+	}
+	if label != "" && !containsString(actions.Labels, label) {
+		actions.Labels = append(actions.Labels, label)
+	}
+	actions.DraftReply = false
+	if actions.NotificationMessage != "" {
+		actions.NotificationMessage = stripLinks(actions.NotificationMessage)
+		if !strings.HasPrefix(actions.NotificationMessage, "⚠️") {
+			actions.NotificationMessage = "⚠️ Possible phishing: " + actions.NotificationMessage
+		}
+	}
+	actions.Reasoning += " [Possible phishing: " + strings.Join(report.Signals, "; ") + "]"
+}
+
+// linkPattern matches URLs and bare domains with a path
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+|\b[a-z0-9-]+(\.[a-z0-9-]+)+/\S*`)
+
+// stripLinks removes URLs from a notification message
+func stripLinks(message string) string {
+	return strings.Join(strings.Fields(linkPattern.ReplaceAllString(message, "[link removed]")), " ")
+}
+
+// containsString returns true if the slice holds the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownDomain returns true if the domain, or a domain it is a subdomain of, is known
+func isKnownDomain(domain string, knownDomains []string) bool {
+	for _, known := range knownDomains {
+		if sameSite(domain, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSite returns true if the domains are equal or one is a subdomain of the other
+func sameSite(a, b string) bool {
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// lookalikeOf returns the known domain the domain imitates ("" if none) with the signal's weight.
+// Every weight is below database.PhishingThreshold, so a lookalike needs a second signal (first
+// contact, failed authentication, a claiming display name) to make an email high risk. The same
+// name once homoglyphs are folded ("paypa1.com", "rnicrosoft.com") weighs most; a known domain used
+// as a subdomain ("paypal.com.account-help.io") or a known name with words added
+// ("paypal-community.com") is often legitimate and weighs less; a name one or two edits away
+// ("amazom.com") may be a coincidence and weighs least.
+func lookalikeOf(domain string, knownDomains []string) (string, float64) {
+	name := domainName(domain)
+	folded := foldHomoglyphs(name)
+	var nearest string
+	for _, known := range knownDomains {
+		knownName := domainName(known)
+		if len(knownName) < 4 || knownName == name {
+			continue
+		}
+		if folded == foldHomoglyphs(knownName) {
+			return known, 0.4
+		}
+		if usedAsSubdomain(domain, known) {
+			return known, 0.3
+		}
+		for _, word := range strings.Split(name, "-") {
+			if word == knownName {
+				return known, 0.3
+			}
+		}
+		distance := editDistance(folded, foldHomoglyphs(knownName))
+		if (distance == 1 && len(knownName) >= 5) || (distance == 2 && len(knownName) >= 9) {
+			nearest = known
+		}
+	}
+	if nearest != "" {
+		return nearest, 0.25
+	}
+	return "", 0
+}
+
+// unicodeDomain decodes a punycode domain to the characters it renders as, so homoglyph folding
+// sees "xn--pypal-4ve.com" as "pаypal.com" (Cyrillic а). Domains that fail to decode are unchanged.
+func unicodeDomain(domain string) string {
+	decoded, err := idna.ToUnicode(domain)
+	if err != nil {
+		return domain
+	}
+	return decoded
+}
+
+// usedAsSubdomain returns true if the known domain appears as the leading labels of a host on
+// another registered domain: "paypal.com.account-help.io", "login.paypal.com.evil.net". Country
+// variants such as "paypal.com.au" are not flagged.
+func usedAsSubdomain(domain, known string) bool {
+	idx := strings.Index("."+domain, "."+known+".")
+	if idx < 0 {
+		return false
+	}
+	rest := domain[idx+len(known)+1:]
+	return strings.Contains(rest, ".") && !isCountrySuffix(rest)
+}
+
+// isCountrySuffix returns true for two-part country suffixes such as co.uk and com.au
+func isCountrySuffix(suffix string) bool {
+	labels := strings.Split(suffix, ".")
+	return len(labels) == 2 && len(labels[1]) == 2 && countrySecondLevel[labels[0]]
+}
+
+// countrySecondLevel are the second-level labels of two-part country suffixes
+var countrySecondLevel = map[string]bool{"co": true, "com": true, "org": true, "net": true, "ac": true, "gov": true, "edu": true, "ltd": true, "plc": true}
+
+// claimedDomain returns the known domain whose name the display name uses while the sender
+// is elsewhere, e.g. "PayPal Support" from paypal-help.net ("" if none)
+func claimedDomain(displayName, domain string, knownDomains []string) string {
+	if displayName == "" {
+		return ""
+	}
+	words := strings.FieldsFunc(strings.ToLower(displayName), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	for _, known := range knownDomains {
+		knownName := domainName(known)
+		if len(knownName) < 4 || sameSite(domain, known) {
+			continue
+		}
+		for _, word := range words {
+			if word == knownName {
+				return known
+			}
+		}
+	}
+	return ""
+}
+
+// domainName is the registered name of a domain without its suffix:
+// "mail.paypal.com" → "paypal", "shop.example.co.uk" → "example"
+func domainName(domain string) string {
+	labels := strings.Split(strings.ToLower(domain), ".")
+	if len(labels) < 2 {
+		return domain
+	}
+	suffix := 1
+	if len(labels) >= 3 && isCountrySuffix(strings.Join(labels[len(labels)-2:], ".")) {
+		suffix = 2
+	}
+	return labels[len(labels)-1-suffix]
+}
+
+// homoglyphs maps look-alike characters and sequences to the letter they imitate
+var homoglyphs = strings.NewReplacer(
+	"rn", "m", "vv", "w", "cl", "d",
+	"0", "o", "1", "l", "3", "e", "5", "s", "7", "t", "8", "b", "!", "i", "|", "l",
+	// Cyrillic and Greek letters that render like Latin ones
+	"а", "a", "е", "e", "о", "o", "р", "p", "с", "c", "у", "y", "х", "x", "і", "i", "ј", "j", "ѕ", "s", "ԁ", "d", "ɡ", "g",
+	"α", "a", "ο", "o", "ρ", "p", "ν", "v", "ι", "i",
+)
+
+// foldHomoglyphs maps a domain name to the letters it appears to spell; "i" and "l" are merged
+// since they are indistinguishable in many fonts
+func foldHomoglyphs(name string) string {
+	return strings.ReplaceAll(homoglyphs.Replace(strings.ToLower(name)), "i", "l")
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+var testKnownDomains = []string{"paypal.com", "microsoft.com", "amazon.com", "apple.com", "bankofamerica.com", "ebay.com"}
+
+func TestLookalikeOf(t *testing.T) {
+	tests := []struct {
+		domain     string
+		wantDomain string
+		wantWeight float64
+	}{
+		// Same name once homoglyphs are folded
+		{"paypa1.com", "paypal.com", 0.4},
+		{"rnicrosoft.com", "microsoft.com", 0.4},
+		{"pаypal.com", "paypal.com", 0.4}, // Cyrillic а
+		{"paypal.net", "", 0},             // Same name, different suffix: not a lookalike
+
+		// Known domain used as a subdomain, or a known name with words added
+		{"paypal.com.account-help.io", "paypal.com", 0.3},
+		{"login.paypal.com.evil.net", "paypal.com", 0.3},
+		{"paypal-community.com", "paypal.com", 0.3},
+
+		// Near misses
+		{"amazom.com", "amazon.com", 0.25},
+		{"bankofamerlca.com", "bankofamerica.com", 0.4},
+		{"bankofamerrica.com", "bankofamerica.com", 0.25},
+		{"apply.com", "apple.com", 0.25},
+		{"ebey.com", "", 0}, // One edit from a four-letter name: too likely a coincidence
+
+		// Country variants and unrelated domains
+		{"paypal.com.au", "", 0},
+		{"paypal.co.uk", "", 0},
+		{"example.org", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			gotDomain, gotWeight := lookalikeOf(tt.domain, testKnownDomains)
+			if gotDomain != tt.wantDomain || gotWeight != tt.wantWeight {
+				t.Errorf("lookalikeOf(%q) = (%q, %v), want (%q, %v)", tt.domain, gotDomain, gotWeight, tt.wantDomain, tt.wantWeight)
+			}
+		})
+	}
+}
+
+func TestFoldHomoglyphs(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"paypal", "paypal"},
+		{"paypa1", "paypal"},
+		{"rnicrosoft", "mlcrosoft"},
+		{"micr0soft", "mlcrosoft"},
+		{"pаypal", "paypal"}, // Cyrillic а
+		{"аррle", "apple"},   // Cyrillic а and р
+		{"vvalmart", "walmart"},
+	}
+	for _, tt := range tests {
+		if got := foldHomoglyphs(tt.in); got != tt.want {
+			t.Errorf("foldHomoglyphs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"amazon", "amazon", 0},
+		{"amazom", "amazon", 1},
+		{"amazn", "amazon", 1},
+		{"amazoon", "amazon", 1},
+		{"mazona", "amazon", 2},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUnicodeDomain(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"xn--pypal-4ve.com", "pаypal.com"},
+		{"mail.xn--micrsoft-qbh.com", "mail.micrоsoft.com"},
+		{"paypal.com", "paypal.com"},
+	}
+	for _, tt := range tests {
+		if got := unicodeDomain(tt.in); got != tt.want {
+			t.Errorf("unicodeDomain(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAssessPhishing(t *testing.T) {
+	const paymentBody = "Please pay this invoice by wire transfer today."
+
+	tests := []struct {
+		name      string
+		message   gmail.Message
+		body      string
+		newSender bool
+		wantScore float64
+		highRisk  bool
+	}{
+		// Single signals stay below the threshold
+		{name: "lookalike from a known sender", message: gmail.Message{From: "service@paypa1.com"}, wantScore: 0.4},
+		{name: "dmarc failed", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{DMARC: "fail"}}, wantScore: 0.5},
+		{name: "spf failed", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{SPF: "fail"}}, wantScore: 0.3},
+		{name: "spf soft-failed", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{SPF: "softfail"}}, wantScore: 0.15},
+		{name: "dkim failed", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{DKIM: "fail"}}, wantScore: 0.2},
+		{name: "display name shows another address", message: gmail.Message{From: "a@example.org", FromName: "billing@paypal.com"}, wantScore: 0.4},
+		{name: "display name claims a known brand", message: gmail.Message{From: "a@example.org", FromName: "PayPal Support"}, wantScore: 0.3},
+		{name: "first contact asking for payment", message: gmail.Message{From: "a@example.org"}, body: paymentBody, newSender: true, wantScore: 0.4},
+		{name: "punycode domain", message: gmail.Message{From: "a@xn--mnchen-3ya.de"}, wantScore: 0.2},
+
+		// Corroborated signals reach it
+		{name: "lookalike on first contact", message: gmail.Message{From: "service@paypa1.com"}, newSender: true, wantScore: 0.6, highRisk: true},
+		{name: "punycode homoglyph on first contact", message: gmail.Message{From: "service@xn--pypal-4ve.com"}, newSender: true, wantScore: 0.8, highRisk: true},
+		{name: "dmarc and spf failed", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{DMARC: "fail", SPF: "fail"}}, wantScore: 0.8, highRisk: true},
+		{name: "claimed brand asking for payment", message: gmail.Message{From: "a@example.org", FromName: "PayPal"}, body: paymentBody, newSender: true, wantScore: 0.7, highRisk: true},
+
+		// Known domains and country variants are not flagged
+		{name: "known domain", message: gmail.Message{From: "service@paypal.com", FromName: "PayPal"}, newSender: true},
+		{name: "known subdomain", message: gmail.Message{From: "service@mail.paypal.com", FromName: "service@paypal.com"}},
+		{name: "country variant", message: gmail.Message{From: "service@paypal.co.uk", FromName: "PayPal UK"}, wantScore: 0.3},
+		{name: "country suffix", message: gmail.Message{From: "service@paypal.com.au"}, newSender: true},
+		{name: "passing authentication", message: gmail.Message{From: "a@example.org", Auth: &gmail.AuthResults{DMARC: "pass", SPF: "pass", DKIM: "pass"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := assessPhishing(&tt.message, tt.body, tt.newSender, testKnownDomains)
+			if diff := report.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("score = %v, want %v (signals %v)", report.Score, tt.wantScore, report.Signals)
+			}
+			if report.highRisk() != tt.highRisk {
+				t.Errorf("highRisk = %v, want %v (threshold %v)", report.highRisk(), tt.highRisk, database.PhishingThreshold)
+			}
+		})
+	}
+}
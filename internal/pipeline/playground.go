@@ -0,0 +1,277 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// ErrInvalidPlaygroundRequest is returned for playground requests that cannot be run as given
+var ErrInvalidPlaygroundRequest = errors.New("invalid playground request")
+
+// PlaygroundRequest describes a dry run of the pipeline.
+// The email comes from EmailID (fetched from Gmail), RawMessage (RFC 822) or From/Subject/Body.
+type PlaygroundRequest struct {
+	EmailID       string `json:"email_id"`
+	RawMessage    string `json:"raw_message"`
+	From          string `json:"from"`
+	Subject       string `json:"subject"`
+	Body          string `json:"body"`
+	AnalyzePrompt string `json:"analyze_prompt"` // Overrides the assembled Stage 1 system prompt
+	ActionsPrompt string `json:"actions_prompt"` // Overrides the assembled Stage 2 system prompt
+	Model         string `json:"model"`
+	Mode          string `json:"mode"` // Pipeline mode to dry-run; empty uses the user's setting
+	IncludeDraft  bool   `json:"include_draft"`
+}
+
+// Validate checks the request before any email is fetched or AI call is made
+func (req *PlaygroundRequest) Validate() error {
+	if req.Mode != "" && !database.IsValidPipelineMode(req.Mode) {
+		return fmt.Errorf("%w: mode must be \"two_stage\", \"economy\" or \"buckets\"", ErrInvalidPlaygroundRequest)
+	}
+	if req.EmailID == "" && strings.TrimSpace(req.RawMessage) == "" && strings.TrimSpace(req.Subject) == "" && strings.TrimSpace(req.Body) == "" {
+		return fmt.Errorf("%w: email_id, raw_message or subject/body is required", ErrInvalidPlaygroundRequest)
+	}
+	return nil
+}
+
+// PlaygroundResult holds everything the pipeline would have done, without applying it
+type PlaygroundResult struct {
+	From             string                 `json:"from"`
+	Subject          string                 `json:"subject"`
+	Body             string                 `json:"body"`
+	Model            string                 `json:"model"`
+	Mode             string                 `json:"mode"`
+	Analysis         *ai.EmailAnalysis      `json:"analysis"`
+	Actions          *ai.EmailActions       `json:"actions"`
+	Bucket           *ai.BucketTriage       `json:"bucket,omitempty"`        // Buckets mode: the bucket classification
+	BucketResult     *ai.BucketResult       `json:"bucket_result,omitempty"` // Buckets mode: the bucket processor's output
+	Draft            string                 `json:"draft,omitempty"`
+	Calls            []ai.TraceCall         `json:"calls"`
+	ContextTrims     []database.ContextTrim `json:"context_trims"`               // Sections cut to fit the context budget
+	ExampleEmailIDs  []string               `json:"example_email_ids"`           // Similar past emails used as few-shot examples
+	Injection        *ai.InjectionReport    `json:"injection"`                   // Prompt-injection scan of the email
+	HeldNotification string                 `json:"held_notification,omitempty"` // Notification the pipeline would hold for confirmation
+	Redactions       map[string]int         `json:"redactions"`                  // Values replaced with placeholders, per kind
+	PromptTokens     int                    `json:"prompt_tokens"`
+	CompletionTokens int                    `json:"completion_tokens"`
+	CostUSD          float64                `json:"cost_usd"` // Estimated from the price table
+}
+
+// RunPlayground runs Stage 1, Stage 2 (or the bucket stages) and optionally the draft with the user's real labels,
+// memories and sender profiles. Nothing is written to Gmail or the database.
+func (p *Processor) RunPlayground(ctx context.Context, user *database.User, req *PlaygroundRequest) (*PlaygroundResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	from, subject, body, err := p.resolvePlaygroundEmail(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+	return p.dryRun(ctx, user, req, from, subject, body)
+}
+
+// dryRun runs the playground request against an email that is already fetched and cleaned
+func (p *Processor) dryRun(ctx context.Context, user *database.User, req *PlaygroundRequest, from, subject, body string) (*PlaygroundResult, error) {
+	var err error
+	if from == "" && subject == "" && strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: the email has no sender, subject or body", ErrInvalidPlaygroundRequest)
+	}
+
+	// Show the email as the AI sees it, with personal data replaced by placeholders
+	subject, body, redactions := p.redactEmail(ctx, user, from, subject, body)
+
+	prompts := p.loadSystemPrompts(ctx, user.ID, nil)
+	if req.AnalyzePrompt != "" {
+		prompts.Analyze = req.AnalyzePrompt
+	}
+	if req.ActionsPrompt != "" {
+		prompts.Actions = req.ActionsPrompt
+	}
+
+	aiClient := p.ai.WithModel(req.Model)
+	ctx = ai.WithUser(ctx, user.ID)
+	ctx, usage := ai.WithUsage(ctx)
+	ctx, trace := ai.WithTrace(ctx)
+
+	// Use existing profiles only; bootstrapping would write to the database
+	domain := database.ExtractDomain(from)
+	senderProfile, _ := p.db.GetSenderProfile(ctx, user.ID, database.ProfileTypeSender, from)
+	var domainProfile *database.SenderProfile
+	if !database.IsIgnoredDomain(domain) {
+		domainProfile, _ = p.db.GetSenderProfile(ctx, user.ID, database.ProfileTypeDomain, domain)
+	}
+
+	result := &PlaygroundResult{
+		From:       from,
+		Subject:    subject,
+		Body:       body,
+		Redactions: redactions.Counts(),
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = user.PipelineMode
+	}
+	result.Mode = mode
+
+	promptCtx, labelNames := p.buildPromptContext(ctx, user, body, senderProfile, domainProfile)
+	result.ContextTrims = promptCtx.Trims
+	result.Injection = p.checkInjection(ctx, user, from, subject, body, true)
+	promptCtx.SenderContext = ai.InjectionNotice(result.Injection) + promptCtx.SenderContext
+	if mode == database.PipelineModeBuckets {
+		var outcome *bucketOutcome
+		result.Analysis, result.Actions, outcome, err = p.runBuckets(ctx, user, aiClient, from, subject, promptCtx, labelNames)
+		if outcome != nil {
+			result.Bucket, result.BucketResult = outcome.Triage, outcome.Result
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if mode == database.PipelineModeEconomy {
+		result.Analysis, result.Actions, err = aiClient.TriageEmail(ctx, from, subject, promptCtx.Body, labelNames, promptCtx.FormattedLabels, promptCtx.SenderContext, promptCtx.MemoryContext, prompts.Analyze, prompts.Actions)
+		if err != nil {
+			return nil, fmt.Errorf("triage failed: %w", err)
+		}
+	} else {
+		result.Analysis, err = aiClient.AnalyzeEmail(ctx, from, subject, promptCtx.Body, promptCtx.SenderContext, prompts.Analyze)
+		if err != nil {
+			return nil, fmt.Errorf("stage 1 failed: %w", err)
+		}
+
+		vector, embeddingModel := p.embedEmail(ctx, user, subject, result.Analysis.Summary)
+		examples := p.findExamples(ctx, user, req.EmailID, vector, embeddingModel, redactions)
+		result.ExampleEmailIDs = exampleIDs(examples)
+
+		result.Actions, err = aiClient.DetermineActions(ctx, from, subject, result.Analysis.Slug, result.Analysis.Keywords, result.Analysis.Summary, labelNames, promptCtx.FormattedLabels, promptCtx.SenderContext, promptCtx.MemoryContext+formatExamples(examples), prompts.Actions)
+		if err != nil {
+			return nil, fmt.Errorf("stage 2 failed: %w", err)
+		}
+	}
+
+	result.HeldNotification = capSuspiciousActions(result.Injection, senderProfile, result.Actions)
+
+	if req.IncludeDraft {
+		result.Draft, err = aiClient.GenerateDraftReply(ctx, from, subject, promptCtx.Body, promptCtx.SenderContext, prompts.Actions)
+		if err != nil {
+			return nil, fmt.Errorf("draft failed: %w", err)
+		}
+		result.Draft = redactions.Restore(result.Draft)
+	}
+
+	result.Calls = trace.Calls()
+	for _, call := range result.Calls {
+		if call.Name != "ClassifyInjection" {
+			result.Model = call.Model
+			break
+		}
+	}
+	result.PromptTokens, result.CompletionTokens = usage.Totals()
+	result.CostUSD = usage.Cost()
+	return result, nil
+}
+
+// resolvePlaygroundEmail returns the from, subject and prepared body for a playground request
+func (p *Processor) resolvePlaygroundEmail(ctx context.Context, user *database.User, req *PlaygroundRequest) (string, string, string, error) {
+	switch {
+	case req.EmailID != "":
+		client, err := gmail.NewClient(ctx, p.oauthConfig, user.GetOAuth2Token())
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to create gmail client: %w", err)
+		}
+		message, err := client.GetMessage(ctx, req.EmailID)
+		if err != nil {
+			return "", "", "", err
+		}
+		return message.From, message.Subject, prepareBody(message.Body), nil
+
+	case req.RawMessage != "":
+		return parseRawMessage(req.RawMessage)
+
+	default:
+		return req.From, req.Subject, cleanBody(req.Body), nil
+	}
+}
+
+// parseRawMessage extracts the sender, subject and text body from an RFC 822 message
+func parseRawMessage(raw string) (string, string, string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: failed to parse raw message: %v", ErrInvalidPlaygroundRequest, err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	from := msg.Header.Get("From")
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.Address
+	}
+
+	bodies := map[string]string{}
+	if err := readTextBodies(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, bodies); err != nil {
+		return "", "", "", fmt.Errorf("%w: failed to read message body: %v", ErrInvalidPlaygroundRequest, err)
+	}
+	body := bodies["text/plain"]
+	if body == "" {
+		// HTML-only message; the preprocessor renders it as text
+		body = bodies["text/html"]
+	}
+	return from, subject, cleanBody(body), nil
+}
+
+// readTextBodies collects the first text/plain and text/html parts of a (possibly multipart) MIME
+// body into bodies, keyed by media type
+func readTextBodies(contentType, transferEncoding string, r io.Reader, bodies map[string]string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := readTextBodies(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, bodies); err != nil {
+				return err
+			}
+		}
+	}
+
+	if (mediaType != "text/plain" && mediaType != "text/html") || bodies[mediaType] != "" {
+		return nil
+	}
+
+	switch strings.ToLower(transferEncoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+	bodies[mediaType] = buf.String()
+	return nil
+}
@@ -8,9 +8,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/config"
 	"github.com/den/gmail-triage-assistant/internal/database"
 	"github.com/den/gmail-triage-assistant/internal/gmail"
-	"github.com/den/gmail-triage-assistant/internal/openai"
+	"github.com/den/gmail-triage-assistant/internal/llm"
+	"github.com/den/gmail-triage-assistant/internal/preprocess"
 	"github.com/den/gmail-triage-assistant/internal/pushover"
 	"github.com/den/gmail-triage-assistant/internal/webhook"
 	"golang.org/x/oauth2"
@@ -18,23 +21,27 @@ import (
 
 type Processor struct {
 	db          *database.DB
-	openai      *openai.Client
+	config      *config.Config
+	ai          *ai.Client
+	embedder    llm.Embedder // nil disables similar-email retrieval
 	oauthConfig *oauth2.Config
 	pushover    *pushover.Client
 	webhook     *webhook.Client
 }
 
-func NewProcessor(db *database.DB, openaiClient *openai.Client, oauthConfig *oauth2.Config, pushoverClient *pushover.Client, webhookClient *webhook.Client) *Processor {
+func NewProcessor(db *database.DB, cfg *config.Config, provider llm.LLM, embedder llm.Embedder, oauthConfig *oauth2.Config, pushoverClient *pushover.Client, webhookClient *webhook.Client) *Processor {
 	return &Processor{
 		db:          db,
-		openai:      openaiClient,
+		config:      cfg,
+		ai:          ai.NewClient(provider).WithEscalation(cfg.EscalationModel, cfg.EscalationThreshold),
+		embedder:    embedder,
 		oauthConfig: oauthConfig,
 		pushover:    pushoverClient,
 		webhook:     webhookClient,
 	}
 }
 
-// ProcessEmail runs the full two-stage AI pipeline on an email
+// ProcessEmail runs the AI pipeline on an email in the user's pipeline mode (two-stage, economy or buckets)
 func (p *Processor) ProcessEmail(ctx context.Context, user *database.User, message *gmail.Message) error {
 	log.Printf("[%s] Processing email: %s - %s", user.Email, message.From, message.Subject)
 
@@ -47,183 +54,224 @@ func (p *Processor) ProcessEmail(ctx context.Context, user *database.User, messa
 		return nil
 	}
 
-	// Decode body if it's base64 encoded
-	body := message.Body
-	if body != "" {
-		decoded, err := base64.URLEncoding.DecodeString(body)
-		if err == nil {
-			body = string(decoded)
-		}
+	body := prepareBody(message.Body)
+	rawBody := body
+
+	// Replace personal data with placeholders before any content is sent to the AI or embedding provider
+	subject, body, redactions := p.redactEmail(ctx, user, message.From, message.Subject, body)
+
+	// Assign an A/B experiment variant (if the user has one running)
+	experiment, variantName := p.assignExperimentVariant(ctx, user, message.ID)
+	var variant *database.ExperimentVariant
+	aiClient := p.ai
+	if experiment != nil {
+		variant = experiment.Variant(variantName)
+		aiClient = aiClient.WithModel(variant.Model)
+		log.Printf("[%s] Experiment %q - variant %s", user.Email, experiment.Name, variantName)
 	}
 
-	// Truncate body for AI processing (to save tokens)
-	if len(body) > 2000 {
-		body = body[:2000] + "..."
+	// Pipeline mode: the user's setting, unless the experiment variant overrides it
+	mode := user.PipelineMode
+	if variant != nil && variant.PipelineMode != "" {
+		mode = variant.PipelineMode
 	}
 
 	// Get custom system prompts
-	analyzePrompt := ""
-	actionsPrompt := ""
-	if prompt, err := p.db.GetSystemPrompt(ctx, user.ID, database.PromptTypeEmailAnalyze); err == nil {
-		analyzePrompt = prompt.Content
-	}
-	if prompt, err := p.db.GetSystemPrompt(ctx, user.ID, database.PromptTypeEmailActions); err == nil {
-		actionsPrompt = prompt.Content
-	}
+	prompts := p.loadSystemPrompts(ctx, user.ID, variant)
 
-	// Append AI-generated prompt supplements (if any exist)
-	if aiPrompt, err := p.db.GetLatestAIPrompt(ctx, user.ID, database.AIPromptTypeEmailAnalyze); err == nil && aiPrompt != nil {
-		if analyzePrompt != "" {
-			analyzePrompt += "\n\n" + aiPrompt.Content
-		} else {
-			analyzePrompt = aiPrompt.Content
-		}
-	}
-	if aiPrompt, err := p.db.GetLatestAIPrompt(ctx, user.ID, database.AIPromptTypeEmailActions); err == nil && aiPrompt != nil {
-		if actionsPrompt != "" {
-			actionsPrompt += "\n\n" + aiPrompt.Content
-		} else {
-			actionsPrompt = aiPrompt.Content
-		}
-	}
+	// Bill AI calls to this user and check how much of their budget is left
+	ctx = ai.WithUser(ctx, user.ID)
+	budget := p.budgetLevel(ctx, user)
 
-	// Get recent memories to provide context (1 yearly, 1 monthly, 1 weekly, up to 7 daily)
-	memoryContext := ""
-	allMemories, err := p.db.GetRecentMemoriesForContext(ctx, user.ID)
-	if err == nil && len(allMemories) > 0 {
-		memoryContext = "Past learnings from email processing:\n\n"
-		for _, mem := range allMemories {
-			memoryContext += fmt.Sprintf("**%s Memory:**\n%s\n\n", strings.ToUpper(string(mem.Type)), mem.Content)
-		}
+	// Track token usage for this email, and capture prompts for explainability if enabled
+	ctx, usage := ai.WithUsage(ctx)
+	var trace *ai.Trace
+	if p.config.ExplanationRetentionDays > 0 {
+		ctx, trace = ai.WithTrace(ctx)
 	}
 
-	// Load or bootstrap sender and domain profiles
+	// Score phishing and spoofing risk against the sender's existing profile (bootstrapping may call
+	// the AI); the From header of a high-risk email cannot be trusted
 	domain := database.ExtractDomain(message.From)
-	senderProfile := p.loadOrBootstrapProfile(ctx, user.ID, database.ProfileTypeSender, message.From, domain)
-	var domainProfile *database.SenderProfile
-	if !database.IsIgnoredDomain(domain) {
-		domainProfile = p.loadOrBootstrapProfile(ctx, user.ID, database.ProfileTypeDomain, domain, domain)
+	senderProfile, profileErr := p.db.GetSenderProfile(ctx, user.ID, database.ProfileTypeSender, message.From)
+	if profileErr != nil {
+		log.Printf("[%s] Error loading sender profile for %s: %v", user.Email, message.From, profileErr)
 	}
-	senderContext := p.formatProfilesForPrompt(senderProfile, domainProfile)
+	phishing := p.checkPhishing(ctx, user, message, rawBody, senderProfile)
 
-	// Stage 1: Analyze email content
-	analysis, err := p.openai.AnalyzeEmail(ctx, message.From, message.Subject, body, senderContext, analyzePrompt)
-	if err != nil {
-		return fmt.Errorf("stage 1 failed: %w", err)
-	}
+	// Security emails (one-time codes, password resets, login alerts) are decided by rules so the
+	// code is pushed straight away, even for experiment emails. They are classified before profiles
+	// are bootstrapped and the prompt context is assembled, neither of which they need.
+	security := p.checkSecurity(ctx, user, message, rawBody, subject, body, senderProfile, phishing, budget == BudgetOK)
 
-	log.Printf("[%s] Stage 1 - Slug: %s, Keywords: %v", user.Email, analysis.Slug, analysis.Keywords)
-
-	// Stage 2: Get user's available labels with descriptions
-	labelDetails, err := p.db.GetUserLabelsWithDetails(ctx, user.ID)
-	if err != nil {
-		log.Printf("Error getting user labels: %v", err)
-		labelDetails = nil
+	// Bootstrap missing sender and domain profiles. Profile summaries are optional AI work, skipped
+	// for security emails and once the budget runs low
+	profileAI := budget == BudgetOK && security == nil
+	if senderProfile == nil && profileErr == nil {
+		senderProfile = p.bootstrapProfile(ctx, user.ID, database.ProfileTypeSender, message.From, domain, profileAI)
+	}
+	var domainProfile *database.SenderProfile
+	if !database.IsIgnoredDomain(domain) {
+		domainProfile = p.loadOrBootstrapProfile(ctx, user.ID, database.ProfileTypeDomain, domain, domain, profileAI)
 	}
 
-	// Format labels as bullet points with quoted names and descriptions
+	// Fit body, profiles, labels and memories into the context token budget (security emails make no AI calls)
+	promptCtx := &promptContext{Body: body}
 	var labelNames []string
-	var labelLines []string
-	for _, l := range labelDetails {
-		labelNames = append(labelNames, l.Name)
-		line := fmt.Sprintf(`- "%s"`, l.Name)
-		if l.Description != "" {
-			line += ": " + l.Description
+	if security == nil {
+		promptCtx, labelNames = p.buildPromptContext(ctx, user, body, senderProfile, domainProfile)
+	}
+
+	// Thread replies and consistent senders skip the AI entirely (not for experiment emails, whose
+	// variants are compared on AI decisions, nor for high-risk emails, which may be spoofing the sender)
+	var analysis *ai.EmailAnalysis
+	var actions *ai.EmailActions
+	var fast *fastPath
+	if security != nil {
+		analysis, actions, fast = security.decision(message, p.config.SecurityLabel)
+	} else if experiment == nil && !phishing.highRisk() {
+		analysis, actions, fast = p.findFastPath(ctx, user, message, senderProfile, labelNames)
+	}
+
+	// Scan for instructions aimed at the AI; suspicious emails get a warning in the prompt
+	injection := p.checkInjection(ctx, user, message.From, subject, body, budget == BudgetOK && fast == nil)
+	promptCtx.SenderContext = ai.InjectionNotice(injection) + promptCtx.SenderContext
+
+	// Duplicate and templated emails reuse a cached decision instead of calling the AI
+	// (not in buckets mode, whose bucket prompts and assessments the cache does not hold)
+	var cacheKey string
+	if fast == nil && budget != BudgetExhausted && user.DecisionCacheEnabled && p.config.DecisionCacheTTLHours > 0 && mode != database.PipelineModeBuckets {
+		cacheKey = decisionCacheKey(message, body, prompts, promptCtx.FormattedLabels, mode, p.modelKey(aiClient))
+		analysis, actions = p.lookupCachedDecision(ctx, user, message, cacheKey)
+	}
+	cached := fast == nil && actions != nil
+	cacheable := cacheKey != "" && !cached
+
+	// Embedding of the subject and summary, reused to store this email for future retrieval
+	var vector []float32
+	var embeddingModel string
+
+	// Bucket and bucket-specific assessments (buckets mode only)
+	var buckets *bucketOutcome
+
+	// How the decision was reached; rules-only decisions are left unmarked
+	triageVia := database.TriageViaAI
+
+	if fast != nil {
+		triageVia = fast.Via
+		log.Printf("[%s] Fast path (%s) - Slug: %s, Labels: %v, Bypass: %v", user.Email, fast.Via, analysis.Slug, actions.Labels, actions.BypassInbox)
+	} else if cached {
+		triageVia = database.TriageViaCache
+		log.Printf("[%s] Decision cache hit - Slug: %s", user.Email, analysis.Slug)
+	} else if budget == BudgetExhausted {
+		triageVia = ""
+		analysis, actions = rulesOnlyTriage(message, senderProfile, domainProfile, labelNames)
+	} else if mode == database.PipelineModeEconomy {
+		// Economy mode: Stage 1 and Stage 2 in one merged call
+		analysis, actions, err = aiClient.TriageEmail(ctx, message.From, subject, promptCtx.Body, labelNames, promptCtx.FormattedLabels, promptCtx.SenderContext, promptCtx.MemoryContext, prompts.Analyze, prompts.Actions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("triage failed: %w", err)
+			}
+			log.Printf("[%s] Economy triage failed, leaving email in inbox unlabelled: %v", user.Email, err)
+			analysis, actions = defaultAnalysis(message), defaultActions(err)
+			cacheable = false
 		}
-		if len(l.Reasons) > 0 {
-			line += " (e.g. " + strings.Join(l.Reasons, ", ") + ")"
+
+		log.Printf("[%s] Economy triage - Slug: %s, Keywords: %v", user.Email, analysis.Slug, analysis.Keywords)
+	} else if mode == database.PipelineModeBuckets {
+		// Buckets mode: classify into a bucket, then run that bucket's processor and default actions
+		analysis, actions, buckets, err = p.runBuckets(ctx, user, aiClient, message.From, subject, promptCtx, labelNames)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("bucket pipeline failed: %w", err)
+			}
+			log.Printf("[%s] Bucket pipeline failed, leaving email in inbox unlabelled: %v", user.Email, err)
+			analysis, actions = defaultAnalysis(message), defaultActions(err)
+		}
+
+		if buckets != nil {
+			log.Printf("[%s] Bucket - %s (%.2f): %s", user.Email, buckets.Triage.Bucket, buckets.Triage.Confidence, buckets.Triage.Reasoning)
+		}
+	} else {
+		// Stage 1: Analyze email content
+		// Failures after retries and fallbacks degrade to safe defaults so the checkpoint can advance
+		analysis, err = aiClient.AnalyzeEmail(ctx, message.From, subject, promptCtx.Body, promptCtx.SenderContext, prompts.Analyze)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("stage 1 failed: %w", err)
+			}
+			log.Printf("[%s] Stage 1 failed, using default analysis: %v", user.Email, err)
+			analysis = defaultAnalysis(message)
+			cacheable = false
 		}
-		labelLines = append(labelLines, line)
-	}
-	formattedLabels := strings.Join(labelLines, "\n")
-
-	// Append timed action labels
-	formattedLabels += "\n\n--- Timed Action Labels (system) ---\n"
-	formattedLabels += `- "📥/1d": Archive this email after 1 day (use for time-sensitive items the user should see briefly)` + "\n"
-	formattedLabels += `- "📥/1w": Archive this email after 1 week (use for newsletters, digests, weekly content)` + "\n"
-	formattedLabels += `- "📥/1m": Archive this email after 1 month` + "\n"
-	formattedLabels += `- "📥/1y": Archive this email after 1 year` + "\n"
-	formattedLabels += `- "📥/read": Archive this email after the user reads it (use for emails worth glancing at but not keeping in inbox)` + "\n"
-	formattedLabels += `- "🗑️/1d": Delete this email after 1 day (use for truly disposable emails like OTP codes, shipping notifications after delivery)` + "\n"
-	formattedLabels += `- "🗑️/1w": Delete this email after 1 week` + "\n"
-	formattedLabels += `- "🗑️/1m": Delete this email after 1 month` + "\n"
-	formattedLabels += `- "🗑️/1y": Delete this email after 1 year` + "\n"
-	formattedLabels += "\nYou may apply ONE timed label alongside regular labels. Use these instead of bypass_inbox when the user might want to see the email briefly before it's archived. Use delete labels sparingly — only for emails with no long-term value."
-
-	// Stage 2: Determine actions
-	actions, err := p.openai.DetermineActions(ctx, message.From, message.Subject, analysis.Slug, analysis.Keywords, analysis.Summary, labelNames, formattedLabels, senderContext, memoryContext, actionsPrompt)
-	if err != nil {
-		return fmt.Errorf("stage 2 failed: %w", err)
-	}
 
-	log.Printf("[%s] Stage 2 - Labels: %v, Bypass: %v, Reason: %s", user.Email, actions.Labels, actions.BypassInbox, actions.Reasoning)
+		log.Printf("[%s] Stage 1 - Slug: %s, Keywords: %v", user.Email, analysis.Slug, analysis.Keywords)
 
-	// Send push notification if AI provided a notification message and user has Pushover configured
-	notificationSent := false
-	if actions.NotificationMessage != "" && user.HasPushoverConfig() {
-		if err := p.pushover.Send(user.PushoverUserKey, user.PushoverAppToken, message.Subject, actions.NotificationMessage); err != nil {
-			log.Printf("[%s] Failed to send push notification: %v", user.Email, err)
-		} else {
-			notificationSent = true
-			log.Printf("[%s] Push notification sent for: %s", user.Email, message.Subject)
+		// Retrieve similar past decisions (corrected ones first) as few-shot examples for Stage 2
+		vector, embeddingModel = p.embedEmail(ctx, user, subject, analysis.Summary)
+		examples := p.findExamples(ctx, user, message.ID, vector, embeddingModel, redactions)
+		promptCtx.ExampleEmailIDs = exampleIDs(examples)
 
-			// Persist notification to database (non-critical)
-			notif := &database.Notification{
-				UserID:      user.ID,
-				EmailID:     message.ID,
-				FromAddress: message.From,
-				Subject:     message.Subject,
-				Message:     actions.NotificationMessage,
-				SentAt:      time.Now(),
-			}
-			if err := p.db.CreateNotification(ctx, notif); err != nil {
-				log.Printf("[%s] Failed to save notification: %v", user.Email, err)
+		// Stage 2: Determine actions
+		actions, err = aiClient.DetermineActions(ctx, message.From, subject, analysis.Slug, analysis.Keywords, analysis.Summary, labelNames, promptCtx.FormattedLabels, promptCtx.SenderContext, promptCtx.MemoryContext+formatExamples(examples), prompts.Actions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("stage 2 failed: %w", err)
 			}
+			log.Printf("[%s] Stage 2 failed, leaving email in inbox unlabelled: %v", user.Email, err)
+			actions = defaultActions(err)
+			cacheable = false
 		}
 	}
 
-	// Send webhook notification if AI provided a notification message and user has webhook configured
-	if actions.NotificationMessage != "" && user.HasWebhookConfig() {
-		payload := webhook.Payload{
-			Title:         message.Subject,
-			Message:       actions.NotificationMessage,
-			FromAddress:   message.From,
-			EmailID:       message.ID,
-			Slug:          analysis.Slug,
-			Subject:       message.Subject,
-			LabelsApplied: actions.Labels,
-			ProcessedAt:   time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := p.webhook.Send(user.WebhookURL, user.WebhookHeaderKey, user.WebhookHeaderValue, payload); err != nil {
-			log.Printf("[%s] Failed to send webhook notification: %v", user.Email, err)
-		} else {
-			log.Printf("[%s] Webhook notification sent for: %s", user.Email, message.Subject)
+	if cacheable && isCacheable(analysis, actions) {
+		p.storeCachedDecision(ctx, user, cacheKey, analysis, actions)
+	}
 
-			// Persist notification to database if not already saved by Pushover
-			if !notificationSent {
-				notif := &database.Notification{
-					UserID:      user.ID,
-					EmailID:     message.ID,
-					FromAddress: message.From,
-					Subject:     message.Subject,
-					Message:     actions.NotificationMessage,
-					SentAt:      time.Now(),
-				}
-				if err := p.db.CreateNotification(ctx, notif); err != nil {
-					log.Printf("[%s] Failed to save notification: %v", user.Email, err)
-				}
-			}
-			notificationSent = true
-		}
+	// Shipping notifications only notify when out for delivery or held up
+	p.applyShipmentRules(analysis, actions)
+
+	// Cap what a suspected injection can trigger (after caching, so cache hits are capped again)
+	heldNotification := capSuspiciousActions(injection, senderProfile, actions)
+
+	// Warn about likely phishing: warning label, no draft and no links in the notification
+	capRiskyActions(phishing, p.config.PhishingLabel, actions)
+
+	// Count subscription emails and apply the subscription's label policy (security and high-risk
+	// emails are left alone: their labels are deliberate and their sender may be spoofed)
+	var subscriptionID *int64
+	if security == nil && !phishing.highRisk() {
+		subscriptionID = p.trackSubscription(ctx, user, message, domainProfile, actions)
 	}
 
-	// Draft reply if AI decided one is warranted
+	log.Printf("[%s] Stage 2 - Labels: %v, Bypass: %v, Confidence: %.2f, Model: %s, Reason: %s", user.Email, actions.Labels, actions.BypassInbox, actions.Confidence, actions.Model, actions.Reasoning)
+
+	// Notify via Pushover and/or webhook if the AI provided a notification message
+	notificationSent := false
+	if actions.NotificationMessage != "" {
+		notificationSent = p.sendNotification(ctx, user, &outgoingNotification{
+			EmailID: message.ID,
+			From:    message.From,
+			Subject: message.Subject,
+			Slug:    analysis.Slug,
+			Labels:  actions.Labels,
+			Message: actions.NotificationMessage,
+			Urgent:  security.urgent(),
+			Redact:  security.redact,
+		})
+	}
+
+	// Draft reply if AI decided one is warranted and the budget allows it
 	draftCreated := false
-	if actions.DraftReply {
-		draftBody, err := p.openai.GenerateDraftReply(ctx, message.From, message.Subject, body, senderContext, actionsPrompt)
+	if actions.DraftReply && budget == BudgetOK {
+		draftBody, err := aiClient.GenerateDraftReply(ctx, message.From, subject, promptCtx.Body, promptCtx.SenderContext, prompts.Actions)
 		if err != nil {
 			log.Printf("[%s] Failed to generate draft reply: %v", user.Email, err)
 		} else if draftBody != "" {
+			// Put redacted values back so the draft reads naturally (this stays local)
+			draftBody = redactions.Restore(draftBody)
+
 			// Create Gmail client for this user
 			token := user.GetOAuth2Token()
 			draftClient, err := gmail.NewClient(ctx, p.oauthConfig, token)
@@ -241,42 +289,97 @@ func (p *Processor) ProcessEmail(ctx context.Context, user *database.User, messa
 	}
 
 	// Save to database
+	promptTokens, completionTokens := usage.Totals()
 	email := &database.Email{
 		ID:               message.ID,
 		UserID:           user.ID,
 		FromAddress:      message.From,
 		FromDomain:       domain,
-		Subject:          message.Subject,
+		Subject:          security.redact(message.Subject),
 		Slug:             analysis.Slug,
 		Keywords:         analysis.Keywords,
-		Summary:          analysis.Summary,
+		Summary:          security.redact(analysis.Summary),
 		LabelsApplied:    actions.Labels,
 		BypassedInbox:    actions.BypassInbox,
 		Reasoning:        actions.Reasoning,
 		NotificationSent: notificationSent,
 		DraftCreated:     draftCreated,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CostUSD:          usage.Cost(),
+		DecisionModel:    actions.Model,
+		Confidence:       actions.Confidence,
+		Escalated:        actions.Escalated,
+		PipelineMode:     mode,
+		Cached:           cached,
+		InjectionScore:   injection.Score,
+		InjectionSignals: injection.Signals,
+		PhishingScore:    phishing.Score,
+		PhishingSignals:  phishing.Signals,
+		SubscriptionID:   subscriptionID,
+		HeldNotification: security.redact(heldNotification),
+		RedactionCount:   redactions.Count(),
+		ThreadID:         message.ThreadID,
+		TriageVia:        triageVia,
 		ProcessedAt:      time.Now(),
 		CreatedAt:        time.Now(),
 	}
+	if experiment != nil {
+		email.ExperimentID = &experiment.ID
+		email.ExperimentVariant = variantName
+	}
+	buckets.apply(email)
+	fast.apply(email)
+	recordTransaction(email, analysis.Transaction)
 
 	if err := p.db.CreateEmail(ctx, email); err != nil {
 		return fmt.Errorf("failed to save email to database: %w", err)
 	}
 
+	// Store the embedding so later emails can retrieve this decision (non-critical)
+	if triageVia == database.TriageViaAI && budget != BudgetExhausted {
+		p.saveEmbedding(ctx, user, email.ID, vector, embeddingModel, subject, analysis.Summary)
+	}
+
+	// Store calendar invites and extracted events for the ICS feed (non-critical)
+	p.saveEvents(ctx, user, email.ID, message.Events, analysis.Event, redactions)
+
+	// Store action items for the task list (non-critical)
+	p.saveTasks(ctx, user, email.ID, analysis.Tasks, redactions)
+
+	// Add shipping updates to their shipment's timeline (non-critical)
+	p.recordShipment(ctx, user, email, analysis, redactions)
+
+	// Log security emails (non-critical)
+	p.recordSecurityEvent(ctx, user, email, security)
+
+	// Save explanation snapshot (non-critical)
+	if trace != nil {
+		p.saveExplanation(ctx, user, email.ID, prompts, promptCtx, senderProfile, domainProfile, labelNames, trace, triageVia, actions.Reasoning)
+	}
+
 	// Apply actions to Gmail
 	if err := p.applyActionsToGmail(ctx, user, message.ID, actions); err != nil {
 		log.Printf("Error applying actions to Gmail: %v", err)
 		// Don't return error - email is already processed and saved
+	} else if actions.BypassInbox {
+		// Only emails that really left the inbox count as un-archived if they come back
+		if err := p.db.MarkEmailArchived(ctx, user.ID, message.ID); err != nil {
+			log.Printf("[%s] Failed to record archive of %s: %v", user.Email, message.ID, err)
+		}
 	}
 
-	// Update sender profiles (non-critical)
-	if senderProfile != nil {
-		if err := p.updateProfileAfterProcessing(ctx, senderProfile, analysis, actions); err != nil {
+	// Update sender profiles (non-critical); economy mode keeps counters but skips summary evolution, and fast
+	// paths only count the email. High-risk emails are left out, so a spoofed From does not train the real sender's profile
+	evolveProfiles := profileAI && mode != database.PipelineModeEconomy && fast == nil
+	trustedSender := !phishing.highRisk()
+	if senderProfile != nil && trustedSender {
+		if err := p.updateProfileAfterProcessing(ctx, senderProfile, analysis, actions, fast != nil, evolveProfiles); err != nil {
 			log.Printf("[%s] Error updating sender profile: %v", user.Email, err)
 		}
 	}
-	if domainProfile != nil {
-		if err := p.updateProfileAfterProcessing(ctx, domainProfile, analysis, actions); err != nil {
+	if domainProfile != nil && trustedSender {
+		if err := p.updateProfileAfterProcessing(ctx, domainProfile, analysis, actions, fast != nil, evolveProfiles); err != nil {
 			log.Printf("[%s] Error updating domain profile: %v", user.Email, err)
 		}
 	}
@@ -285,8 +388,181 @@ func (p *Processor) ProcessEmail(ctx context.Context, user *database.User, messa
 	return nil
 }
 
+// outgoingNotification is a notification about one processed email
+type outgoingNotification struct {
+	EmailID string
+	From    string
+	Subject string
+	Slug    string
+	Labels  []string
+	Message string
+	Urgent  bool                // High priority: flagged security events
+	Redact  func(string) string // Masks secrets (one-time codes) in the recorded copy; nil records it as sent
+}
+
+// sendNotification delivers a notification to the user's configured Pushover and webhook channels
+// and records it. Returns true if any channel accepted it.
+func (p *Processor) sendNotification(ctx context.Context, user *database.User, n *outgoingNotification) bool {
+	// Send push notification if user has Pushover configured
+	notificationSent := false
+	if user.HasPushoverConfig() {
+		priority := pushover.PriorityNormal
+		if n.Urgent {
+			priority = pushover.PriorityHigh
+		}
+		if err := p.pushover.SendWithPriority(user.PushoverUserKey, user.PushoverAppToken, n.Subject, n.Message, priority); err != nil {
+			log.Printf("[%s] Failed to send push notification: %v", user.Email, err)
+		} else {
+			notificationSent = true
+			log.Printf("[%s] Push notification sent for: %s", user.Email, n.Subject)
+
+			// Persist notification to database (non-critical)
+			p.recordNotification(ctx, user, n)
+		}
+	}
+
+	// Send webhook notification if user has webhook configured
+	if user.HasWebhookConfig() {
+		payload := webhook.Payload{
+			Title:         n.Subject,
+			Message:       n.Message,
+			FromAddress:   n.From,
+			EmailID:       n.EmailID,
+			Slug:          n.Slug,
+			Subject:       n.Subject,
+			LabelsApplied: n.Labels,
+			ProcessedAt:   time.Now().UTC().Format(time.RFC3339),
+			Priority:      "normal",
+		}
+		if n.Urgent {
+			payload.Priority = "high"
+		}
+		if err := p.webhook.Send(user.WebhookURL, user.WebhookHeaderKey, user.WebhookHeaderValue, payload); err != nil {
+			log.Printf("[%s] Failed to send webhook notification: %v", user.Email, err)
+		} else {
+			log.Printf("[%s] Webhook notification sent for: %s", user.Email, n.Subject)
+
+			// Persist notification to database if not already saved by Pushover
+			if !notificationSent {
+				p.recordNotification(ctx, user, n)
+			}
+			notificationSent = true
+		}
+	}
+
+	return notificationSent
+}
+
+// recordNotification saves a sent notification to the notification history (non-critical)
+func (p *Processor) recordNotification(ctx context.Context, user *database.User, n *outgoingNotification) {
+	subject, message := n.Subject, n.Message
+	if n.Redact != nil {
+		subject, message = n.Redact(subject), n.Redact(message)
+	}
+	notif := &database.Notification{
+		UserID:      user.ID,
+		EmailID:     n.EmailID,
+		FromAddress: n.From,
+		Subject:     subject,
+		Message:     message,
+		SentAt:      time.Now(),
+	}
+	if err := p.db.CreateNotification(ctx, notif); err != nil {
+		log.Printf("[%s] Failed to save notification: %v", user.Email, err)
+	}
+}
+
+// defaultAnalysis is the Stage 1 result used when the AI cannot classify an email
+func defaultAnalysis(message *gmail.Message) *ai.EmailAnalysis {
+	return &ai.EmailAnalysis{
+		Slug:     "unclassified",
+		Keywords: []string{},
+		Summary:  message.Subject,
+	}
+}
+
+// defaultActions is the safe Stage 2 decision when the AI fails: leave the email in the inbox unlabelled
+func defaultActions(cause error) *ai.EmailActions {
+	return &ai.EmailActions{
+		Labels:    []string{},
+		Reasoning: fmt.Sprintf("AI decision unavailable, left in inbox unlabelled: %v", cause),
+		Model:     "default",
+	}
+}
+
+// recordTransaction copies the data extracted from a transactional email onto it
+func recordTransaction(email *database.Email, t *ai.Transaction) {
+	if t == nil {
+		return
+	}
+	email.Vendor = t.Vendor
+	email.DocumentType = t.DocumentType
+	email.Amount = t.Amount
+	email.Currency = t.Currency
+	email.DueDate = t.ParseDueDate()
+}
+
+// maxBodyTokens is the cleaned body budget for AI processing (the context budget may cut it further)
+const maxBodyTokens = 500
+
+// prepareBody decodes a base64url message body (if encoded) and cleans it for AI processing
+func prepareBody(raw string) string {
+	body := raw
+	if body != "" {
+		decoded, err := base64.URLEncoding.DecodeString(body)
+		if err == nil {
+			body = string(decoded)
+		}
+	}
+
+	return cleanBody(body)
+}
+
+// cleanBody strips quoted replies, signatures, disclaimers, footers and tracking links,
+// then truncates the remaining new content to maxBodyTokens
+func cleanBody(body string) string {
+	return truncateToTokens(preprocess.Clean(body).Text, maxBodyTokens)
+}
+
+// loadMemories returns the user's recent memories for context (1 yearly, 1 monthly, 1 weekly, up to 7 daily)
+func (p *Processor) loadMemories(ctx context.Context, userID int64) []*database.Memory {
+	memories, err := p.db.GetRecentMemoriesForContext(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting memories: %v", err)
+		return nil
+	}
+	return memories
+}
+
+// buildPromptContext fits the body, profiles and the user's memories and labels into the context
+// token budget, and returns the label names the AI may choose from
+func (p *Processor) buildPromptContext(ctx context.Context, user *database.User, body string, senderProfile, domainProfile *database.SenderProfile) (*promptContext, []string) {
+	memories := p.loadMemories(ctx, user.ID)
+	labels, labelNames := p.loadLabels(ctx, user.ID)
+	promptCtx := p.assembleContext(body, memories, senderProfile, domainProfile, labels)
+	if len(promptCtx.Trims) > 0 {
+		log.Printf("[%s] Prompt context over budget, applied %d trims", user.Email, len(promptCtx.Trims))
+	}
+	return promptCtx, labelNames
+}
+
+// loadLabels returns the user's labels with descriptions and reasons, and their names
+func (p *Processor) loadLabels(ctx context.Context, userID int64) ([]*database.Label, []string) {
+	labelDetails, err := p.db.GetUserLabelsWithDetails(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting user labels: %v", err)
+		return nil, nil
+	}
+
+	var labelNames []string
+	for _, l := range labelDetails {
+		labelNames = append(labelNames, l.Name)
+	}
+	return labelDetails, labelNames
+}
+
 // loadOrBootstrapProfile fetches an existing profile or creates one from history
-func (p *Processor) loadOrBootstrapProfile(ctx context.Context, userID int64, profileType database.ProfileType, identifier string, domain string) *database.SenderProfile {
+func (p *Processor) loadOrBootstrapProfile(ctx context.Context, userID int64, profileType database.ProfileType, identifier string, domain string, useAI bool) *database.SenderProfile {
 	profile, err := p.db.GetSenderProfile(ctx, userID, profileType, identifier)
 	if err != nil {
 		log.Printf("Error loading %s profile for %s: %v", profileType, identifier, err)
@@ -295,11 +571,12 @@ func (p *Processor) loadOrBootstrapProfile(ctx context.Context, userID int64, pr
 	if profile != nil {
 		return profile
 	}
-	return p.bootstrapProfile(ctx, userID, profileType, identifier, domain)
+	return p.bootstrapProfile(ctx, userID, profileType, identifier, domain, useAI)
 }
 
-// bootstrapProfile creates a new profile from historical emails
-func (p *Processor) bootstrapProfile(ctx context.Context, userID int64, profileType database.ProfileType, identifier string, domain string) *database.SenderProfile {
+// bootstrapProfile creates a new profile from historical emails.
+// The AI classification and summary are skipped when useAI is false.
+func (p *Processor) bootstrapProfile(ctx context.Context, userID int64, profileType database.ProfileType, identifier string, domain string, useAI bool) *database.SenderProfile {
 	var emails []*database.Email
 	var err error
 
@@ -317,8 +594,8 @@ func (p *Processor) bootstrapProfile(ctx context.Context, userID int64, profileT
 	profile := database.BuildProfileFromEmails(userID, profileType, identifier, emails)
 
 	// If we have history, use AI to classify and summarize
-	if len(emails) > 0 {
-		result, err := p.openai.BootstrapSenderProfile(ctx, identifier, emails)
+	if len(emails) > 0 && useAI {
+		result, err := p.ai.BootstrapSenderProfile(ctx, identifier, emails)
 		if err != nil {
 			log.Printf("Error bootstrapping %s profile for %s: %v", profileType, identifier, err)
 		} else {
@@ -337,10 +614,16 @@ func (p *Processor) bootstrapProfile(ctx context.Context, userID int64, profileT
 	return profile
 }
 
-// updateProfileAfterProcessing increments counters and evolves summary
-func (p *Processor) updateProfileAfterProcessing(ctx context.Context, profile *database.SenderProfile, analysis *openai.EmailAnalysis, actions *openai.EmailActions) error {
+// updateProfileAfterProcessing increments counters and, when evolve is set, evolves the summary.
+// Fast-path and security-lane decisions are only counted as emails, not in the decision counters,
+// so the consistent-sender fast path never feeds on its own decisions.
+func (p *Processor) updateProfileAfterProcessing(ctx context.Context, profile *database.SenderProfile, analysis *ai.EmailAnalysis, actions *ai.EmailActions, fastPath bool, evolve bool) error {
 	profile.EmailCount++
 	profile.LastSeenAt = time.Now()
+	if fastPath {
+		profile.EmailsFastPath++
+		return p.db.UpsertSenderProfile(ctx, profile)
+	}
 
 	if analysis.Slug != "" {
 		if profile.SlugCounts == nil {
@@ -367,8 +650,12 @@ func (p *Processor) updateProfileAfterProcessing(ctx context.Context, profile *d
 		profile.EmailsNotified++
 	}
 
+	if !evolve {
+		return p.db.UpsertSenderProfile(ctx, profile)
+	}
+
 	// Evolve summary via AI
-	update := &openai.ProfileUpdateContext{
+	update := &ai.ProfileUpdateContext{
 		From:     profile.Identifier,
 		Subject:  analysis.Summary,
 		Slug:     analysis.Slug,
@@ -378,7 +665,7 @@ func (p *Processor) updateProfileAfterProcessing(ctx context.Context, profile *d
 		Notified: actions.NotificationMessage != "",
 		Summary:  analysis.Summary,
 	}
-	result, err := p.openai.EvolveProfileSummary(ctx, profile.Summary, profile.SenderType, update)
+	result, err := p.ai.EvolveProfileSummary(ctx, profile.Summary, profile.SenderType, update)
 	if err != nil {
 		log.Printf("Error evolving %s profile summary for %s: %v", profile.ProfileType, profile.Identifier, err)
 	} else {
@@ -406,7 +693,7 @@ func (p *Processor) formatProfilesForPrompt(sender *database.SenderProfile, doma
 }
 
 // applyActionsToGmail applies labels and inbox bypass to the actual Gmail message
-func (p *Processor) applyActionsToGmail(ctx context.Context, user *database.User, messageID string, actions *openai.EmailActions) error {
+func (p *Processor) applyActionsToGmail(ctx context.Context, user *database.User, messageID string, actions *ai.EmailActions) error {
 	// Create Gmail client for this user
 	token := user.GetOAuth2Token()
 	client, err := gmail.NewClient(ctx, p.oauthConfig, token)
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// systemPrompts holds the assembled Stage 1 and Stage 2 system prompts
+// and the AI prompt versions that went into them
+type systemPrompts struct {
+	Analyze           string
+	Actions           string
+	AIAnalyzePromptID *int64
+	AIActionsPromptID *int64
+}
+
+// loadSystemPrompts builds the analyze and actions system prompts for a user.
+// The user's custom prompts are combined with the latest AI-generated supplements;
+// an experiment variant may override either part.
+func (p *Processor) loadSystemPrompts(ctx context.Context, userID int64, variant *database.ExperimentVariant) *systemPrompts {
+	prompts := &systemPrompts{}
+	if prompt, err := p.db.GetSystemPrompt(ctx, userID, database.PromptTypeEmailAnalyze); err == nil {
+		prompts.Analyze = prompt.Content
+	}
+	if prompt, err := p.db.GetSystemPrompt(ctx, userID, database.PromptTypeEmailActions); err == nil {
+		prompts.Actions = prompt.Content
+	}
+
+	var aiAnalyzeID, aiActionsID *int64
+	if variant != nil {
+		if variant.AnalyzePrompt != "" {
+			prompts.Analyze = variant.AnalyzePrompt
+		}
+		if variant.ActionsPrompt != "" {
+			prompts.Actions = variant.ActionsPrompt
+		}
+		if variant.DisableAIPrompts {
+			return prompts
+		}
+		aiAnalyzeID = variant.AIAnalyzePromptID
+		aiActionsID = variant.AIActionsPromptID
+	}
+
+	// Append AI-generated prompt supplements (if any exist)
+	if aiPrompt := p.loadAIPrompt(ctx, userID, database.AIPromptTypeEmailAnalyze, aiAnalyzeID); aiPrompt != nil {
+		if prompts.Analyze != "" {
+			prompts.Analyze += "\n\n" + aiPrompt.Content
+		} else {
+			prompts.Analyze = aiPrompt.Content
+		}
+		prompts.AIAnalyzePromptID = &aiPrompt.ID
+	}
+	if aiPrompt := p.loadAIPrompt(ctx, userID, database.AIPromptTypeEmailActions, aiActionsID); aiPrompt != nil {
+		if prompts.Actions != "" {
+			prompts.Actions += "\n\n" + aiPrompt.Content
+		} else {
+			prompts.Actions = aiPrompt.Content
+		}
+		prompts.AIActionsPromptID = &aiPrompt.ID
+	}
+
+	return prompts
+}
+
+// loadAIPrompt returns a pinned AI prompt version, or the latest one when no ID is given
+func (p *Processor) loadAIPrompt(ctx context.Context, userID int64, promptType database.AIPromptType, promptID *int64) *database.AIPrompt {
+	if promptID != nil {
+		aiPrompt, err := p.db.GetAIPromptByID(ctx, userID, *promptID)
+		if err != nil || aiPrompt == nil || aiPrompt.Type != promptType {
+			return nil
+		}
+		return aiPrompt
+	}
+	aiPrompt, err := p.db.GetLatestAIPrompt(ctx, userID, promptType)
+	if err != nil {
+		return nil
+	}
+	return aiPrompt
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// emailRedactor applies a user's redaction settings to email content
+type emailRedactor struct {
+	settings *database.RedactionSettings
+	redactor *redact.Redactor
+}
+
+// loadRedactor returns the user's redactor: the built-in detectors and their custom patterns
+func (p *Processor) loadRedactor(ctx context.Context, user *database.User) *emailRedactor {
+	settings, err := p.db.GetRedactionSettings(ctx, user.ID)
+	if err != nil {
+		// Fail closed: redact with the built-in detectors
+		log.Printf("[%s] Failed to load redaction settings, using built-in detectors: %v", user.Email, err)
+		settings = &database.RedactionSettings{Enabled: true}
+	}
+	redactor, err := settings.Redactor()
+	if err != nil {
+		log.Printf("[%s] Invalid custom redaction pattern, using built-in detectors: %v", user.Email, err)
+	}
+	return &emailRedactor{settings: settings, redactor: redactor}
+}
+
+// redact replaces personal data in text from a sender with placeholders recorded in result. Text
+// from allowlisted senders, and all text of users who turned redaction off, is returned unchanged.
+func (r *emailRedactor) redact(from, text string, result *redact.Result) string {
+	if !r.settings.Enabled || r.settings.Allows(from) {
+		return text
+	}
+	return r.redactor.Redact(text, result)
+}
+
+// redactEmail replaces personal data in the subject and body with typed placeholders, using the
+// built-in detectors and the user's custom patterns. Emails from allowlisted senders and users who
+// turned redaction off are returned unchanged. The result restores the values in drafts.
+func (p *Processor) redactEmail(ctx context.Context, user *database.User, from, subject, body string) (string, string, *redact.Result) {
+	result := redact.NewResult()
+	redactor := p.loadRedactor(ctx, user)
+	subject = redactor.redact(from, subject, result)
+	body = redactor.redact(from, body, result)
+	if result.Count() > 0 {
+		log.Printf("[%s] Redacted %d values before AI processing: %v", user.Email, result.Count(), result.Counts())
+	}
+	return subject, body, result
+}
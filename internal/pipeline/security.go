@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// securityOutcome is a security email recognised by checkSecurity
+type securityOutcome struct {
+	Report      *ai.SecurityReport
+	Flagged     bool     // Unexpected password reset or login from an unknown or suspicious sender
+	FlagReasons []string // Why it was flagged, e.g. "the sender has no history"
+}
+
+// checkSecurity recognises one-time codes, password resets, new-login alerts and account
+// recovery emails. The heuristics read the original content, since redaction may mask codes;
+// when they are unsure and useAI is set, the AI decides from the redacted content. Resets and
+// logins are flagged when the sender is unknown or the email looks like phishing.
+// Returns nil for other emails.
+func (p *Processor) checkSecurity(ctx context.Context, user *database.User, message *gmail.Message, rawBody, subject, body string, senderProfile *database.SenderProfile, phishing *phishingReport, useAI bool) *securityOutcome {
+	report := ai.DetectSecurity(message.Subject, rawBody)
+	if report == nil {
+		return nil
+	}
+
+	if !report.Confident {
+		if !useAI {
+			return nil
+		}
+		verdict, err := p.ai.ClassifySecurity(ctx, message.From, subject, body)
+		if err != nil {
+			log.Printf("[%s] Security classifier failed, treating as a regular email: %v", user.Email, err)
+			return nil
+		}
+		if verdict == nil {
+			return nil
+		}
+		// A code from the redacted content may be a placeholder; prefer the original's
+		if verdict.Kind == database.SecurityKindOTP {
+			if code := ai.ExtractOTP(message.Subject + "\n" + rawBody); code != "" {
+				verdict.Code = code
+			}
+		}
+		report = verdict
+	}
+
+	outcome := &securityOutcome{Report: report, FlagReasons: []string{}}
+	if report.Kind == database.SecurityKindPasswordReset || report.Kind == database.SecurityKindNewLogin {
+		if isNewSender(senderProfile) {
+			outcome.FlagReasons = append(outcome.FlagReasons, "the sender has no history")
+		}
+		if phishing.highRisk() {
+			outcome.FlagReasons = append(outcome.FlagReasons, "it looks like phishing")
+		}
+		outcome.Flagged = len(outcome.FlagReasons) > 0
+	}
+	log.Printf("[%s] Security email (%s, by %s, flagged: %v)", user.Email, report.Kind, report.DetectedBy, outcome.Flagged)
+	return outcome
+}
+
+// decision is the security lane's decision: the email stays in the inbox and the code (or alert)
+// is pushed straight away. Unflagged emails get the security label to clean them up; flagged ones
+// keep no label and notify at high priority.
+func (s *securityOutcome) decision(message *gmail.Message, label string) (*ai.EmailAnalysis, *ai.EmailActions, *fastPath) {
+	kind := s.Report.Kind
+	fast := &fastPath{
+		Via:       database.TriageViaSecurity,
+		Reasoning: fmt.Sprintf("Security email (%s), detected by %s", kind, s.Report.DetectedBy),
+	}
+	if s.Flagged {
+		fast.Reasoning += "; flagged: " + strings.Join(s.FlagReasons, " and ")
+	}
+
+	analysis := &ai.EmailAnalysis{
+		Slug:     "security_" + kind,
+		Keywords: []string{"security", kind},
+		Summary:  message.Subject,
+	}
+	actions := &ai.EmailActions{
+		Labels:              []string{},
+		NotificationMessage: s.notificationMessage(),
+		Reasoning:           fast.Reasoning,
+		Model:               database.TriageViaSecurity,
+	}
+	if !s.Flagged && label != "" {
+		actions.Labels = append(actions.Labels, label)
+	}
+	return analysis, actions, fast
+}
+
+// notificationMessage is the push message for a security email ("" for routine login alerts)
+func (s *securityOutcome) notificationMessage() string {
+	if s.Flagged {
+		what := "password reset"
+		if s.Report.Kind == database.SecurityKindNewLogin {
+			what = "sign-in"
+		}
+		return fmt.Sprintf("⚠️ Unexpected %s email (%s) - if it wasn't you, check your account directly, not through the email's links", what, strings.Join(s.FlagReasons, " and "))
+	}
+
+	switch s.Report.Kind {
+	case database.SecurityKindOTP:
+		if s.Report.Code != "" {
+			return "🔐 Code: " + s.Report.Code
+		}
+		return "🔐 One-time code received"
+	case database.SecurityKindPasswordReset:
+		return "🔑 Password reset email"
+	case database.SecurityKindAccountRecovery:
+		return "🔑 Account recovery email"
+	}
+	// Sign-in alerts from senders the user already hears from are routine
+	return ""
+}
+
+// urgent returns true when the email's notification should be high priority
+func (s *securityOutcome) urgent() bool {
+	return s != nil && s.Flagged
+}
+
+// redact masks the one-time code wherever it appears in text, for copies that are stored
+// (the email's subject and summary, the notification history). Other text is returned unchanged.
+func (s *securityOutcome) redact(text string) string {
+	if s == nil || s.Report.Code == "" {
+		return text
+	}
+	// The code was normalised; the text may still separate its digits ("123 456", "123-456")
+	parts := make([]string, 0, len(s.Report.Code))
+	for _, r := range s.Report.Code {
+		parts = append(parts, regexp.QuoteMeta(string(r)))
+	}
+	pattern := regexp.MustCompile(`(?i)\b` + strings.Join(parts, `[ -]?`) + `\b`)
+	return pattern.ReplaceAllString(text, "••••••")
+}
+
+// recordSecurityEvent stores a security email for the security log (non-critical). The subject was
+// redacted with the email, so no code is stored.
+func (p *Processor) recordSecurityEvent(ctx context.Context, user *database.User, email *database.Email, s *securityOutcome) {
+	if s == nil {
+		return
+	}
+	err := p.db.CreateSecurityEvent(ctx, &database.SecurityEvent{
+		UserID:      user.ID,
+		EmailID:     email.ID,
+		Kind:        s.Report.Kind,
+		DetectedBy:  s.Report.DetectedBy,
+		Flagged:     s.Flagged,
+		FromAddress: email.FromAddress,
+		Subject:     email.Subject,
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to record security event: %v", user.Email, err)
+	}
+}
+
+// ProcessSecurityEmail processes an email from the security lane ahead of the regular poll when
+// the heuristics are sure it is a security email. Other emails are left for the regular poll.
+func (p *Processor) ProcessSecurityEmail(ctx context.Context, user *database.User, message *gmail.Message) error {
+	report := ai.DetectSecurity(message.Subject, prepareBody(message.Body))
+	if report == nil || !report.Confident {
+		return nil
+	}
+	return p.ProcessEmail(ctx, user, message)
+}
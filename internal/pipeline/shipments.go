@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// applyShipmentRules adjusts the actions for a shipping notification: only out-for-delivery and
+// exception updates notify (with a default message if the AI left none), and a delivered
+// update gets the delivered label.
+func (p *Processor) applyShipmentRules(analysis *ai.EmailAnalysis, actions *ai.EmailActions) {
+	s := analysis.Shipment
+	if s == nil {
+		return
+	}
+
+	switch s.Status {
+	case database.ShipmentStatusOutForDelivery:
+		if actions.NotificationMessage == "" {
+			actions.NotificationMessage = "📦 Out for delivery: " + describeShipment(s, analysis.Transaction)
+		}
+	case database.ShipmentStatusException:
+		if actions.NotificationMessage == "" {
+			actions.NotificationMessage = "📦 Delivery problem: " + describeShipment(s, analysis.Transaction) + " - " + analysis.Summary
+		}
+	default:
+		actions.NotificationMessage = ""
+	}
+
+	if s.Status == database.ShipmentStatusDelivered && p.config.ShipmentDeliveredLabel != "" {
+		for _, label := range actions.Labels {
+			if label == p.config.ShipmentDeliveredLabel {
+				return
+			}
+		}
+		actions.Labels = append(actions.Labels, p.config.ShipmentDeliveredLabel)
+	}
+}
+
+// describeShipment names a shipment for a notification, e.g. "Amazon order 112-4431 (UPS)"
+func describeShipment(s *ai.Shipment, t *ai.Transaction) string {
+	var parts []string
+	if t != nil && t.Vendor != "" {
+		parts = append(parts, t.Vendor)
+	}
+	if s.OrderRef != "" {
+		parts = append(parts, "order "+s.OrderRef)
+	} else {
+		parts = append(parts, "package "+s.TrackingNumber)
+	}
+	description := strings.Join(parts, " ")
+	if s.Carrier != "" {
+		description += " (" + s.Carrier + ")"
+	}
+	return description
+}
+
+// shipmentUpdate builds the update for an extracted shipment. Tracking numbers and order references
+// were extracted from the redacted email, so redacted values are restored before normalizing.
+func shipmentUpdate(userID int64, emailID string, analysis *ai.EmailAnalysis, redactions *redact.Result) *database.ShipmentUpdate {
+	s := analysis.Shipment
+	vendor := ""
+	if analysis.Transaction != nil {
+		vendor = analysis.Transaction.Vendor
+	}
+	return &database.ShipmentUpdate{
+		UserID:         userID,
+		EmailID:        emailID,
+		Carrier:        s.Carrier,
+		TrackingNumber: ai.NormalizeTrackingNumber(redactions.Restore(s.TrackingNumber)),
+		OrderRef:       ai.NormalizeOrderRef(redactions.Restore(s.OrderRef)),
+		Vendor:         vendor,
+		Status:         s.Status,
+		OccurredAt:     time.Now(),
+	}
+}
+
+// recordShipment adds a shipping notification to its shipment's timeline (non-critical). When the
+// update delivers the shipment, the delivered label is applied to the shipment's earlier emails.
+func (p *Processor) recordShipment(ctx context.Context, user *database.User, email *database.Email, analysis *ai.EmailAnalysis, redactions *redact.Result) {
+	s := analysis.Shipment
+	if s == nil {
+		return
+	}
+
+	shipmentID, delivered, err := p.db.RecordShipmentUpdate(ctx, shipmentUpdate(user.ID, email.ID, analysis, redactions))
+	if errors.Is(err, database.ErrNoShipmentKey) {
+		log.Printf("[%s] Shipment update has no usable tracking number or order reference, not grouping it", user.Email)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] Failed to record shipment update: %v", user.Email, err)
+		return
+	}
+	log.Printf("[%s] Shipment %d - %s", user.Email, shipmentID, s.Status)
+
+	if !delivered || p.config.ShipmentDeliveredLabel == "" {
+		return
+	}
+	emailIDs, err := p.db.GetShipmentEmailIDs(ctx, user.ID, shipmentID)
+	if err != nil {
+		log.Printf("[%s] Failed to load shipment emails: %v", user.Email, err)
+		return
+	}
+	label := &ai.EmailActions{Labels: []string{p.config.ShipmentDeliveredLabel}}
+	for _, id := range emailIDs {
+		if id == email.ID {
+			continue // Labelled with the rest of its actions
+		}
+		if err := p.applyActionsToGmail(ctx, user, id, label); err != nil {
+			log.Printf("[%s] Failed to label delivered shipment email %s: %v", user.Email, id, err)
+		}
+	}
+}
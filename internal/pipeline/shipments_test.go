@@ -0,0 +1,28 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+func TestShipmentUpdateRestoresRedactedTrackingNumbers(t *testing.T) {
+	// A pattern that catches spaced tracking numbers, as an over-eager phone detector would
+	redactor, err := redact.New([]redact.Pattern{{Name: "phone", Pattern: `\d{4} \d{4} \d{4}`}})
+	if err != nil {
+		t.Fatalf("redact.New: %v", err)
+	}
+	redactions := redact.NewResult()
+	redactor.Redact("Package 7749 1234 5678 shipped. Package 7749 8765 4321 delivered.", redactions)
+
+	first := shipmentUpdate(1, "a", &ai.EmailAnalysis{Shipment: &ai.Shipment{TrackingNumber: "[PHONE_1]", Status: "shipped"}}, redactions)
+	second := shipmentUpdate(1, "b", &ai.EmailAnalysis{Shipment: &ai.Shipment{TrackingNumber: "[PHONE_2]", Status: "delivered"}}, redactions)
+
+	if first.TrackingNumber != "774912345678" {
+		t.Errorf("first tracking number = %q, want %q", first.TrackingNumber, "774912345678")
+	}
+	if second.TrackingNumber != "774987654321" {
+		t.Errorf("second tracking number = %q, want %q", second.TrackingNumber, "774987654321")
+	}
+}
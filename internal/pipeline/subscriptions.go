@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// subscriptionSenderTypes are the domain profile types whose senders count as subscriptions
+// even without mailing list headers
+var subscriptionSenderTypes = map[string]bool{"newsletter": true, "marketing": true}
+
+// subscriptionFor identifies the subscription an email belongs to: the mailing list named by its
+// List-Id, or its sender when it carries a List-Unsubscribe header or comes from a newsletter or
+// marketing domain. Returns nil for other emails.
+func subscriptionFor(message *gmail.Message, domainProfile *database.SenderProfile) *database.SubscriptionEmail {
+	key := message.ListID
+	if key == "" {
+		hasUnsubscribe := message.UnsubscribeURL != "" || message.UnsubscribeMailto != ""
+		if !hasUnsubscribe && (domainProfile == nil || !subscriptionSenderTypes[domainProfile.SenderType]) {
+			return nil
+		}
+		key = strings.ToLower(message.From)
+	}
+
+	return &database.SubscriptionEmail{
+		ListKey:           key,
+		ListID:            message.ListID,
+		Name:              message.FromName,
+		FromAddress:       message.From,
+		FromDomain:        database.ExtractDomain(message.From),
+		UnsubscribeURL:    message.UnsubscribeURL,
+		UnsubscribeMailto: message.UnsubscribeMailto,
+		OneClick:          message.OneClickUnsubscribe,
+	}
+}
+
+// trackSubscription counts a subscription email in the inventory and applies the subscription's
+// label policy. Returns the subscription's ID, or nil for other emails (non-critical).
+func (p *Processor) trackSubscription(ctx context.Context, user *database.User, message *gmail.Message, domainProfile *database.SenderProfile, actions *ai.EmailActions) *int64 {
+	subscription := subscriptionFor(message, domainProfile)
+	if subscription == nil {
+		return nil
+	}
+	subscription.UserID = user.ID
+	subscription.Archived = actions.BypassInbox
+	subscription.Notified = actions.NotificationMessage != ""
+
+	id, labelPolicy, err := p.db.RecordSubscriptionEmail(ctx, subscription)
+	if err != nil {
+		log.Printf("[%s] Failed to record subscription email: %v", user.Email, err)
+		return nil
+	}
+	applyLabelPolicy(labelPolicy, actions)
+	return &id
+}
+
+// applyLabelPolicy replaces the timed labels the AI chose with the subscription's policy label
+func applyLabelPolicy(labelPolicy string, actions *ai.EmailActions) {
+	if labelPolicy == "" {
+		return
+	}
+	labels := make([]string, 0, len(actions.Labels)+1)
+	for _, label := range actions.Labels {
+		if !gmail.IsTimedLabel(label) {
+			labels = append(labels, label)
+		}
+	}
+	actions.Labels = append(labels, labelPolicy)
+	actions.Reasoning += " [Subscription label policy: " + labelPolicy + "]"
+}
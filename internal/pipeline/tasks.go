@@ -0,0 +1,32 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/redact"
+)
+
+// saveTasks stores the action items extracted from an email (non-critical), with the values
+// redacted from the email put back
+func (p *Processor) saveTasks(ctx context.Context, user *database.User, emailID string, extracted []ai.Task, redactions *redact.Result) {
+	if len(extracted) == 0 {
+		return
+	}
+	tasks := make([]*database.Task, 0, len(extracted))
+	for _, t := range extracted {
+		tasks = append(tasks, &database.Task{
+			UserID:      user.ID,
+			EmailID:     emailID,
+			Description: redactions.Restore(t.Description),
+			DueDate:     t.ParseDueDate(),
+		})
+	}
+	if err := p.db.CreateTasks(ctx, tasks); err != nil {
+		log.Printf("[%s] Failed to save tasks: %v", user.Email, err)
+		return
+	}
+	log.Printf("[%s] Extracted %d task(s)", user.Email, len(tasks))
+}
@@ -0,0 +1,53 @@
+package preprocess
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlMarker recognises bodies that are HTML rather than plain text
+	htmlMarker = regexp.MustCompile(`(?i)<(!doctype html|html|body|div|p|br|table|span|a\s)[\s/>]`)
+
+	htmlInvisible  = regexp.MustCompile(`(?is)<(head|style|script|title)\b.*?</(head|style|script|title)\s*>|<!--.*?-->`)
+	htmlLink       = regexp.MustCompile(`(?is)<a\b[^>]*?\bhref\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a\s*>`)
+	htmlListItem   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlLineBreak  = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|h[1-6]|ul|ol|table|blockquote)\s*>|<(p|div|tr|h[1-6]|table|blockquote|hr)\b[^>]*>`)
+	htmlTag        = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlSpaces     = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	htmlMailtoLink = regexp.MustCompile(`(?i)^mailto:`)
+)
+
+// looksLikeHTML returns true if the body is an HTML document or fragment
+func looksLikeHTML(body string) bool {
+	return htmlMarker.MatchString(body)
+}
+
+// htmlToText renders an HTML body as plain text: block elements become line breaks, links keep
+// their target after the text ("Pay now (https://...)") so link cleaning still applies, and
+// entities are decoded
+func htmlToText(body string) string {
+	text := htmlInvisible.ReplaceAllString(body, "")
+	text = strings.NewReplacer("\r\n", " ", "\n", " ").Replace(text)
+	text = htmlLink.ReplaceAllStringFunc(text, func(a string) string {
+		m := htmlLink.FindStringSubmatch(a)
+		href, label := strings.TrimSpace(html.UnescapeString(m[1])), strings.TrimSpace(htmlTag.ReplaceAllString(m[2], ""))
+		switch {
+		case htmlMailtoLink.MatchString(href) || !strings.Contains(href, "://"):
+			return label
+		case label == "" || strings.Contains(href, label):
+			return " " + href + " "
+		}
+		return label + " (" + href + ")"
+	})
+	text = htmlListItem.ReplaceAllString(text, "\n- ")
+	text = htmlLineBreak.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(htmlSpaces.ReplaceAllString(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+package preprocess
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxLinkLength is the longest URL kept verbatim; longer ones are shortened to their host and path
+const maxLinkLength = 100
+
+var (
+	urlPattern = regexp.MustCompile(`https?://[^\s<>()"'\]]+`)
+
+	// Redirect and open/click tracking hosts used by mailing services
+	trackingHost = regexp.MustCompile(`(?i)(^|\.)(list-manage\.com|sendgrid\.net|mandrillapp\.com|mailchimp\.com|mcsv\.net|hubspotlinks\.com|exacttarget\.com|rs6\.net|ct\.sendgrid\.net|mailgun\.org|sparkpostmail\.com|customeriomail\.com|klclick\d*\.com|convertkit-mail\d*\.com)$|^(click|clicks|track|tracking|links?|email|t|go|r)\.([^.]+\.)+[a-z]{2,}$`)
+	trackingPath = regexp.MustCompile(`(?i)/(ls/click|track/click|wf/click|ss/c|e3t/|redirect|trk|c/[a-z0-9_-]{20,}|open\.php|pixel)`)
+
+	// Query parameters that only identify the campaign or recipient
+	trackingParams = regexp.MustCompile(`(?i)^(utm_[a-z]+|fbclid|gclid|dclid|msclkid|mc_cid|mc_eid|_hsenc|_hsmi|mkt_tok|vero_id|oly_enc_id|oly_anon_id|ref_src|trk|trkid|s_cid|cmpid|spm|__s)$`)
+
+	linkOnlyLine = regexp.MustCompile(`^\s*(\[[^\]]*\]|[|•·\s-])*\s*$`)
+	// One-word link text followed by its URL, as rendered from HTML: "Twitter (https://twitter.com/acme)"
+	labelledLink = regexp.MustCompile(`[\p{L}\p{N}.+-]{1,20} \(https?://[^\s)]+\)`)
+)
+
+// cleanLinks removes tracking parameters from URLs, collapses click-tracking redirects to
+// "[link: host]" and shortens overlong URLs. Returns true if anything was changed.
+func cleanLinks(text string) (string, bool) {
+	changed := false
+	cleaned := urlPattern.ReplaceAllStringFunc(text, func(raw string) string {
+		trailing := ""
+		for strings.HasSuffix(raw, ".") || strings.HasSuffix(raw, ",") || strings.HasSuffix(raw, ";") {
+			trailing = raw[len(raw)-1:] + trailing
+			raw = raw[:len(raw)-1]
+		}
+
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return raw + trailing
+		}
+
+		if trackingHost.MatchString(u.Host) || trackingPath.MatchString(u.Path) {
+			changed = true
+			return "[link: " + strings.TrimPrefix(u.Host, "www.") + "]" + trailing
+		}
+
+		query := u.Query()
+		stripped := false
+		for key := range query {
+			if trackingParams.MatchString(key) {
+				query.Del(key)
+				stripped = true
+			}
+		}
+		if stripped {
+			changed = true
+			u.RawQuery = query.Encode()
+		}
+		u.Fragment = ""
+
+		link := u.String()
+		if len(link) > maxLinkLength {
+			changed = true
+			u.RawQuery = ""
+			link = u.String()
+			if len(link) > maxLinkLength {
+				link = link[:maxLinkLength] + "..."
+			}
+		}
+		if !stripped && link == raw {
+			return raw + trailing
+		}
+		return link + trailing
+	})
+	return cleaned, changed
+}
+
+// isLinkOnly returns true for paragraphs that are nothing but links, e.g. social icon rows
+func isLinkOnly(paragraph string) bool {
+	return linkOnlyLine.MatchString(urlPattern.ReplaceAllString(labelledLink.ReplaceAllString(paragraph, ""), ""))
+}
@@ -0,0 +1,218 @@
+// Package preprocess reduces an email body to its new content before AI processing: HTML is
+// rendered as text, quoted replies, signatures, legal disclaimers and unsubscribe footers are
+// removed and tracking links are shortened.
+package preprocess
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Result is a cleaned body and what was removed from it
+type Result struct {
+	Text    string   `json:"text"`
+	Removed []string `json:"removed"` // Kinds of content removed, e.g. "quoted_reply", "signature"
+}
+
+// Kinds of removed content
+const (
+	RemovedQuotedReply = "quoted_reply"
+	RemovedSignature   = "signature"
+	RemovedDisclaimer  = "disclaimer"
+	RemovedFooter      = "footer"
+	RemovedTracking    = "tracking_links"
+)
+
+var (
+	// "On Mon, Jan 6, 2025 at 9:14 AM Jane Doe <jane@example.com> wrote:" (sometimes wrapped onto two lines)
+	replyHeader = regexp.MustCompile(`(?i)^\s*(on\s.{4,250}\swrote:|le\s.{4,250}\sa écrit\s?:|am\s.{4,250}\sschrieb\s.{0,100}:|el\s.{4,250}\sescribió:)\s*$`)
+	// Outlook: "-----Original Message-----" or a line of underscores before the quoted headers
+	originalMessage = regexp.MustCompile(`(?i)^\s*-{2,}\s*(original message|reply message|mensaje original|ursprüngliche nachricht)\s*-{2,}\s*$`)
+	outlookRule     = regexp.MustCompile(`^\s*_{10,}\s*$`)
+	// Forwarded messages are content, not history: "---------- Forwarded message ---------", "Begin forwarded message:"
+	forwardedMessage = regexp.MustCompile(`(?i)^\s*(-{2,}\s*forwarded message\s*-{2,}|begin forwarded message:)\s*$`)
+	headerFrom       = regexp.MustCompile(`(?i)^\s*\*?(from|von|de):\*?\s+\S`)
+	headerOther      = regexp.MustCompile(`(?i)^\s*\*?(sent|date|to|subject|gesendet|envoyé|an|à|betreff|objet):\*?\s`)
+	quotedLine       = regexp.MustCompile(`^\s*>`)
+
+	signatureDelimiter = regexp.MustCompile(`^(--|__)\s*$`)
+	mobileSignature    = regexp.MustCompile(`(?i)^\s*(sent from my \w+|sent from (mail|outlook|yahoo mail) for \w+|get outlook for \w+|sent via \w+ mobile)\b.*$`)
+
+	disclaimer = regexp.MustCompile(`(?i)(this (e-?mail|message|communication)( and any (files|attachments)( transmitted with it)?)? (is|are|may be|contains?) (strictly )?(confidential|privileged|intended (solely|only))|confidentiality notice|if you (have )?received this (e-?mail|message|communication) in error|intended (solely|only) for the (use of the )?(individual|addressee|person|named recipient)|^\s*disclaimer\s*:|please consider the environment before printing)`)
+	footer     = regexp.MustCompile(`(?i)(unsubscribe|opt[- ]out|manage (your )?(e-?mail |subscription |notification )?(preferences|settings|subscriptions)|update your (e-?mail )?preferences|you('re| are) receiving this (e-?mail|message|because)|this (e-?mail|message) was sent to|view (this e-?mail |it )?in (your |a )?browser|no longer wish to receive|all rights reserved|©|\(c\) \d{4}|privacy policy|add us to your address book)`)
+
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// Clean strips quoted history, signatures, disclaimers, unsubscribe footers and tracking links.
+// HTML bodies are rendered as text first. If nothing would remain, the original text is kept with
+// only links cleaned.
+func Clean(body string) *Result {
+	result := &Result{Removed: []string{}}
+	text := strings.ReplaceAll(body, "\r\n", "\n")
+	if looksLikeHTML(text) {
+		text = htmlToText(text)
+	}
+
+	lines := strings.Split(text, "\n")
+	lines = result.cutQuotedReply(lines)
+	lines = result.cutSignature(lines)
+	paragraphs := result.dropBoilerplate(splitParagraphs(lines))
+	cleaned := strings.Join(paragraphs, "\n\n")
+
+	if strings.TrimSpace(cleaned) == "" {
+		result.Removed = []string{}
+		cleaned = text
+	}
+
+	cleaned, tracked := cleanLinks(cleaned)
+	if tracked {
+		result.Removed = append(result.Removed, RemovedTracking)
+	}
+
+	result.Text = strings.TrimSpace(blankLines.ReplaceAllString(cleaned, "\n\n"))
+	return result
+}
+
+func (r *Result) remove(kind string) {
+	for _, k := range r.Removed {
+		if k == kind {
+			return
+		}
+	}
+	r.Removed = append(r.Removed, kind)
+}
+
+// cutQuotedReply drops everything from the first reply header, and any remaining ">" lines.
+// A forwarded message and its header block are kept; only quoted history inside it is cut.
+func (r *Result) cutQuotedReply(lines []string) []string {
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if forwardedMessage.MatchString(line) {
+			i = skipForwardedHeaders(lines, i+1) - 1
+			continue
+		}
+		if replyHeader.MatchString(line) || originalMessage.MatchString(line) {
+			r.remove(RemovedQuotedReply)
+			return dropQuotedLines(lines[:i])
+		}
+		// Header wrapped onto two lines: "On Mon, Jan 6, 2025 at 9:14 AM Jane Doe" + "<jane@example.com> wrote:"
+		if i+1 < len(lines) && replyHeader.MatchString(strings.TrimSpace(line)+" "+strings.TrimSpace(lines[i+1])) && strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "on ") {
+			r.remove(RemovedQuotedReply)
+			return dropQuotedLines(lines[:i])
+		}
+		// Outlook quoted headers: "From: ..." followed closely by Sent/Date/To/Subject
+		if (headerFrom.MatchString(line) || outlookRule.MatchString(line)) && i > 0 && quotedHeadersFollow(lines[i:]) {
+			r.remove(RemovedQuotedReply)
+			return dropQuotedLines(lines[:i])
+		}
+	}
+	filtered := dropQuotedLines(lines)
+	if len(filtered) < len(lines) {
+		r.remove(RemovedQuotedReply)
+	}
+	return filtered
+}
+
+// quotedHeadersFollow returns true if a From: line is followed by at least two other header lines
+func quotedHeadersFollow(lines []string) bool {
+	seenFrom := false
+	headers := 0
+	for i, line := range lines {
+		if i > 6 {
+			break
+		}
+		if headerFrom.MatchString(line) {
+			seenFrom = true
+		} else if headerOther.MatchString(line) {
+			headers++
+		}
+	}
+	return seenFrom && headers >= 2
+}
+
+// skipForwardedHeaders returns the index of the first line after the header block
+// (From/Date/Subject/To) of a forwarded message starting at start
+func skipForwardedHeaders(lines []string, start int) int {
+	i := start
+	for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || headerFrom.MatchString(lines[i]) || headerOther.MatchString(lines[i])) {
+		i++
+	}
+	return i
+}
+
+func dropQuotedLines(lines []string) []string {
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !quotedLine.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// cutSignature drops everything after a "-- " delimiter in the second half of the body, and
+// mobile client signatures anywhere
+func (r *Result) cutSignature(lines []string) []string {
+	for i := len(lines) - 1; i >= len(lines)/2 && i > 0; i-- {
+		if signatureDelimiter.MatchString(lines[i]) {
+			r.remove(RemovedSignature)
+			lines = lines[:i]
+			break
+		}
+	}
+
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if mobileSignature.MatchString(line) {
+			r.remove(RemovedSignature)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// splitParagraphs groups lines into blank-line separated paragraphs
+func splitParagraphs(lines []string) []string {
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t"))
+	}
+	flush()
+	return paragraphs
+}
+
+// dropBoilerplate removes legal disclaimers anywhere and trailing unsubscribe/legal footers.
+// The first paragraph is always kept.
+func (r *Result) dropBoilerplate(paragraphs []string) []string {
+	kept := make([]string, 0, len(paragraphs))
+	for i, p := range paragraphs {
+		if i > 0 && disclaimer.MatchString(p) {
+			r.remove(RemovedDisclaimer)
+			continue
+		}
+		kept = append(kept, p)
+	}
+
+	// Footers: trim matching paragraphs from the end, stopping at the first real content
+	end := len(kept)
+	for end > 1 && (footer.MatchString(kept[end-1]) || isLinkOnly(kept[end-1])) {
+		end--
+	}
+	if end < len(kept) {
+		r.remove(RemovedFooter)
+	}
+	return kept[:end]
+}
@@ -0,0 +1,121 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files from the current output")
+
+// TestCleanGolden runs Clean on each testdata/*.eml sample and compares the cleaned text and
+// removed kinds with testdata/*.golden. Run with -update to accept new output.
+func TestCleanGolden(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join("testdata", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Fatal("no samples in testdata")
+	}
+
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".eml")
+		t.Run(name, func(t *testing.T) {
+			body := readSampleBody(t, sample)
+			result := Clean(body)
+			removed := strings.Join(result.Removed, ", ")
+			if removed == "" {
+				removed = "none"
+			}
+			got := "removed: " + removed + "\n---\n" + result.Text + "\n"
+
+			golden := strings.TrimSuffix(sample, ".eml") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("Clean(%s) mismatch\n--- got ---\n%s--- want ---\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+// readSampleBody returns the text/plain body of a sample message, or its HTML body if it has none,
+// the same way the Gmail client picks a body
+func readSampleBody(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	bodies := map[string]string{}
+	if err := collectBodies(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, bodies); err != nil {
+		t.Fatalf("failed to read body of %s: %v", path, err)
+	}
+	if bodies["text/plain"] != "" {
+		return bodies["text/plain"]
+	}
+	return bodies["text/html"]
+}
+
+// collectBodies decodes the first text/plain and text/html parts of a MIME body into bodies
+func collectBodies(contentType, transferEncoding string, r io.Reader, bodies map[string]string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := collectBodies(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, bodies); err != nil {
+				return err
+			}
+		}
+	}
+	if bodies[mediaType] != "" {
+		return nil
+	}
+
+	switch strings.ToLower(transferEncoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+	bodies[mediaType] = buf.String()
+	return nil
+}
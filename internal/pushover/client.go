@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 const apiURL = "https://api.pushover.net/1/messages.json"
@@ -14,13 +15,25 @@ func NewClient() *Client {
 	return &Client{}
 }
 
+// Pushover message priorities
+const (
+	PriorityNormal = 0
+	PriorityHigh   = 1 // Bypasses the user's quiet hours
+)
+
 // Send sends a push notification via the Pushover API using the provided per-user credentials.
 func (c *Client) Send(userKey, appToken, title, message string) error {
+	return c.SendWithPriority(userKey, appToken, title, message, PriorityNormal)
+}
+
+// SendWithPriority sends a push notification with a Pushover priority (PriorityNormal or PriorityHigh).
+func (c *Client) SendWithPriority(userKey, appToken, title, message string, priority int) error {
 	resp, err := http.PostForm(apiURL, url.Values{
-		"token":   {appToken},
-		"user":    {userKey},
-		"title":   {title},
-		"message": {message},
+		"token":    {appToken},
+		"user":     {userKey},
+		"title":    {title},
+		"message":  {message},
+		"priority": {strconv.Itoa(priority)},
 	})
 	if err != nil {
 		return fmt.Errorf("pushover request failed: %w", err)
@@ -0,0 +1,108 @@
+package redact
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtinDetectors run in order: the more specific formats claim their matches before the
+// phone detector sees the digits
+var builtinDetectors = []detector{
+	{
+		kind:     "IBAN",
+		pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		validate: validIBAN,
+	},
+	{
+		// Visa, Mastercard and Discover in groups of four, and Amex as 4-6-5
+		kind:     "CARD",
+		pattern:  regexp.MustCompile(`\b[2-6]\d{3}(?:[ -]?\d{4}){2}[ -]?\d{1,4}(?:[ -]?\d{3})?\b|\b3[47]\d{2}[ -]?\d{6}[ -]?\d{5}\b`),
+		validate: validLuhn,
+	},
+	{
+		// US Social Security and UK National Insurance numbers
+		kind:    "GOV_ID",
+		pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+	},
+	{
+		// Only phone-shaped numbers: an international +prefix, an area code in parentheses, a national
+		// trunk 0, or the North American 3-3-4 grouping. Spaced digit runs such as tracking and
+		// invoice numbers (7749 1234 5678, 2024-0001-2345) are left alone.
+		kind: "PHONE",
+		pattern: regexp.MustCompile(`\+\d{1,3}(?:[ .-]?(?:\(\d{1,5}\)|\d{1,5})){2,6}\b` +
+			`|\(\d{2,5}\)[ .-]?\d{3,4}[ .-]?\d{3,4}\b` +
+			`|\b0[1-9]\d{0,3}[ .-]\d{3,8}(?:[ .-]\d{2,4})?\b` +
+			`|\b[2-9]\d{2}[ .-]\d{3}[ .-]\d{4}\b`),
+		validate: validPhone,
+	},
+	{
+		kind:    "ADDRESS",
+		pattern: regexp.MustCompile(`\b\d{1,5} (?:[A-Z][A-Za-z'-]* ){1,4}(?i:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|terrace|parkway|pkwy|highway|hwy|square|sq)\b\.?(?:,? (?i:apt|suite|unit|#) ?[A-Za-z0-9-]+)?`),
+	},
+}
+
+// digits returns only the digits of s
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validLuhn checks a card number's Luhn checksum
+func validLuhn(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks an IBAN's ISO 13616 mod-97 checksum
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(r - 'A' + 10)))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validPhone accepts 10-15 digit numbers, which rules out most dates, times and short codes
+func validPhone(match string) bool {
+	n := len(digits(match))
+	return n >= 10 && n <= 15
+}
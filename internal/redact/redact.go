@@ -0,0 +1,143 @@
+// Package redact replaces personal data in email content with typed placeholders before it is
+// sent to an AI provider, and restores the original values in AI output kept locally.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pattern is a named regular expression whose matches are redacted
+type Pattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// detector finds one kind of personal data; validate (optional) rejects false positives
+type detector struct {
+	kind     string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// Redactor replaces matches of the built-in detectors and any custom patterns
+type Redactor struct {
+	detectors []detector
+}
+
+// New returns a redactor with the built-in detectors followed by the custom patterns.
+// Custom patterns are redacted as their upper-cased name (e.g. "patient id" becomes [PATIENT_ID_1]).
+func New(custom []Pattern) (*Redactor, error) {
+	r := &Redactor{detectors: append([]detector{}, builtinDetectors...)}
+	for _, p := range custom {
+		compiled, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p.Name, err)
+		}
+		r.detectors = append(r.detectors, detector{kind: placeholderKind(p.Name), pattern: compiled})
+	}
+	return r, nil
+}
+
+// ValidatePatterns checks that custom patterns have a name and compile
+func ValidatePatterns(patterns []Pattern) error {
+	for _, p := range patterns {
+		if placeholderKind(p.Name) == "" {
+			return fmt.Errorf("pattern name is required")
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+var nonWord = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// placeholderKind turns a pattern name into a placeholder prefix
+func placeholderKind(name string) string {
+	return strings.Trim(nonWord.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
+
+// Result records the placeholders used for one email so they can be restored
+type Result struct {
+	originals map[string]string // placeholder -> original value
+	values    map[string]string // original value -> placeholder
+	counts    map[string]int    // kind -> distinct values redacted
+}
+
+// NewResult returns an empty result; texts redacted into the same result share placeholders
+func NewResult() *Result {
+	return &Result{originals: map[string]string{}, values: map[string]string{}, counts: map[string]int{}}
+}
+
+// Redact replaces personal data in text with placeholders such as [CARD_1], recording them in result.
+// The same value always gets the same placeholder.
+func (r *Redactor) Redact(text string, result *Result) string {
+	for _, d := range r.detectors {
+		text = d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if isPlaceholder(match) || (d.validate != nil && !d.validate(match)) {
+				return match
+			}
+			return result.placeholder(d.kind, match)
+		})
+	}
+	return text
+}
+
+func (res *Result) placeholder(kind, value string) string {
+	if p, ok := res.values[value]; ok {
+		return p
+	}
+	res.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", kind, res.counts[kind])
+	res.values[value] = p
+	res.originals[p] = value
+	return p
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`^\[[A-Z0-9_]+_\d+\]$`)
+	placeholderInText  = regexp.MustCompile(`\[[A-Z0-9_]+_\d+\]`)
+)
+
+func isPlaceholder(s string) bool {
+	return placeholderPattern.MatchString(s)
+}
+
+// HasPlaceholder reports whether text still contains a placeholder, e.g. an identifier the AI
+// copied from a redacted email that could not be restored
+func HasPlaceholder(text string) bool {
+	return placeholderInText.MatchString(text)
+}
+
+// Restore puts the original values back in place of the placeholders in text (e.g. an AI draft)
+func (res *Result) Restore(text string) string {
+	if len(res.originals) == 0 {
+		return text
+	}
+	// Longest placeholders first so [CARD_1] does not clobber [CARD_10]
+	placeholders := make([]string, 0, len(res.originals))
+	for p := range res.originals {
+		placeholders = append(placeholders, p)
+	}
+	sort.Slice(placeholders, func(i, j int) bool { return len(placeholders[i]) > len(placeholders[j]) })
+
+	pairs := make([]string, 0, len(placeholders)*2)
+	for _, p := range placeholders {
+		pairs = append(pairs, p, res.originals[p])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Count returns the number of distinct values redacted
+func (res *Result) Count() int {
+	return len(res.originals)
+}
+
+// Counts returns the number of distinct values redacted per kind
+func (res *Result) Counts() map[string]int {
+	return res.counts
+}
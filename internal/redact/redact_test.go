@@ -0,0 +1,175 @@
+package redact
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactDetectors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Card numbers pass the Luhn check
+		{"visa spaced", "Card 4111 1111 1111 1111 was charged", "Card [CARD_1] was charged"},
+		{"visa contiguous", "Card 4111111111111111", "Card [CARD_1]"},
+		{"mastercard dashed", "Card 5555-5555-5555-4444", "Card [CARD_1]"},
+		{"amex", "Amex 3782 822463 10005", "Amex [CARD_1]"},
+		{"card failing luhn", "Card 4111 1111 1111 1112", "Card 4111 1111 1111 1112"},
+
+		// IBANs pass the mod-97 check
+		{"iban spaced", "Pay GB82 WEST 1234 5698 7654 32 today", "Pay [IBAN_1] today"},
+		{"iban contiguous", "IBAN DE89370400440532013000", "IBAN [IBAN_1]"},
+		{"iban bad checksum", "Pay GB82 WEST 1234 5698 7654 33", "Pay GB82 WEST 1234 5698 7654 33"},
+
+		// Government IDs
+		{"ssn", "SSN 123-45-6789 on file", "SSN [GOV_ID_1] on file"},
+		{"national insurance", "NI number AB 12 34 56 C", "NI number [GOV_ID_1]"},
+
+		// Addresses
+		{"street address", "Deliver to 221 Baker Street tomorrow", "Deliver to [ADDRESS_1] tomorrow"},
+		{"address with suite", "Visit 1600 Pennsylvania Avenue, Suite 200.", "Visit [ADDRESS_1]."},
+
+		// Phone numbers
+		{"north american dashed", "Call 555-123-4567 now", "Call [PHONE_1] now"},
+		{"north american area code", "Call (555) 123-4567", "Call [PHONE_1]"},
+		{"international", "Call +44 20 7946 0958", "Call [PHONE_1]"},
+		{"international with area code", "Call +1 (555) 123-4567", "Call [PHONE_1]"},
+		{"uk national", "Call 020 7946 0958", "Call [PHONE_1]"},
+		{"uk mobile", "Text 07700 900123", "Text [PHONE_1]"},
+	}
+
+	r, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Redact(tt.in, NewResult()); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactFalsePositives(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"spaced tracking number", "FedEx tracking 7749 1234 5678"},
+		{"second spaced tracking number", "FedEx tracking 7749 8765 4321"},
+		{"ups tracking number", "UPS 1Z999AA10123456784"},
+		{"usps tracking number", "USPS 9400 1000 0000 0000 0000 00"},
+		{"amazon order number", "Order 112-4431234-5566123"},
+		{"dashed invoice number", "Invoice 2024-0001-2345"},
+		{"spaced reference", "Reference 1234 5678 9012 3456"},
+		{"iso date", "Due on 2024-01-15"},
+		{"slashed date", "Due on 15/01/2024"},
+		{"dotted date", "Due on 15.01.2024"},
+		{"long numeric id", "Account 1234567890123"},
+		{"longer numeric id", "Customer 98765432101234"},
+		{"time range", "Open 09:00 - 17:30"},
+	}
+
+	r, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Redact(tt.in, NewResult()); got != tt.in {
+				t.Errorf("Redact(%q) = %q, want it unchanged", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestRedactCustomPatterns(t *testing.T) {
+	r, err := New([]Pattern{{Name: "patient id", Pattern: `PT-\d{6}`}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got, want := r.Redact("Patient PT-123456", NewResult()), "Patient [PATIENT_ID_1]"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+
+	if _, err := New([]Pattern{{Name: "broken", Pattern: `(`}}); err == nil {
+		t.Error("New accepted an invalid pattern")
+	}
+}
+
+func TestRedactReusesPlaceholders(t *testing.T) {
+	r, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	result := NewResult()
+	subject := r.Redact("Call 555-123-4567", result)
+	body := r.Redact("Call 555-123-4567 or 555-987-6543", result)
+
+	if subject != "Call [PHONE_1]" {
+		t.Errorf("subject = %q", subject)
+	}
+	if body != "Call [PHONE_1] or [PHONE_2]" {
+		t.Errorf("body = %q", body)
+	}
+	if result.Count() != 2 || result.Counts()["PHONE"] != 2 {
+		t.Errorf("Count() = %d, Counts() = %v, want 2 phones", result.Count(), result.Counts())
+	}
+}
+
+func TestRestoreRoundTrip(t *testing.T) {
+	r, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Twelve phone numbers, so [PHONE_1] and [PHONE_10] through [PHONE_12] are all in use
+	var numbers []string
+	for i := 0; i < 12; i++ {
+		numbers = append(numbers, fmt.Sprintf("555-%03d-%04d", 200+i, 1000+i))
+	}
+	original := "Numbers: " + strings.Join(numbers, ", ") + ". Card 4111 1111 1111 1111 to 221 Baker Street."
+
+	result := NewResult()
+	redacted := r.Redact(original, result)
+	for _, number := range numbers {
+		if strings.Contains(redacted, number) {
+			t.Fatalf("redacted text still contains %s: %q", number, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "[PHONE_1],") || !strings.Contains(redacted, "[PHONE_10],") {
+		t.Fatalf("expected [PHONE_1] and [PHONE_10] in %q", redacted)
+	}
+
+	if restored := result.Restore(redacted); restored != original {
+		t.Errorf("Restore = %q, want %q", restored, original)
+	}
+
+	// AI output may reorder placeholders
+	if got, want := result.Restore("[PHONE_10] then [PHONE_1]"), numbers[9]+" then "+numbers[0]; got != want {
+		t.Errorf("Restore = %q, want %q", got, want)
+	}
+}
+
+func TestHasPlaceholder(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"[PHONE_1]", true},
+		{"TRACK-[PHONE_12]", true},
+		{"[PATIENT_ID_3]", true},
+		{"774912345678", false},
+		{"[phone_1]", false},
+		{"[PHONE]", false},
+	}
+	for _, tt := range tests {
+		if got := HasPlaceholder(tt.in); got != tt.want {
+			t.Errorf("HasPlaceholder(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
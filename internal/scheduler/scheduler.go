@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/config"
 	"github.com/den/gmail-triage-assistant/internal/database"
 	"github.com/den/gmail-triage-assistant/internal/gmail"
 	"github.com/den/gmail-triage-assistant/internal/memory"
@@ -14,15 +15,17 @@ import (
 
 type Scheduler struct {
 	db            *database.DB
+	config        *config.Config
 	memoryService *memory.Service
 	wrapupService *wrapup.Service
 	oauthConfig   *oauth2.Config
 	stopChan      chan struct{}
 }
 
-func NewScheduler(db *database.DB, memoryService *memory.Service, wrapupService *wrapup.Service, oauthConfig *oauth2.Config) *Scheduler {
+func NewScheduler(db *database.DB, cfg *config.Config, memoryService *memory.Service, wrapupService *wrapup.Service, oauthConfig *oauth2.Config) *Scheduler {
 	return &Scheduler{
 		db:            db,
+		config:        cfg,
 		memoryService: memoryService,
 		wrapupService: wrapupService,
 		oauthConfig:   oauthConfig,
@@ -175,6 +178,24 @@ func (s *Scheduler) runEveningTasks(ctx context.Context) {
 	} else if deleted > 0 {
 		log.Printf("Cleaned up %d stale sender profiles", deleted)
 	}
+
+	// Cleanup expired explanation snapshots
+	if s.config.ExplanationRetentionDays > 0 {
+		deleted, err = s.db.DeleteExpiredExplanations(ctx, s.config.ExplanationRetentionDays)
+		if err != nil {
+			log.Printf("Error cleaning up expired explanations: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Cleaned up %d expired email explanations", deleted)
+		}
+	}
+
+	// Cleanup expired decision cache entries
+	deleted, err = s.db.DeleteExpiredCachedDecisions(ctx)
+	if err != nil {
+		log.Printf("Error cleaning up decision cache: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Cleaned up %d expired cached decisions", deleted)
+	}
 }
 
 func (s *Scheduler) runWeeklyMemory(ctx context.Context) {
@@ -273,5 +294,79 @@ func (s *Scheduler) runTimedLabelsSweep(ctx context.Context) {
 		} else {
 			log.Printf("✓ Timed labels processed for %s", user.Email)
 		}
+
+		s.detectUnarchivedExperimentEmails(ctx, user, client)
+		s.refreshSubscriptionReads(ctx, user, client)
+	}
+}
+
+// Read emails are listed over this window, at most maxReadScan IDs, by each subscription sweep.
+// Emails read long after they arrived are not counted.
+const (
+	subscriptionReadWindow = "newer_than:14d"
+	maxReadScan            = 1000
+)
+
+// refreshSubscriptionReads records when the user last read an email from each subscription, for the
+// subscription inventory's engagement and unsubscribe suggestions. One listing of recently received
+// read emails is matched against the processed emails' subscriptions, so the Gmail cost doesn't
+// grow with the number of subscriptions.
+func (s *Scheduler) refreshSubscriptionReads(ctx context.Context, user *database.User, client *gmail.Client) {
+	readIDs, err := client.ListMessageIDs(ctx, "-is:unread "+subscriptionReadWindow, maxReadScan)
+	if err != nil {
+		log.Printf("Failed to list read emails for %s: %v", user.Email, err)
+		return
+	}
+
+	updated, err := s.db.MarkSubscriptionsRead(ctx, user.ID, readIDs)
+	if err != nil {
+		log.Printf("Failed to refresh subscription reads for %s: %v", user.Email, err)
+		return
+	}
+	if updated > 0 {
+		log.Printf("Refreshed last read time of %d subscriptions for %s", updated, user.Email)
+	}
+}
+
+// maxInboxScan caps the inbox message IDs listed when looking for un-archived experiment emails
+const maxInboxScan = 2000
+
+// detectUnarchivedExperimentEmails flags AI-archived experiment emails the user has moved back to the inbox.
+// Only emails from the last 30 days are checked, against a single listing of the recent inbox.
+func (s *Scheduler) detectUnarchivedExperimentEmails(ctx context.Context, user *database.User, client *gmail.Client) {
+	ids, err := s.db.GetArchivedExperimentEmailIDs(ctx, user.ID, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		log.Printf("Failed to load archived experiment emails for %s: %v", user.Email, err)
+		return
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	// One listing of the recent inbox instead of a Gmail call per experiment email
+	inboxIDs, err := client.ListMessageIDs(ctx, "in:inbox newer_than:31d", maxInboxScan)
+	if err != nil {
+		log.Printf("Failed to list inbox for %s: %v", user.Email, err)
+		return
+	}
+	inInbox := make(map[string]bool, len(inboxIDs))
+	for _, id := range inboxIDs {
+		inInbox[id] = true
+	}
+
+	unarchived := 0
+	for _, id := range ids {
+		if !inInbox[id] {
+			continue
+		}
+		if err := s.db.MarkEmailUnarchived(ctx, user.ID, id); err != nil {
+			log.Printf("Failed to mark email %s unarchived: %v", id, err)
+		} else {
+			unarchived++
+		}
+	}
+	if unarchived > 0 {
+		log.Printf("Detected %d un-archived experiment emails for %s", unarchived, user.Email)
 	}
 }
@@ -3,12 +3,16 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
 	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/pipeline"
+	"github.com/den/gmail-triage-assistant/internal/redact"
 	"github.com/gorilla/mux"
 )
 
@@ -162,8 +166,19 @@ func (s *Server) handleAPIGetEmails(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	bucket := r.URL.Query().Get("bucket")
+	if bucket != "" && !database.IsValidBucket(bucket) {
+		respondError(w, http.StatusBadRequest, "Unknown bucket")
+		return
+	}
+	triageVia := r.URL.Query().Get("triage_via")
+	if triageVia != "" && !database.IsValidTriageVia(triageVia) {
+		respondError(w, http.StatusBadRequest, "triage_via must be \"ai\", \"cache\", \"thread_reply\", \"consistent_sender\" or \"security\"")
+		return
+	}
+
 	ctx := context.Background()
-	emails, err := s.db.GetRecentEmails(ctx, userID, limit, offset)
+	emails, err := s.db.GetRecentEmails(ctx, userID, limit, offset, bucket, triageVia)
 	if err != nil {
 		log.Printf("API: Failed to load emails: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to load emails")
@@ -199,6 +214,35 @@ func (s *Server) handleAPIUpdateFeedback(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
+// POST /api/v1/emails/{id}/release-notification
+// Sends a notification that was held because the email looked like a prompt injection.
+func (s *Server) handleAPIReleaseNotification(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	emailID := mux.Vars(r)["id"]
+
+	ctx := context.Background()
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to load user: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	if err := s.processor.ReleaseHeldNotification(ctx, user, emailID); err != nil {
+		if errors.Is(err, pipeline.ErrNoHeldNotification) {
+			respondError(w, http.StatusNotFound, "No held notification for this email")
+			return
+		}
+		log.Printf("API: Failed to release held notification: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to send notification")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "sent"})
+}
+
 // GET /api/v1/prompts
 func (s *Server) handleAPIGetPrompts(w http.ResponseWriter, r *http.Request) {
 	session, _ := s.sessionStore.Get(r, "session")
@@ -354,13 +398,17 @@ func (s *Server) handleAPIGetSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"processing_enabled":   user.IsActive,
-		"pushover_user_key":    maskedKey,
-		"pushover_configured":  user.HasPushoverConfig(),
-		"webhook_url":          user.WebhookURL,
-		"webhook_header_key":   user.WebhookHeaderKey,
-		"webhook_header_value": maskedHeaderValue,
-		"webhook_configured":   user.HasWebhookConfig(),
+		"processing_enabled":     user.IsActive,
+		"pushover_user_key":      maskedKey,
+		"pushover_configured":    user.HasPushoverConfig(),
+		"webhook_url":            user.WebhookURL,
+		"webhook_header_key":     user.WebhookHeaderKey,
+		"webhook_header_value":   maskedHeaderValue,
+		"webhook_configured":     user.HasWebhookConfig(),
+		"daily_budget_usd":       user.DailyBudgetUSD,
+		"monthly_budget_usd":     user.MonthlyBudgetUSD,
+		"pipeline_mode":          user.PipelineMode,
+		"decision_cache_enabled": user.DecisionCacheEnabled,
 	})
 }
 
@@ -439,6 +487,156 @@ func (s *Server) handleAPIUpdateWebhook(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
+// PUT /api/v1/settings/pipeline-mode
+// Switches between the two-stage pipeline, the single-call economy mode and the bucket pipeline.
+func (s *Server) handleAPIUpdatePipelineMode(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !database.IsValidPipelineMode(body.Mode) {
+		respondError(w, http.StatusBadRequest, "Invalid request body: expected { mode: \"two_stage\" | \"economy\" | \"buckets\" }")
+		return
+	}
+
+	ctx := context.Background()
+	if err := s.db.UpdatePipelineMode(ctx, userID, body.Mode); err != nil {
+		log.Printf("API: Failed to update pipeline mode: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save pipeline mode")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "updated",
+		"pipeline_mode": body.Mode,
+	})
+}
+
+// PUT /api/v1/settings/decision-cache
+// Enables or bypasses reuse of cached decisions for duplicate emails.
+func (s *Server) handleAPIUpdateDecisionCache(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: expected { enabled: boolean }")
+		return
+	}
+
+	ctx := context.Background()
+	if err := s.db.SetDecisionCacheEnabled(ctx, userID, *body.Enabled); err != nil {
+		log.Printf("API: Failed to update decision cache setting: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save decision cache setting")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":                 "updated",
+		"decision_cache_enabled": *body.Enabled,
+	})
+}
+
+// GET /api/v1/settings/redaction
+func (s *Server) handleAPIGetRedaction(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	settings, err := s.db.GetRedactionSettings(context.Background(), userID)
+	if err != nil {
+		log.Printf("API: Failed to load redaction settings: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load redaction settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// PUT /api/v1/settings/redaction
+// Updates PII redaction: built-in detectors on/off, custom patterns and senders/domains sent unredacted.
+// Omitted fields are left unchanged.
+func (s *Server) handleAPIUpdateRedaction(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		Enabled   *bool                        `json:"enabled"`
+		Patterns  *[]database.RedactionPattern `json:"patterns"`
+		Allowlist *[]string                    `json:"allowlist"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	ctx := context.Background()
+	settings, err := s.db.GetRedactionSettings(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to load redaction settings: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load redaction settings")
+		return
+	}
+
+	if body.Enabled != nil {
+		settings.Enabled = *body.Enabled
+	}
+	if body.Patterns != nil {
+		patterns := make([]redact.Pattern, len(*body.Patterns))
+		for i, p := range *body.Patterns {
+			patterns[i] = redact.Pattern(p)
+		}
+		if err := redact.ValidatePatterns(patterns); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		settings.Patterns = *body.Patterns
+	}
+	if body.Allowlist != nil {
+		settings.Allowlist = *body.Allowlist
+	}
+
+	if err := s.db.SaveRedactionSettings(ctx, settings); err != nil {
+		log.Printf("API: Failed to save redaction settings: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save redaction settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// PUT /api/v1/settings/budget
+// Sets the user's AI spending limits in USD; 0 falls back to the server default.
+func (s *Server) handleAPIUpdateBudget(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		DailyBudgetUSD   float64 `json:"daily_budget_usd"`
+		MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if body.DailyBudgetUSD < 0 || body.MonthlyBudgetUSD < 0 {
+		respondError(w, http.StatusBadRequest, "Budgets cannot be negative")
+		return
+	}
+
+	ctx := context.Background()
+	if err := s.db.UpdateUserBudgets(ctx, userID, body.DailyBudgetUSD, body.MonthlyBudgetUSD); err != nil {
+		log.Printf("API: Failed to update budgets: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save budget settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
 // GET /api/v1/notifications
 func (s *Server) handleAPIGetNotifications(w http.ResponseWriter, r *http.Request) {
 	session, _ := s.sessionStore.Get(r, "session")
@@ -558,7 +756,7 @@ func (s *Server) handleAPIGenerateSenderProfile(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	ctx := context.Background()
+	ctx := ai.WithUser(context.Background(), userID)
 
 	// Fetch historical emails
 	var emails []*database.Email
@@ -585,8 +783,8 @@ func (s *Server) handleAPIGenerateSenderProfile(w http.ResponseWriter, r *http.R
 
 	// If we have history, use AI to classify and summarize
 	var aiError string
-	if len(emails) > 0 && s.openaiClient != nil {
-		result, err := s.openaiClient.BootstrapSenderProfile(ctx, body.Identifier, emails)
+	if len(emails) > 0 && s.ai != nil {
+		result, err := s.ai.BootstrapSenderProfile(ctx, body.Identifier, emails)
 		if err != nil {
 			log.Printf("API: Error bootstrapping profile for %s: %v", body.Identifier, err)
 			aiError = err.Error()
@@ -594,8 +792,8 @@ func (s *Server) handleAPIGenerateSenderProfile(w http.ResponseWriter, r *http.R
 			profile.SenderType = result.SenderType
 			profile.Summary = result.Summary
 		}
-	} else if s.openaiClient == nil {
-		aiError = "openai client not configured"
+	} else if s.ai == nil {
+		aiError = "AI client not configured"
 	} else {
 		aiError = "no historical emails found"
 	}
@@ -642,6 +840,7 @@ func (s *Server) handleAPIUpdateSenderProfile(w http.ResponseWriter, r *http.Req
 		Summary     *string         `json:"summary"`
 		SenderType  *string         `json:"sender_type"`
 		LabelCounts *map[string]int `json:"label_counts"`
+		Mixed       *bool           `json:"mixed"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
@@ -670,6 +869,9 @@ func (s *Server) handleAPIUpdateSenderProfile(w http.ResponseWriter, r *http.Req
 	if body.LabelCounts != nil {
 		profile.LabelCounts = *body.LabelCounts
 	}
+	if body.Mixed != nil {
+		profile.Mixed = *body.Mixed
+	}
 
 	if err := s.db.UpsertSenderProfile(ctx, profile); err != nil {
 		log.Printf("API: Failed to update sender profile: %v", err)
@@ -719,6 +921,83 @@ func (s *Server) handleAPIGetStatsTimeseries(w http.ResponseWriter, r *http.Requ
 	respondJSON(w, http.StatusOK, timeseries)
 }
 
+// GET /api/v1/stats/usage
+// Returns AI token usage and estimated cost by task and model, per day, budget status and decision cache hit rate.
+func (s *Server) handleAPIGetStatsUsage(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	ctx := context.Background()
+	breakdown, err := s.db.GetUsageBreakdown(ctx, userID, since)
+	if err != nil {
+		log.Printf("API: Failed to load usage breakdown: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+
+	daily, err := s.db.GetDailyUsage(ctx, userID, since)
+	if err != nil {
+		log.Printf("API: Failed to load daily usage: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to load user: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+	budget, err := s.processor.BudgetStatus(ctx, user)
+	if err != nil {
+		log.Printf("API: Failed to load budget status: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+
+	cache, err := s.db.GetCacheStats(ctx, userID, since)
+	if err != nil {
+		log.Printf("API: Failed to load cache stats: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+
+	fastPaths, err := s.db.GetFastPathStats(ctx, userID, since)
+	if err != nil {
+		log.Printf("API: Failed to load fast path stats: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load usage stats")
+		return
+	}
+
+	var totalCost float64
+	var promptTokens, completionTokens int
+	for _, b := range breakdown {
+		totalCost += b.CostUSD
+		promptTokens += b.PromptTokens
+		completionTokens += b.CompletionTokens
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"days":              days,
+		"total_cost_usd":    totalCost,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"by_task_model":     breakdown,
+		"daily":             daily,
+		"budget":            budget,
+		"cache":             cache,
+		"fast_paths":        fastPaths,
+	})
+}
+
 // GET /api/v1/wrapups
 func (s *Server) handleAPIGetWrapups(w http.ResponseWriter, r *http.Request) {
 	session, _ := s.sessionStore.Get(r, "session")
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxQuestionLength bounds the question accepted by /ask
+const maxQuestionLength = 500
+
+// POST /api/v1/ask
+// Answers a natural-language question about the processed mail archive, citing the emails used.
+func (s *Server) handleAPIAsk(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		Question string `json:"question"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	body.Question = strings.TrimSpace(body.Question)
+	if body.Question == "" {
+		respondError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	if len(body.Question) > maxQuestionLength {
+		respondError(w, http.StatusBadRequest, "question is too long")
+		return
+	}
+
+	answer, err := s.askService.Ask(context.Background(), userID, body.Question)
+	if err != nil {
+		log.Printf("API: Ask failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to answer question")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, answer)
+}
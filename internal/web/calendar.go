@@ -0,0 +1,194 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/gorilla/mux"
+)
+
+// calendarFeedWindow is how far back the feed and the events list reach
+const calendarFeedWindow = 90 * 24 * time.Hour
+
+// GET /api/v1/events
+// Upcoming and recent calendar events parsed from invites or extracted from emails.
+func (s *Server) handleAPIGetEvents(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	events, err := s.db.GetEvents(context.Background(), userID, time.Now().Add(-calendarFeedWindow), 500)
+	if err != nil {
+		log.Printf("API: Failed to load events: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load events")
+		return
+	}
+	respondJSON(w, http.StatusOK, events)
+}
+
+// GET /api/v1/calendar/feed
+// The user's ICS subscription URL, created on first request.
+func (s *Server) handleAPIGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	token, err := s.db.GetCalendarFeedToken(context.Background(), userID)
+	if err != nil {
+		log.Printf("API: Failed to get calendar feed token: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get calendar feed")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"url": s.calendarFeedURL(r, token)})
+}
+
+// POST /api/v1/calendar/feed/rotate
+// Replaces the feed token; calendars subscribed to the old URL stop updating.
+func (s *Server) handleAPIRotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	token, err := s.db.RotateCalendarFeedToken(context.Background(), userID)
+	if err != nil {
+		log.Printf("API: Failed to rotate calendar feed token: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to rotate calendar feed")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"url": s.calendarFeedURL(r, token)})
+}
+
+// GET /calendar/{token}.ics
+// Public ICS feed for calendar clients; the token in the URL is the only credential.
+func (s *Server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID, err := s.db.GetUserIDByCalendarFeedToken(ctx, mux.Vars(r)["token"])
+	if err != nil {
+		log.Printf("Calendar feed: failed to look up token: %v", err)
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+	if userID == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, err := s.db.GetEvents(ctx, userID, time.Now().Add(-calendarFeedWindow), 0)
+	if err != nil {
+		log.Printf("Calendar feed: failed to load events: %v", err)
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(renderICS(events, time.Now()))
+}
+
+// calendarFeedURL builds the public feed URL on the app's configured origin (from the OAuth
+// redirect URL), falling back to the request's host
+func (s *Server) calendarFeedURL(r *http.Request, token string) string {
+	origin := ""
+	if u, err := url.Parse(s.config.GoogleRedirectURL); err == nil && u.Host != "" {
+		origin = u.Scheme + "://" + u.Host
+	} else {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		origin = scheme + "://" + r.Host
+	}
+	return origin + "/calendar/" + token + ".ics"
+}
+
+// renderICS renders events as an iCalendar document. Times are written in UTC; all-day events as dates.
+func renderICS(events []*database.Event, now time.Time) []byte {
+	var buf bytes.Buffer
+	line := func(content string) {
+		buf.WriteString(foldICSLine(content))
+		buf.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//gmail-triage-assistant//Email Events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:Email Events")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeICS(e.UID))
+		line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		line("LAST-MODIFIED:" + e.UpdatedAt.UTC().Format("20060102T150405Z"))
+		line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:" + e.StartsAt.UTC().Format("20060102"))
+			end := e.StartsAt.UTC().AddDate(0, 0, 1)
+			if e.EndsAt != nil && e.EndsAt.After(e.StartsAt) {
+				end = e.EndsAt.UTC()
+			}
+			line("DTEND;VALUE=DATE:" + end.Format("20060102"))
+		} else {
+			line("DTSTART:" + e.StartsAt.UTC().Format("20060102T150405Z"))
+			if e.EndsAt != nil {
+				line("DTEND:" + e.EndsAt.UTC().Format("20060102T150405Z"))
+			}
+		}
+		if e.RRule != "" {
+			line("RRULE:" + e.RRule)
+		}
+		if len(e.RecurrenceID) == len("20060102") {
+			line("RECURRENCE-ID;VALUE=DATE:" + e.RecurrenceID)
+		} else if e.RecurrenceID != "" {
+			line("RECURRENCE-ID:" + e.RecurrenceID)
+		}
+		line("SUMMARY:" + escapeICS(e.Summary))
+		if e.Location != "" {
+			line("LOCATION:" + escapeICS(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeICS(e.Description))
+		}
+		if e.Organizer != "" {
+			line("ORGANIZER:mailto:" + e.Organizer)
+		}
+		for _, attendee := range e.Attendees {
+			line("ATTENDEE:mailto:" + attendee)
+		}
+		if e.Status != "" {
+			line("STATUS:" + e.Status)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// escapeICS applies RFC 5545 text escaping
+func escapeICS(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// foldICSLine folds a content line to 75 octets, without splitting UTF-8 sequences
+func foldICSLine(content string) string {
+	const limit = 75
+	if len(content) <= limit {
+		return content
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range content {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/gorilla/mux"
+)
+
+// GET /api/v1/experiments
+func (s *Server) handleAPIGetExperiments(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	ctx := context.Background()
+	experiments, err := s.db.GetExperiments(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to get experiments: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get experiments")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, experiments)
+}
+
+// POST /api/v1/experiments
+func (s *Server) handleAPICreateExperiment(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		Name         string                     `json:"name"`
+		Description  string                     `json:"description"`
+		VariantA     database.ExperimentVariant `json:"variant_a"`
+		VariantB     database.ExperimentVariant `json:"variant_b"`
+		TrafficSplit *int                       `json:"traffic_split"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if body.Name == "" {
+		respondError(w, http.StatusBadRequest, "Experiment name is required")
+		return
+	}
+
+	for _, variant := range []database.ExperimentVariant{body.VariantA, body.VariantB} {
+		if variant.PipelineMode != "" && !database.IsValidPipelineMode(variant.PipelineMode) {
+			respondError(w, http.StatusBadRequest, "pipeline_mode must be \"two_stage\", \"economy\" or \"buckets\"")
+			return
+		}
+	}
+
+	split := 50
+	if body.TrafficSplit != nil {
+		split = *body.TrafficSplit
+	}
+	if split < 0 || split > 100 {
+		respondError(w, http.StatusBadRequest, "traffic_split must be between 0 and 100")
+		return
+	}
+
+	ctx := context.Background()
+	experiment := &database.PromptExperiment{
+		UserID:       userID,
+		Name:         body.Name,
+		Description:  body.Description,
+		VariantA:     body.VariantA,
+		VariantB:     body.VariantB,
+		TrafficSplit: split,
+		IsActive:     true,
+	}
+
+	if err := s.db.CreateExperiment(ctx, experiment); err != nil {
+		log.Printf("API: Failed to create experiment: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to create experiment")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, experiment)
+}
+
+// PUT /api/v1/experiments/{id}
+// Adjusts the traffic split and/or stops the experiment.
+func (s *Server) handleAPIUpdateExperiment(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		return
+	}
+
+	var body struct {
+		TrafficSplit *int  `json:"traffic_split"`
+		IsActive     *bool `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if body.IsActive != nil && *body.IsActive {
+		respondError(w, http.StatusBadRequest, "Stopped experiments cannot be restarted; create a new one")
+		return
+	}
+
+	ctx := context.Background()
+	if body.TrafficSplit != nil {
+		if *body.TrafficSplit < 0 || *body.TrafficSplit > 100 {
+			respondError(w, http.StatusBadRequest, "traffic_split must be between 0 and 100")
+			return
+		}
+		if err := s.db.UpdateExperimentSplit(ctx, userID, id, *body.TrafficSplit); err != nil {
+			log.Printf("API: Failed to update experiment split: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update experiment")
+			return
+		}
+	}
+
+	if body.IsActive != nil && !*body.IsActive {
+		if err := s.db.StopExperiment(ctx, userID, id); err != nil {
+			log.Printf("API: Failed to stop experiment: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to stop experiment")
+			return
+		}
+	}
+
+	experiment, err := s.db.GetExperiment(ctx, userID, id)
+	if err != nil {
+		log.Printf("API: Failed to get experiment: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get experiment")
+		return
+	}
+	if experiment == nil {
+		respondError(w, http.StatusNotFound, "Experiment not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, experiment)
+}
+
+// GET /api/v1/experiments/{id}/results
+func (s *Server) handleAPIGetExperimentResults(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		return
+	}
+
+	ctx := context.Background()
+	results, err := s.db.GetExperimentResults(ctx, userID, id)
+	if err != nil {
+		log.Printf("API: Failed to get experiment results: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get experiment results")
+		return
+	}
+	if results == nil {
+		respondError(w, http.StatusNotFound, "Experiment not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/gorilla/mux"
+)
+
+// explanationReferences resolves the referenced context rows for an explanation.
+// Profiles and memories are returned as they are now; the prompts in the snapshot show what was actually sent.
+type explanationReferences struct {
+	AIAnalyzePrompt *database.AIPrompt      `json:"ai_analyze_prompt"`
+	AIActionsPrompt *database.AIPrompt      `json:"ai_actions_prompt"`
+	Memories        []*database.Memory      `json:"memories"`
+	SenderProfile   *database.SenderProfile `json:"sender_profile"`
+	DomainProfile   *database.SenderProfile `json:"domain_profile"`
+}
+
+// GET /api/v1/emails/{id}/explain
+func (s *Server) handleAPIExplainEmail(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	emailID := mux.Vars(r)["id"]
+
+	ctx := context.Background()
+	email, err := s.db.GetEmailByID(ctx, userID, emailID)
+	if err != nil {
+		log.Printf("API: Failed to get email: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get email")
+		return
+	}
+	if email == nil {
+		respondError(w, http.StatusNotFound, "Email not found")
+		return
+	}
+
+	explanation, err := s.db.GetEmailExplanation(ctx, userID, emailID)
+	if err != nil {
+		log.Printf("API: Failed to get email explanation: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get explanation")
+		return
+	}
+
+	// No snapshot: processed before capture was enabled, or expired
+	if explanation == nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"email":       email,
+			"explanation": nil,
+			"references":  nil,
+		})
+		return
+	}
+
+	refs := &explanationReferences{}
+	if explanation.AIAnalyzePromptID != nil {
+		refs.AIAnalyzePrompt, _ = s.db.GetAIPromptByID(ctx, userID, *explanation.AIAnalyzePromptID)
+	}
+	if explanation.AIActionsPromptID != nil {
+		refs.AIActionsPrompt, _ = s.db.GetAIPromptByID(ctx, userID, *explanation.AIActionsPromptID)
+	}
+	if explanation.SenderProfileID != nil {
+		refs.SenderProfile, _ = s.db.GetSenderProfileByID(ctx, userID, *explanation.SenderProfileID)
+	}
+	if explanation.DomainProfileID != nil {
+		refs.DomainProfile, _ = s.db.GetSenderProfileByID(ctx, userID, *explanation.DomainProfileID)
+	}
+	refs.Memories, err = s.db.GetMemoriesByIDs(ctx, userID, explanation.MemoryIDs)
+	if err != nil {
+		log.Printf("API: Failed to get explanation memories: %v", err)
+		refs.Memories = []*database.Memory{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"email":       email,
+		"explanation": explanation,
+		"references":  refs,
+	})
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/pipeline"
+)
+
+// POST /api/v1/playground
+// Runs the pipeline against a stored, raw or pasted email without applying anything to Gmail.
+func (s *Server) handleAPIPlayground(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body pipeline.PlaygroundRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := body.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to load user: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	result, err := s.processor.RunPlayground(ctx, user, &body)
+	if errors.Is(err, pipeline.ErrInvalidPlaygroundRequest) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("API: Playground run failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Playground run failed: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// POST /api/v1/playground/compare-modes
+// Replays recent emails whose decision the user accepted through each pipeline mode (default
+// two_stage and economy) and reports each mode's agreement with those decisions, tokens and cost.
+func (s *Server) handleAPICompareModes(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body pipeline.ModeEvalRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	for _, mode := range body.Modes {
+		if !database.IsValidPipelineMode(mode) {
+			respondError(w, http.StatusBadRequest, "modes must be \"two_stage\", \"economy\" or \"buckets\"")
+			return
+		}
+	}
+
+	ctx := context.Background()
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("API: Failed to load user: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	result, err := s.processor.CompareModes(ctx, user, &body)
+	if err != nil {
+		log.Printf("API: Mode comparison failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Mode comparison failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// maxSearchLimit bounds the page size accepted by /emails/search
+const maxSearchLimit = 200
+
+// GET /api/v1/emails/search
+// Full-text and filtered search over processed emails with cursor pagination.
+// Facet counts (slug, label, domain, bucket) are included on the first page only.
+func (s *Server) handleAPISearchEmails(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	q := r.URL.Query()
+	search := database.EmailSearch{
+		Text:       strings.TrimSpace(q.Get("q")),
+		Sender:     q.Get("from"),
+		Domain:     q.Get("domain"),
+		Slug:       q.Get("slug"),
+		Label:      q.Get("label"),
+		SenderType: q.Get("sender_type"),
+		Bucket:     q.Get("bucket"),
+		TriageVia:  q.Get("triage_via"),
+		Limit:      50,
+	}
+	if search.Bucket != "" && !database.IsValidBucket(search.Bucket) {
+		respondError(w, http.StatusBadRequest, "Unknown bucket")
+		return
+	}
+	if search.TriageVia != "" && !database.IsValidTriageVia(search.TriageVia) {
+		respondError(w, http.StatusBadRequest, "triage_via must be \"ai\", \"cache\", \"thread_reply\", \"consistent_sender\" or \"security\"")
+		return
+	}
+	if l := q.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			search.Limit = min(parsed, maxSearchLimit)
+		}
+	}
+
+	for param, target := range map[string]**bool{
+		"bypassed":      &search.Bypassed,
+		"notified":      &search.Notified,
+		"draft_created": &search.DraftCreated,
+		"has_feedback":  &search.HasFeedback,
+	} {
+		if v := q.Get(param); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, param+" must be true or false")
+				return
+			}
+			*target = &parsed
+		}
+	}
+
+	for param, target := range map[string]**time.Time{
+		"after":  &search.After,
+		"before": &search.Before,
+	} {
+		if v := q.Get(param); v != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, param+" must be a YYYY-MM-DD date")
+				return
+			}
+			*target = &parsed
+		}
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := database.DecodeEmailCursor(c)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		search.Cursor = cursor
+	}
+
+	ctx := context.Background()
+	page, err := s.db.SearchEmailPage(ctx, userID, search)
+	if err != nil {
+		log.Printf("API: Email search failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to search emails")
+		return
+	}
+
+	if search.Cursor == nil {
+		page.Facets, err = s.db.GetEmailFacets(ctx, userID, search)
+		if err != nil {
+			log.Printf("API: Failed to load email facets: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to search emails")
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, page)
+}
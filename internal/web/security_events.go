@@ -0,0 +1,46 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GET /api/v1/security-events?flagged=true
+// One-time codes, password resets, login alerts and account recovery emails handled by the security
+// lane, newest first. flagged=true limits them to unexpected resets and logins (unknown sender or likely phishing).
+func (s *Server) handleAPIGetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	flaggedOnly := false
+	if f := r.URL.Query().Get("flagged"); f != "" {
+		parsed, err := strconv.ParseBool(f)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "flagged must be true or false")
+			return
+		}
+		flaggedOnly = parsed
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	events, err := s.db.GetSecurityEvents(context.Background(), userID, flaggedOnly, limit, offset)
+	if err != nil {
+		log.Printf("API: Failed to load security events: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load security events")
+		return
+	}
+	respondJSON(w, http.StatusOK, events)
+}
@@ -7,10 +7,13 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
+	"github.com/den/gmail-triage-assistant/internal/ask"
 	"github.com/den/gmail-triage-assistant/internal/config"
 	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/llm"
 	"github.com/den/gmail-triage-assistant/internal/memory"
-	"github.com/den/gmail-triage-assistant/internal/openai"
+	"github.com/den/gmail-triage-assistant/internal/pipeline"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"golang.org/x/oauth2"
@@ -27,11 +30,13 @@ type Server struct {
 	sessionStore  *sessions.CookieStore
 	oauthConfig   *oauth2.Config
 	memoryService *memory.Service
-	openaiClient  *openai.Client
+	ai            *ai.Client
+	processor     *pipeline.Processor
+	askService    *ask.Service
 	frontendFS    fs.FS
 }
 
-func NewServer(db *database.DB, cfg *config.Config, memoryService *memory.Service, openaiClient *openai.Client, frontendFS fs.FS) *Server {
+func NewServer(db *database.DB, cfg *config.Config, memoryService *memory.Service, provider llm.LLM, processor *pipeline.Processor, askService *ask.Service, frontendFS fs.FS) *Server {
 	store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
 	store.Options = &sessions.Options{
 		Path:     "/",
@@ -59,7 +64,9 @@ func NewServer(db *database.DB, cfg *config.Config, memoryService *memory.Servic
 		sessionStore:  store,
 		oauthConfig:   oauthConfig,
 		memoryService: memoryService,
-		openaiClient:  openaiClient,
+		ai:            ai.NewClient(provider),
+		processor:     processor,
+		askService:    askService,
 		frontendFS:    frontendFS,
 	}
 
@@ -84,7 +91,12 @@ func (s *Server) routes() {
 	api.HandleFunc("/labels/{id}", s.requireAuthAPI(s.handleAPIDeleteLabel)).Methods("DELETE")
 
 	api.HandleFunc("/emails", s.requireAuthAPI(s.handleAPIGetEmails)).Methods("GET")
+	api.HandleFunc("/emails/search", s.requireAuthAPI(s.handleAPISearchEmails)).Methods("GET")
 	api.HandleFunc("/emails/{id}/feedback", s.requireAuthAPI(s.handleAPIUpdateFeedback)).Methods("PUT")
+	api.HandleFunc("/emails/{id}/explain", s.requireAuthAPI(s.handleAPIExplainEmail)).Methods("GET")
+	api.HandleFunc("/emails/{id}/release-notification", s.requireAuthAPI(s.handleAPIReleaseNotification)).Methods("POST")
+
+	api.HandleFunc("/ask", s.requireAuthAPI(s.handleAPIAsk)).Methods("POST")
 
 	api.HandleFunc("/sender-profiles/all", s.requireAuthAPI(s.handleAPIGetAllSenderProfiles)).Methods("GET")
 	api.HandleFunc("/sender-profiles", s.requireAuthAPI(s.handleAPIGetSenderProfiles)).Methods("GET")
@@ -103,6 +115,11 @@ func (s *Server) routes() {
 	api.HandleFunc("/settings/processing", s.requireAuthAPI(s.handleAPIUpdateProcessing)).Methods("PUT")
 	api.HandleFunc("/settings/pushover", s.requireAuthAPI(s.handleAPIUpdatePushover)).Methods("PUT")
 	api.HandleFunc("/settings/webhook", s.requireAuthAPI(s.handleAPIUpdateWebhook)).Methods("PUT")
+	api.HandleFunc("/settings/budget", s.requireAuthAPI(s.handleAPIUpdateBudget)).Methods("PUT")
+	api.HandleFunc("/settings/pipeline-mode", s.requireAuthAPI(s.handleAPIUpdatePipelineMode)).Methods("PUT")
+	api.HandleFunc("/settings/decision-cache", s.requireAuthAPI(s.handleAPIUpdateDecisionCache)).Methods("PUT")
+	api.HandleFunc("/settings/redaction", s.requireAuthAPI(s.handleAPIGetRedaction)).Methods("GET")
+	api.HandleFunc("/settings/redaction", s.requireAuthAPI(s.handleAPIUpdateRedaction)).Methods("PUT")
 
 	api.HandleFunc("/notifications", s.requireAuthAPI(s.handleAPIGetNotifications)).Methods("GET")
 
@@ -110,13 +127,44 @@ func (s *Server) routes() {
 
 	api.HandleFunc("/stats/summary", s.requireAuthAPI(s.handleAPIGetStatsSummary)).Methods("GET")
 	api.HandleFunc("/stats/timeseries", s.requireAuthAPI(s.handleAPIGetStatsTimeseries)).Methods("GET")
+	api.HandleFunc("/stats/usage", s.requireAuthAPI(s.handleAPIGetStatsUsage)).Methods("GET")
 
 	api.HandleFunc("/prompt-wizard/start", s.requireAuthAPI(s.handleAPIPromptWizardStart)).Methods("POST")
 	api.HandleFunc("/prompt-wizard/continue", s.requireAuthAPI(s.handleAPIPromptWizardContinue)).Methods("POST")
 
+	api.HandleFunc("/experiments", s.requireAuthAPI(s.handleAPIGetExperiments)).Methods("GET")
+	api.HandleFunc("/experiments", s.requireAuthAPI(s.handleAPICreateExperiment)).Methods("POST")
+	api.HandleFunc("/experiments/{id}", s.requireAuthAPI(s.handleAPIUpdateExperiment)).Methods("PUT")
+	api.HandleFunc("/experiments/{id}/results", s.requireAuthAPI(s.handleAPIGetExperimentResults)).Methods("GET")
+
+	api.HandleFunc("/playground", s.requireAuthAPI(s.handleAPIPlayground)).Methods("POST")
+	api.HandleFunc("/playground/compare-modes", s.requireAuthAPI(s.handleAPICompareModes)).Methods("POST")
+
+	api.HandleFunc("/transactions", s.requireAuthAPI(s.handleAPIGetTransactions)).Methods("GET")
+	api.HandleFunc("/transactions/export", s.requireAuthAPI(s.handleAPIExportTransactions)).Methods("GET")
+
+	api.HandleFunc("/shipments", s.requireAuthAPI(s.handleAPIGetShipments)).Methods("GET")
+
+	api.HandleFunc("/security-events", s.requireAuthAPI(s.handleAPIGetSecurityEvents)).Methods("GET")
+
+	api.HandleFunc("/subscriptions", s.requireAuthAPI(s.handleAPIGetSubscriptions)).Methods("GET")
+	api.HandleFunc("/subscriptions/label-policy", s.requireAuthAPI(s.handleAPISetSubscriptionLabelPolicy)).Methods("POST")
+
+	api.HandleFunc("/tasks", s.requireAuthAPI(s.handleAPIGetTasks)).Methods("GET")
+	api.HandleFunc("/tasks/export", s.requireAuthAPI(s.handleAPIExportTasks)).Methods("GET")
+	api.HandleFunc("/tasks/{id}/complete", s.requireAuthAPI(s.handleAPICompleteTask)).Methods("POST")
+	api.HandleFunc("/tasks/{id}/snooze", s.requireAuthAPI(s.handleAPISnoozeTask)).Methods("POST")
+
+	api.HandleFunc("/events", s.requireAuthAPI(s.handleAPIGetEvents)).Methods("GET")
+	api.HandleFunc("/calendar/feed", s.requireAuthAPI(s.handleAPIGetCalendarFeed)).Methods("GET")
+	api.HandleFunc("/calendar/feed/rotate", s.requireAuthAPI(s.handleAPIRotateCalendarFeed)).Methods("POST")
+
 	api.HandleFunc("/export", s.requireAuthAPI(s.handleAPIExport)).Methods("GET")
 	api.HandleFunc("/import", s.requireAuthAPI(s.handleAPIImport)).Methods("POST")
 
+	// Public ICS feed for calendar clients (authenticated by the token in the URL)
+	s.router.HandleFunc("/calendar/{token}.ics", s.handleCalendarFeed).Methods("GET")
+
 	// SPA fallback — serves React app for all other routes
 	spa := newSPAHandler(s.frontendFS)
 	s.router.PathPrefix("/").Handler(spa)
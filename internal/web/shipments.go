@@ -0,0 +1,44 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// GET /api/v1/shipments?status=active|shipped|out_for_delivery|delivered|exception
+// Packages tracked from shipping notifications, one per order, with their timeline of updates.
+func (s *Server) handleAPIGetShipments(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "active" && !database.IsValidShipmentStatus(status) {
+		respondError(w, http.StatusBadRequest, "status must be one of: active, "+strings.Join(database.ShipmentStatuses, ", "))
+		return
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	shipments, err := s.db.GetShipments(context.Background(), userID, status, limit, offset)
+	if err != nil {
+		log.Printf("API: Failed to load shipments: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load shipments")
+		return
+	}
+	respondJSON(w, http.StatusOK, shipments)
+}
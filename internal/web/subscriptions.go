@@ -0,0 +1,77 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/den/gmail-triage-assistant/internal/gmail"
+)
+
+// GET /api/v1/subscriptions?suggestion=unsubscribe
+// Mailing lists and subscription senders ranked by noise (monthly volume weighted by how little of it
+// is read), each with its archive rate, feedback and an unsubscribe or timed label suggestion.
+// suggestion limits the list to subscriptions with that suggestion.
+func (s *Server) handleAPIGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	suggestion := r.URL.Query().Get("suggestion")
+	if suggestion != "" && suggestion != database.SubscriptionSuggestUnsubscribe && suggestion != database.SubscriptionSuggestTimedLabel {
+		respondError(w, http.StatusBadRequest, "suggestion must be unsubscribe or timed_label")
+		return
+	}
+
+	subscriptions, err := s.db.GetSubscriptions(context.Background(), userID)
+	if err != nil {
+		log.Printf("API: Failed to load subscriptions: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load subscriptions")
+		return
+	}
+
+	if suggestion != "" {
+		filtered := make([]*database.Subscription, 0, len(subscriptions))
+		for _, sub := range subscriptions {
+			if sub.Suggestion == suggestion {
+				filtered = append(filtered, sub)
+			}
+		}
+		subscriptions = filtered
+	}
+	respondJSON(w, http.StatusOK, subscriptions)
+}
+
+// POST /api/v1/subscriptions/label-policy
+// Sets the timed label added to every future email of the given subscriptions, e.g.
+// {"ids": [3, 7], "label": "🗑️/1w"}. An empty label clears the policy.
+func (s *Server) handleAPISetSubscriptionLabelPolicy(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	var body struct {
+		IDs   []int64 `json:"ids"`
+		Label string  `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if len(body.IDs) == 0 {
+		respondError(w, http.StatusBadRequest, "ids is required")
+		return
+	}
+	if body.Label != "" && !gmail.IsTimedLabel(body.Label) {
+		respondError(w, http.StatusBadRequest, "label must be a timed archive or delete label (e.g. 📥/1w, 🗑️/1m) or 📥/read")
+		return
+	}
+
+	updated, err := s.db.SetSubscriptionLabelPolicy(context.Background(), userID, body.IDs, body.Label)
+	if err != nil {
+		log.Printf("API: Failed to set subscription label policy: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to set label policy")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"updated": updated, "label": body.Label})
+}
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+	"github.com/gorilla/mux"
+)
+
+// GET /api/v1/tasks?status=open|snoozed|done|all
+// Action items extracted from emails; open tasks by default.
+func (s *Server) handleAPIGetTasks(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	status, errMsg := parseTaskStatus(r)
+	if errMsg != "" {
+		respondError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	tasks, err := s.db.GetTasks(context.Background(), userID, status, limit, offset)
+	if err != nil {
+		log.Printf("API: Failed to load tasks: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load tasks")
+		return
+	}
+	respondJSON(w, http.StatusOK, tasks)
+}
+
+// POST /api/v1/tasks/{id}/complete
+// Marks a task done; {"completed": false} reopens it.
+func (s *Server) handleAPICompleteTask(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	body := struct {
+		Completed *bool `json:"completed"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+	}
+	completed := body.Completed == nil || *body.Completed
+
+	found, err := s.db.CompleteTask(context.Background(), userID, id, completed)
+	if err != nil {
+		log.Printf("API: Failed to complete task: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to update task")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]bool{"completed": completed})
+}
+
+// POST /api/v1/tasks/{id}/snooze
+// Hides an open task until {"until": "2026-01-31"} (local midnight) or an RFC 3339 time; an empty until unsnoozes it.
+func (s *Server) handleAPISnoozeTask(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	var body struct {
+		Until string `json:"until"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	var until *time.Time
+	if body.Until != "" {
+		parsed, err := time.Parse(time.RFC3339, body.Until)
+		if err != nil {
+			parsed, err = time.ParseInLocation("2006-01-02", body.Until, time.Local)
+		}
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "until must be a YYYY-MM-DD date or an RFC 3339 time")
+			return
+		}
+		if !parsed.After(time.Now()) {
+			respondError(w, http.StatusBadRequest, "until must be in the future")
+			return
+		}
+		until = &parsed
+	}
+
+	found, err := s.db.SnoozeTask(context.Background(), userID, id, until)
+	if err != nil {
+		log.Printf("API: Failed to snooze task: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to update task")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "Open task not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"snoozed_until": until})
+}
+
+// GET /api/v1/tasks/export?format=ics|todotxt&status=open|snoozed|done|all
+// Exports the task list as VTODOs for calendar and task apps, or as a todo.txt file.
+func (s *Server) handleAPIExportTasks(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	status, errMsg := parseTaskStatus(r)
+	if errMsg != "" {
+		respondError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ics"
+	}
+	var render func([]*database.Task) []byte
+	var contentType, filename string
+	switch format {
+	case "ics":
+		render, contentType, filename = tasksICS, "text/calendar", "tasks.ics"
+	case "todotxt":
+		render, contentType, filename = tasksTodoTxt, "text/plain", "todo.txt"
+	default:
+		respondError(w, http.StatusBadRequest, "format must be \"ics\" or \"todotxt\"")
+		return
+	}
+
+	tasks, err := s.db.GetTasks(context.Background(), userID, status, 0, 0)
+	if err != nil {
+		log.Printf("API: Failed to load tasks for export: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to export tasks")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Write(render(tasks))
+}
+
+// parseTaskStatus reads the status filter from the query string (default open).
+// Returns an error message for invalid values.
+func parseTaskStatus(r *http.Request) (string, string) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		return database.TaskStatusOpen, ""
+	}
+	if !database.IsValidTaskStatus(status) {
+		return "", "status must be one of: " + strings.Join(database.TaskStatuses, ", ")
+	}
+	return status, ""
+}
+
+// gmailMessageURL links to a message in the Gmail web UI
+func gmailMessageURL(emailID string) string {
+	return "https://mail.google.com/mail/u/0/#all/" + emailID
+}
+
+// tasksICS renders tasks as an iCalendar document of VTODOs
+func tasksICS(tasks []*database.Task) []byte {
+	now := time.Now()
+	var buf bytes.Buffer
+	line := func(content string) {
+		buf.WriteString(foldICSLine(content))
+		buf.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//gmail-triage-assistant//Email Tasks//EN")
+	for _, t := range tasks {
+		line("BEGIN:VTODO")
+		line(fmt.Sprintf("UID:task-%d@gmail-triage-assistant", t.ID))
+		line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		line("CREATED:" + t.CreatedAt.UTC().Format("20060102T150405Z"))
+		line("LAST-MODIFIED:" + t.UpdatedAt.UTC().Format("20060102T150405Z"))
+		line("SUMMARY:" + escapeICS(t.Description))
+		if t.DueDate != nil {
+			line("DUE;VALUE=DATE:" + t.DueDate.Format("20060102"))
+		}
+		if t.EmailSubject != "" {
+			line("DESCRIPTION:" + escapeICS(fmt.Sprintf("From %s: %s", t.EmailFrom, t.EmailSubject)))
+		}
+		line("URL:" + gmailMessageURL(t.EmailID))
+		if t.CompletedAt != nil {
+			line("STATUS:COMPLETED")
+			line("COMPLETED:" + t.CompletedAt.UTC().Format("20060102T150405Z"))
+		} else {
+			line("STATUS:NEEDS-ACTION")
+		}
+		line("END:VTODO")
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// tasksTodoTxt renders tasks in todo.txt format, with due: and email: tags
+func tasksTodoTxt(tasks []*database.Task) []byte {
+	var buf bytes.Buffer
+	for _, t := range tasks {
+		if t.CompletedAt != nil {
+			fmt.Fprintf(&buf, "x %s ", t.CompletedAt.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&buf, "%s %s", t.CreatedAt.Format("2006-01-02"), strings.Join(strings.Fields(t.Description), " "))
+		if t.DueDate != nil {
+			fmt.Fprintf(&buf, " due:%s", t.DueDate.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&buf, " email:%s\n", t.EmailID)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,262 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/den/gmail-triage-assistant/internal/database"
+)
+
+// Accounts used for exported ledger entries; users rename or re-categorize them in their books
+const (
+	ledgerExpenseAccount = "Expenses:Uncategorized"
+	ledgerFundingAccount = "Liabilities:Unreconciled"
+)
+
+// GET /api/v1/transactions
+// Transactional emails (receipts, invoices, shipping, refunds, bookings) with their extracted
+// vendor, amount and due date, plus totals by vendor and month for the same filters.
+func (s *Server) handleAPIGetTransactions(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	filter, errMsg := parseTransactionFilter(r)
+	if errMsg != "" {
+		respondError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	filter.Limit = 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 500 {
+			filter.Limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			filter.Offset = parsed
+		}
+	}
+
+	ctx := context.Background()
+	transactions, err := s.db.GetTransactions(ctx, userID, filter)
+	if err != nil {
+		log.Printf("API: Failed to load transactions: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load transactions")
+		return
+	}
+	totals, err := s.db.GetTransactionTotals(ctx, userID, filter)
+	if err != nil {
+		log.Printf("API: Failed to load transaction totals: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load transactions")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"transactions": transactions,
+		"totals":       totals,
+	})
+}
+
+// GET /api/v1/transactions/export?format=csv|ledger|beancount
+// Exports every transaction matching the filters. Ledger formats only include receipts and refunds
+// with an amount, so an invoice and its receipt are not booked twice.
+func (s *Server) handleAPIExportTransactions(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
+	filter, errMsg := parseTransactionFilter(r)
+	if errMsg != "" {
+		respondError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	var render func([]*database.Transaction) ([]byte, error)
+	var contentType, extension string
+	switch format {
+	case "csv":
+		render, contentType, extension = transactionsCSV, "text/csv", "csv"
+	case "ledger":
+		render, contentType, extension = transactionsLedger, "text/plain", "ledger"
+	case "beancount":
+		render, contentType, extension = transactionsBeancount, "text/plain", "beancount"
+	default:
+		respondError(w, http.StatusBadRequest, "format must be \"csv\", \"ledger\" or \"beancount\"")
+		return
+	}
+
+	ctx := context.Background()
+	transactions, err := s.db.GetTransactions(ctx, userID, filter)
+	if err != nil {
+		log.Printf("API: Failed to load transactions for export: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to export transactions")
+		return
+	}
+	// Ledgers read oldest first
+	for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+		transactions[i], transactions[j] = transactions[j], transactions[i]
+	}
+
+	data, err := render(transactions)
+	if err != nil {
+		log.Printf("API: Failed to render transactions: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to export transactions")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=transactions."+extension)
+	w.Write(data)
+}
+
+// parseTransactionFilter reads the ledger filters from the query string.
+// Returns an error message for invalid values.
+func parseTransactionFilter(r *http.Request) (database.TransactionFilter, string) {
+	q := r.URL.Query()
+	filter := database.TransactionFilter{
+		Vendor:       q.Get("vendor"),
+		DocumentType: q.Get("document_type"),
+		Currency:     q.Get("currency"),
+	}
+	if filter.DocumentType != "" && !database.IsValidDocumentType(filter.DocumentType) {
+		return filter, "document_type must be one of: " + strings.Join(database.DocumentTypes, ", ")
+	}
+	for param, target := range map[string]**time.Time{
+		"after":  &filter.After,
+		"before": &filter.Before,
+	} {
+		if v := q.Get(param); v != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				return filter, param + " must be a YYYY-MM-DD date"
+			}
+			*target = &parsed
+		}
+	}
+	return filter, ""
+}
+
+// transactionsCSV renders transactions as CSV with a header row
+func transactionsCSV(transactions []*database.Transaction) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"date", "vendor", "document_type", "amount", "currency", "due_date", "subject", "from", "email_id"})
+	for _, t := range transactions {
+		amount, dueDate := "", ""
+		if t.Amount != nil {
+			amount = formatAmount(*t.Amount, t.Currency)
+		}
+		if t.DueDate != nil {
+			dueDate = t.DueDate.Format("2006-01-02")
+		}
+		cw.Write([]string{t.ProcessedAt.Format("2006-01-02"), t.Vendor, t.DocumentType, amount, t.Currency, dueDate, t.Subject, t.FromAddress, t.EmailID})
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+// transactionsLedger renders transactions as ledger-cli journal entries
+func transactionsLedger(transactions []*database.Transaction) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, t := range transactions {
+		if !isPosting(t) {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s * %s\n", t.ProcessedAt.Format("2006/01/02"), ledgerText(t.Vendor))
+		fmt.Fprintf(&buf, "    ; %s: %s\n", t.DocumentType, ledgerText(t.Subject))
+		fmt.Fprintf(&buf, "    ; email: %s\n", t.EmailID)
+		if t.DueDate != nil {
+			fmt.Fprintf(&buf, "    ; due: %s\n", t.DueDate.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&buf, "    %-40s  %s\n", ledgerExpenseAccount, formatPosting(t))
+		fmt.Fprintf(&buf, "    %s\n\n", ledgerFundingAccount)
+	}
+	return buf.Bytes(), nil
+}
+
+// beancountCurrency matches a valid beancount commodity
+var beancountCurrency = regexp.MustCompile(`^[A-Z][A-Z0-9'._-]{0,22}[A-Z0-9]$`)
+
+// transactionsBeancount renders transactions as beancount entries, preceded by open directives for
+// the export's accounts. Transactions without a currency are skipped (beancount requires one).
+func transactionsBeancount(transactions []*database.Transaction) ([]byte, error) {
+	var entries bytes.Buffer
+	var first time.Time
+	for _, t := range transactions {
+		if !isPosting(t) || !beancountCurrency.MatchString(t.Currency) {
+			continue
+		}
+		if first.IsZero() {
+			first = t.ProcessedAt
+		}
+		fmt.Fprintf(&entries, "%s * %s %s\n", t.ProcessedAt.Format("2006-01-02"), strconv.Quote(ledgerText(t.Vendor)), strconv.Quote(ledgerText(t.Subject)))
+		fmt.Fprintf(&entries, "  document-type: %s\n", strconv.Quote(t.DocumentType))
+		fmt.Fprintf(&entries, "  email-id: %s\n", strconv.Quote(t.EmailID))
+		if t.DueDate != nil {
+			fmt.Fprintf(&entries, "  due-date: %s\n", t.DueDate.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&entries, "  %-40s  %s\n", ledgerExpenseAccount, formatPosting(t))
+		fmt.Fprintf(&entries, "  %s\n\n", ledgerFundingAccount)
+	}
+	if first.IsZero() {
+		return entries.Bytes(), nil
+	}
+
+	var buf bytes.Buffer
+	for _, account := range []string{ledgerExpenseAccount, ledgerFundingAccount} {
+		fmt.Fprintf(&buf, "%s open %s\n", first.Format("2006-01-02"), account)
+	}
+	buf.WriteString("\n")
+	buf.Write(entries.Bytes())
+	return buf.Bytes(), nil
+}
+
+// isPosting returns true if the transaction is booked in ledger exports: a receipt or refund with an amount
+func isPosting(t *database.Transaction) bool {
+	return t.Amount != nil && database.IsPostedDocumentType(t.DocumentType)
+}
+
+// currencyDecimals are the ISO 4217 minor units of currencies that don't use 2 decimals
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// formatAmount formats an amount with its currency's number of decimals (2 if unknown)
+func formatAmount(amount float64, currency string) string {
+	decimals, ok := currencyDecimals[strings.ToUpper(currency)]
+	if !ok {
+		decimals = 2
+	}
+	return strconv.FormatFloat(amount, 'f', decimals, 64)
+}
+
+// formatPosting is the expense posting amount: refunds are negative
+func formatPosting(t *database.Transaction) string {
+	amount := *t.Amount
+	if t.DocumentType == database.DocumentTypeRefund {
+		amount = -amount
+	}
+	posting := formatAmount(amount, t.Currency)
+	if t.Currency != "" {
+		posting += " " + t.Currency
+	}
+	return posting
+}
+
+// ledgerText flattens text onto one line for a journal payee or comment
+func ledgerText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
 	"github.com/den/gmail-triage-assistant/internal/database"
 )
 
@@ -88,7 +89,7 @@ func (s *Server) handleAPIPromptWizardStart(w http.ResponseWriter, r *http.Reque
 	session, _ := s.sessionStore.Get(r, "session")
 	userID := session.Values["user_id"].(int64)
 
-	ctx := context.Background()
+	ctx := ai.WithUser(context.Background(), userID)
 
 	// Fetch 2 weeks of emails
 	now := time.Now()
@@ -120,7 +121,7 @@ func (s *Server) handleAPIPromptWizardStart(w http.ResponseWriter, r *http.Reque
 
 	userPrompt := fmt.Sprintf("Here is my email data from the last 2 weeks:\n\n%s\n\nPlease start the setup wizard by asking your first round of questions.", emailSummary)
 
-	result, err := s.openaiClient.RunPromptWizard(ctx, wizardSystemPrompt, userPrompt)
+	result, err := s.ai.RunPromptWizard(ctx, wizardSystemPrompt, userPrompt)
 	if err != nil {
 		log.Printf("API: Wizard AI call failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "AI wizard failed")
@@ -138,6 +139,9 @@ func (s *Server) handleAPIPromptWizardStart(w http.ResponseWriter, r *http.Reque
 
 // POST /api/v1/prompt-wizard/continue
 func (s *Server) handleAPIPromptWizardContinue(w http.ResponseWriter, r *http.Request) {
+	session, _ := s.sessionStore.Get(r, "session")
+	userID := session.Values["user_id"].(int64)
+
 	var body struct {
 		EmailSummary string               `json:"email_summary"`
 		History      []WizardHistoryEntry `json:"history"`
@@ -152,10 +156,10 @@ func (s *Server) handleAPIPromptWizardContinue(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	ctx := context.Background()
+	ctx := ai.WithUser(context.Background(), userID)
 	userPrompt := buildWizardConversationPrompt(body.EmailSummary, body.History)
 
-	result, err := s.openaiClient.RunPromptWizard(ctx, wizardSystemPrompt, userPrompt)
+	result, err := s.ai.RunPromptWizard(ctx, wizardSystemPrompt, userPrompt)
 	if err != nil {
 		log.Printf("API: Wizard AI continue call failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "AI wizard failed")
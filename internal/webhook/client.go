@@ -24,6 +24,7 @@ type Payload struct {
 	Subject       string   `json:"subject"`
 	LabelsApplied []string `json:"labels_applied"`
 	ProcessedAt   string   `json:"processed_at"`
+	Priority      string   `json:"priority"` // "normal" or "high" (security alerts)
 }
 
 // NewClient creates a new webhook client with a 10s timeout
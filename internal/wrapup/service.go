@@ -8,22 +8,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/den/gmail-triage-assistant/internal/ai"
 	"github.com/den/gmail-triage-assistant/internal/database"
 	"github.com/den/gmail-triage-assistant/internal/gmail"
-	"github.com/den/gmail-triage-assistant/internal/openai"
+	"github.com/den/gmail-triage-assistant/internal/llm"
 	"golang.org/x/oauth2"
 )
 
 type Service struct {
 	db          *database.DB
-	openai      *openai.Client
+	ai          *ai.Client
 	oauthConfig *oauth2.Config
 }
 
-func NewService(db *database.DB, openaiClient *openai.Client, oauthConfig *oauth2.Config) *Service {
+func NewService(db *database.DB, provider llm.LLM, oauthConfig *oauth2.Config) *Service {
 	return &Service{
 		db:          db,
-		openai:      openaiClient,
+		ai:          ai.NewClient(provider),
 		oauthConfig: oauthConfig,
 	}
 }
@@ -31,6 +32,7 @@ func NewService(db *database.DB, openaiClient *openai.Client, oauthConfig *oauth
 // GenerateMorningWrapup creates a summary of emails processed overnight (since last evening)
 func (s *Service) GenerateMorningWrapup(ctx context.Context, user *database.User) error {
 	log.Printf("Generating morning wrapup for user %s", user.Email)
+	ctx = ai.WithUser(ctx, user.ID)
 
 	now := time.Now()
 	// Get emails since 5PM yesterday
@@ -51,6 +53,15 @@ func (s *Service) GenerateMorningWrapup(ctx context.Context, user *database.User
 	if err != nil {
 		return fmt.Errorf("failed to generate wrapup: %w", err)
 	}
+	content += s.phishingSection(ctx, user, since, now)
+
+	// Open action items from all emails, not just overnight ones
+	tasks, err := s.db.GetTasks(ctx, user.ID, database.TaskStatusOpen, maxWrapupTasks+1, 0)
+	if err != nil {
+		log.Printf("Failed to load open tasks for %s's wrapup: %v", user.Email, err)
+	} else {
+		content += buildTasksSection(tasks, now)
+	}
 
 	report := &database.WrapupReport{
 		UserID:      user.ID,
@@ -77,6 +88,7 @@ func (s *Service) GenerateMorningWrapup(ctx context.Context, user *database.User
 // GenerateEveningWrapup creates a summary of emails processed during the day
 func (s *Service) GenerateEveningWrapup(ctx context.Context, user *database.User) error {
 	log.Printf("Generating evening wrapup for user %s", user.Email)
+	ctx = ai.WithUser(ctx, user.ID)
 
 	now := time.Now()
 	// Get emails since 8AM today
@@ -96,6 +108,7 @@ func (s *Service) GenerateEveningWrapup(ctx context.Context, user *database.User
 	if err != nil {
 		return fmt.Errorf("failed to generate wrapup: %w", err)
 	}
+	content += s.phishingSection(ctx, user, today, now)
 
 	report := &database.WrapupReport{
 		UserID:      user.ID,
@@ -203,6 +216,66 @@ func buildWrapupStats(emails []*database.Email, reportType string) string {
 	return b.String()
 }
 
+// maxWrapupTasks caps the open tasks listed in the morning wrapup
+const maxWrapupTasks = 15
+
+// buildTasksSection lists open tasks, soonest due first, flagging overdue ones.
+// tasks may hold one more than maxWrapupTasks to signal that the list was cut.
+func buildTasksSection(tasks []*database.Task, now time.Time) string {
+	if len(tasks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nOpen Tasks\n")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for i, t := range tasks {
+		if i == maxWrapupTasks {
+			b.WriteString("... and more in the task list\n")
+			break
+		}
+		due := ""
+		if t.DueDate != nil {
+			due = " (due " + t.DueDate.Format("Jan 2") + ")"
+			if t.DueDate.Before(today) {
+				due = " (OVERDUE, was due " + t.DueDate.Format("Jan 2") + ")"
+			}
+		}
+		fmt.Fprintf(&b, "- %s%s\n", t.Description, due)
+		if t.EmailSubject != "" {
+			fmt.Fprintf(&b, "  from %s: %s\n", t.EmailFrom, t.EmailSubject)
+		}
+	}
+	return b.String()
+}
+
+// phishingSection lists the likely phishing emails processed in the wrapup's window ("" if none)
+func (s *Service) phishingSection(ctx context.Context, user *database.User, since, until time.Time) string {
+	emails, err := s.db.GetHighRiskEmails(ctx, user.ID, since, until)
+	if err != nil {
+		log.Printf("Failed to load high-risk emails for %s's wrapup: %v", user.Email, err)
+		return ""
+	}
+	return buildPhishingSection(emails)
+}
+
+// buildPhishingSection lists high-risk emails with the signals that flagged them
+func buildPhishingSection(emails []*database.Email) string {
+	if len(emails) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nPossible Phishing\n")
+	for _, e := range emails {
+		fmt.Fprintf(&b, "- %s: %s\n", e.FromAddress, e.Subject)
+		if len(e.PhishingSignals) > 0 {
+			fmt.Fprintf(&b, "  %s\n", strings.Join(e.PhishingSignals, "; "))
+		}
+	}
+	return b.String()
+}
+
 type ranked struct {
 	name  string
 	count int
@@ -278,7 +351,7 @@ func (s *Service) generateAISummary(ctx context.Context, emails []*database.Emai
 	userPrompt := fmt.Sprintf("Here are %d emails processed %s. Summarize the most notable themes or items in 1-2 sentences:\n\n%s",
 		len(emails), timeframe, strings.Join(lines, "\n"))
 
-	content, err := s.openai.GenerateMemory(ctx, systemPrompt, userPrompt)
+	content, err := s.ai.GenerateReport(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		log.Printf("Warning: AI summary generation failed, omitting summary section: %v", err)
 		return ""